@@ -0,0 +1,112 @@
+package main
+
+import (
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Duration 支持在配置文件中使用"1h30m"格式的时间长度
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	var err error
+	d.Duration, err = time.ParseDuration(string(text))
+	return err
+}
+
+type HuaweiConfig struct {
+	LoginScope int    `toml:"login_scope"` // 用户类型, 0: 本地用户, 1: LDAP用户
+	VStoreName string `toml:"vstore_name"` // 租户名称, 使用租户管理员登录时配置
+
+	StatisticBatchSize int `toml:"statistic_batch_size"` // 单次性能查询的对象数量
+	StatisticParallel  int `toml:"statistic_parallel"`   // 性能查询并发数
+	RequestRate        int `toml:"request_rate"`         // 每秒最大请求数, 0表示不限制
+
+	BackfillOnStart bool     `toml:"backfill_on_start"` // 启动时补录历史性能数据
+	BackfillWindow  Duration `toml:"backfill_window"`   // 补录的时间范围
+}
+
+type HPConfig struct {
+	Username string `toml:"username"` // 登录用户, 留空时使用 config/hp_account, 可以使用monitor角色的只读用户
+	Password string `toml:"password"` // 登录密码, 留空时使用 config/hp_password
+
+	Transport  string `toml:"transport"`   // 采集方式, api: Web接口, ssh: SSH登录CLI
+	SshAddress string `toml:"ssh_address"` // SSH地址(host:port), 留空时使用设备地址的22端口
+}
+
+type IbmConfig struct {
+	Transport   string `toml:"transport"`    // 采集方式, rpc: 管理界面接口, rest: REST接口, ssh: SSH登录CLI
+	RestAddress string `toml:"rest_address"` // REST接口地址(https://host:port), 留空时使用设备地址的7443端口
+	SshAddress  string `toml:"ssh_address"`  // SSH地址(host:port), 留空时使用设备地址的22端口
+
+	CopyServices bool `toml:"copy_services"` // 采集FlashCopy和远程复制, 管理界面接口(rpc)不支持
+}
+
+// SnmpDevice 通过SNMP采集的设备
+type SnmpDevice struct {
+	Name      string `toml:"name"`
+	Address   string `toml:"address"` // host[:port], 默认161端口
+	Vendor    string `toml:"vendor"`  // 厂商, 对应OID映射文件 <mapping_dir>/<vendor>.toml
+	Version   string `toml:"version"` // 2c, 3
+	Community string `toml:"community"`
+
+	// SNMPv3(USM)
+	Username     string `toml:"username"`
+	AuthProtocol string `toml:"auth_protocol"` // MD5, SHA, SHA224, SHA256, SHA384, SHA512, 留空时不认证
+	AuthPassword string `toml:"auth_password"`
+	PrivProtocol string `toml:"priv_protocol"` // DES, AES, AES192, AES256, 留空时不加密
+	PrivPassword string `toml:"priv_password"`
+	EngineId     string `toml:"engine_id"` // 设备的SNMP引擎ID(十六进制), 接收v3 Trap时需要, v3不支持Inform
+}
+
+type SnmpConfig struct {
+	MappingDir string   `toml:"mapping_dir"` // OID映射文件目录
+	Timeout    Duration `toml:"timeout"`     // 单个请求的超时时间
+	Retries    int      `toml:"retries"`
+
+	TrapAddress string `toml:"trap_address"` // 接收Trap的地址
+
+	Devices []SnmpDevice `toml:"devices"`
+}
+
+type Config struct {
+	Huawei HuaweiConfig `toml:"huawei"`
+	HP     HPConfig     `toml:"hp"`
+	Ibm    IbmConfig    `toml:"ibm"`
+	Snmp   SnmpConfig   `toml:"snmp"`
+}
+
+func NewConfig() *Config {
+	c := new(Config)
+
+	c.Huawei.StatisticBatchSize = 20
+	c.Huawei.StatisticParallel = 4
+	c.Huawei.RequestRate = 10
+	c.Huawei.BackfillWindow.Duration = time.Hour
+
+	c.HP.Transport = "api"
+
+	c.Ibm.Transport = "rpc"
+
+	c.Snmp.MappingDir = "snmp"
+	c.Snmp.Timeout.Duration = 5 * time.Second
+	c.Snmp.Retries = 2
+	c.Snmp.TrapAddress = "0.0.0.0:162"
+
+	return c
+}
+
+// LoadConfig 读取配置文件, 文件不存在时使用默认配置
+func LoadConfig(path string) (*Config, error) {
+	c := NewConfig()
+	if !isExist(path) {
+		return c, nil
+	}
+	if _, err := toml.DecodeFile(path, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
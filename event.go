@@ -0,0 +1,29 @@
+package main
+
+const (
+	EventAlarmRaised  = "alarm_raised"
+	EventAlarmCleared = "alarm_cleared"
+	EventLog          = "event"
+)
+
+type Event struct {
+	Time     string `json:"time"`             // 事件发生时间
+	Source   string `json:"source"`           // 设备类型(huawei, hp, ibm, dell)
+	Device   string `json:"device,omitempty"` // 设备名称, 同一类型有多台设备时区分
+	Type     string `json:"type"`             // 事件类型
+	Id       string `json:"id"`               // 告警或事件ID
+	Level    string `json:"level"`            // 级别
+	Location string `json:"location"`         // 位置
+	Message  string `json:"message"`          // 描述
+
+	ExpireTime string `json:"expireTime,omitempty"` // 设备不发送恢复通知的告警, 到该时间自动清除
+}
+
+// AppendEvents 以JSON Lines格式追加写入事件
+func AppendEvents(path string, events []*Event) error {
+	values := make([]interface{}, len(events))
+	for i := 0; i < len(events); i++ {
+		values[i] = events[i]
+	}
+	return AppendJsonLines(path, values...)
+}
@@ -3,13 +3,13 @@ module oss-exporter
 go 1.16
 
 require (
-	github.com/BurntSushi/toml v0.4.1 // indirect
-	github.com/beevik/etree v1.1.0
+	github.com/BurntSushi/toml v0.4.1
 	github.com/buger/jsonparser v1.1.1
 	github.com/gorilla/websocket v1.4.2
+	github.com/gosnmp/gosnmp v1.32.0
 	github.com/pkg/errors v0.9.1 // indirect
-	github.com/robertkrimen/otto v0.0.0-20211008084715-4eacda02dd21
 	github.com/tidwall/gjson v1.9.1
 	go.uber.org/zap v1.19.1
+	golang.org/x/crypto v0.0.0-20211117183948-ae814b36b871
 	gopkg.in/natefinch/lumberjack.v2 v2.0.0
 )
@@ -12,11 +12,9 @@ import (
 	"io/ioutil"
 	"net/http"
 	"os"
-	"strconv"
 	"strings"
 	"time"
 
-	"github.com/beevik/etree"
 	"go.uber.org/zap"
 )
 
@@ -27,24 +25,34 @@ var (
 	HPPassword string
 )
 
-var (
-	//go:embed hp_parse.js
-	HPScript string
-)
-
 type HPCrawlerData struct {
 	VendorName     string   `json:"vendorName"`
 	BundleVersions []string `json:"bundleVersions"`
 
-	ControllerStates    []interface{} `json:"controllerStates"`    // 控制器状态
-	NetworkStates       []interface{} `json:"networkStates"`       // 网络端口状态
-	PortStates          []interface{} `json:"portStates"`          // 主机端口状态
-	ExpanderPortStates  []interface{} `json:"expanderPortStates"`  // 扩展端口状态
-	CompactFlashStates  []interface{} `json:"compactFlashStates"`  // CompactFlash状态
-	PowerSuppliesStates []interface{} `json:"powerSuppliesStates"` // 电源状态
-	FanStates           []interface{} `json:"fanStates"`           // 风扇状态
-
-	DiskInfo []interface{} `json:"diskInfo"`
+	ControllerStates    []*HPComponentState `json:"controllerStates"`    // 控制器状态
+	NetworkStates       []*HPComponentState `json:"networkStates"`       // 网络端口状态
+	PortStates          []*HPComponentState `json:"portStates"`          // 主机端口状态
+	ExpanderPortStates  []*HPComponentState `json:"expanderPortStates"`  // 扩展端口状态
+	CompactFlashStates  []*HPComponentState `json:"compactFlashStates"`  // CompactFlash状态
+	PowerSuppliesStates []*HPPowerSupply    `json:"powerSuppliesStates"` // 电源状态
+	FanStates           []*HPFan            `json:"fanStates"`           // 风扇状态
+
+	DiskInfo    []*HPDisk       `json:"diskInfo"`
+	Pools       []*HPPool       `json:"pools"`
+	Volumes     []*HPVolume     `json:"volumes"`
+	DiskGroups  []*HPDiskGroup  `json:"diskGroups"`
+	Ports       []*HPPort       `json:"ports"`
+	Sensors     []*HPSensor     `json:"sensors"`
+	Enclosures  []*HPEnclosure  `json:"enclosures"`
+	Controllers []*HPController `json:"controllers"`
+
+	Statistics []*HPStatistic `json:"statistics"`
+
+	Events             []*HPEvent          `json:"events"` // 上次采集之后的事件
+	CriticalEventCount int64               `json:"criticalEventCount"`
+	ErrorEventCount    int64               `json:"errorEventCount"`
+	WarningEventCount  int64               `json:"warningEventCount"`
+	AlertConditions    []*HPAlertCondition `json:"alertConditions"`
 
 	SizeTotal   int64 `json:"sizeTotal"`   // 总容量
 	SizeSpares  int64 `json:"sizeSpares"`  // 全局备用磁盘(spaceSpares)
@@ -54,6 +62,68 @@ type HPCrawlerData struct {
 	VirtUnallocSizeTotal   int64 `json:"virtUnallocSizeTotal"`   // 未分配(spaceVirtualUnalloc)(volumeGroupsSet)
 }
 
+type HPComponentState struct {
+	Id     string `json:"id" hp:"durable-id"`
+	Health string `json:"health" hp:"health-numeric,health"`
+}
+
+type HPPowerSupply struct {
+	Id          string  `json:"id" hp:"durable-id"`
+	Health      string  `json:"health" hp:"health-numeric,health"`
+	Name        string  `json:"name" hp:"name"`
+	Location    string  `json:"location" hp:"location"`
+	Status      string  `json:"status" hp:"status-numeric,power-supply-status"`
+	Firmware    string  `json:"firmware" hp:"fw-revision"`
+	Voltage12   float64 `json:"voltage12"`               // 12V输出电压(V)
+	Voltage5    float64 `json:"voltage5"`                // 5V输出电压(V)
+	Voltage33   float64 `json:"voltage33"`               // 3.3V输出电压(V)
+	Current12   float64 `json:"current12"`               // 12V输出电流(A)
+	Current5    float64 `json:"current5"`                // 5V输出电流(A)
+	Temperature float64 `json:"temperature" hp:"dctemp"` // 温度(°C)
+
+	// 设备原始读数, 电压电流的单位为0.01V和0.01A
+	Dc12v int64 `json:"-" hp:"dc12v"`
+	Dc5v  int64 `json:"-" hp:"dc5v"`
+	Dc33v int64 `json:"-" hp:"dc33v"`
+	Dc12i int64 `json:"-" hp:"dc12i"`
+	Dc5i  int64 `json:"-" hp:"dc5i"`
+
+	Fans []*HPFan `json:"-" hp:"fan"`
+}
+
+// convertUnits 将电压电流的原始读数转换为V和A
+func (p *HPPowerSupply) convertUnits() {
+	p.Voltage12 = float64(p.Dc12v) / 100
+	p.Voltage5 = float64(p.Dc5v) / 100
+	p.Voltage33 = float64(p.Dc33v) / 100
+	p.Current12 = float64(p.Dc12i) / 100
+	p.Current5 = float64(p.Dc5i) / 100
+}
+
+type HPFan struct {
+	Id       string `json:"id" hp:"durable-id"`
+	Health   string `json:"health" hp:"health-numeric,health"`
+	Name     string `json:"name" hp:"name"`
+	Location string `json:"location" hp:"location"`
+	Status   string `json:"status" hp:"status-numeric,fan-status"`
+	Speed    int64  `json:"speed" hp:"speed"` // 转速(RPM)
+}
+
+// hpEnclosureComponents 机柜响应中的控制器和电源
+type hpEnclosureComponents struct {
+	Controllers   []*hpControllerComponents `hp:"controllers"`
+	PowerSupplies []*HPPowerSupply          `hp:"power-supplies"`
+}
+
+type hpControllerComponents struct {
+	Id                 string              `hp:"controller-id"`
+	Health             string              `hp:"health-numeric,health"`
+	NetworkStates      []*HPComponentState `hp:"network-parameters"`
+	PortStates         []*HPComponentState `hp:"port"`
+	ExpanderPortStates []*HPComponentState `hp:"expander-ports"`
+	CompactFlashStates []*HPComponentState `hp:"compact-flash"`
+}
+
 func (h *HPCrawlerData) PrintStr() {
 	data, _ := json.MarshalIndent(&h, "", "  ")
 	fmt.Println(string(data))
@@ -65,15 +135,21 @@ type HP struct {
 	AuthFile   string
 	AuthCookie string
 
+	StatisticFile   string // 上次采集的性能统计, 用于计算速率
+	EventCursorFile string // 各控制器已采集的事件序号
+	EventFile       string
+
 	Host string
 
 	Username string
 	Password string
 
+	Shell *HPShell // 使用SSH方式采集时不为空
+
 	CrawlerData *HPCrawlerData
 }
 
-func NewHPCrawler() (*HP, error) {
+func NewHPCrawler(conf HPConfig) (*HP, error) {
 	c := new(HP)
 
 	logger, err := NewLogger("hp.log")
@@ -81,13 +157,32 @@ func NewHPCrawler() (*HP, error) {
 		return nil, err
 	}
 	c.Log = logger
+	hpDecodeLog = logger
 
 	c.AuthFile = "cookie/hp.cookie"
 
+	c.StatisticFile = "data/hp_statistics.json"
+	c.EventCursorFile = "data/hp_event_cursor.json"
+	c.EventFile = "data/hp_event.json"
+
 	c.Host = "https://7.3.20.19"
 
-	c.Username = HPAccount
+	c.Username = strings.TrimSpace(HPAccount)
+	if len(conf.Username) > 0 {
+		c.Username = conf.Username
+	}
 	c.Password = HPPassword
+	if len(conf.Password) > 0 {
+		c.Password = conf.Password
+	}
+
+	if conf.Transport == "ssh" {
+		address := conf.SshAddress
+		if len(address) == 0 {
+			address = strings.TrimPrefix(strings.TrimPrefix(c.Host, "https://"), "http://") + ":22"
+		}
+		c.Shell = NewHPShell(address, c.Username, c.Password)
+	}
 
 	c.CrawlerData = new(HPCrawlerData)
 
@@ -104,6 +199,11 @@ func (c *HP) Start() {
 	if err := c.preStart(); err != nil {
 		return
 	}
+	if c.Shell != nil {
+		defer func() {
+			_ = c.Shell.Close()
+		}()
+	}
 
 	// 系统信息
 	if err := c.GetSystemInfo(); err != nil {
@@ -129,11 +229,56 @@ func (c *HP) Start() {
 	if err := c.GetVolumeGroupInfo(); err != nil {
 		return
 	}
+	// 卷信息
+	if err := c.GetVolumeInfo(); err != nil {
+		return
+	}
+	// 磁盘组信息
+	if err := c.GetDiskGroupInfo(); err != nil {
+		return
+	}
+	// 主机端口信息
+	if err := c.GetPortInfo(); err != nil {
+		return
+	}
+	// 传感器信息
+	if err := c.GetSensorInfo(); err != nil {
+		return
+	}
+	// 机柜信息
+	if err := c.GetEnclosureInfo(); err != nil {
+		return
+	}
+	// 控制器信息
+	if err := c.GetControllerInfo(); err != nil {
+		return
+	}
+	// 性能统计
+	if err := c.GetStatisticInfo(); err != nil {
+		return
+	}
+	// 事件日志
+	if err := c.GetEventInfo(); err != nil {
+		return
+	}
+	// 告警条件历史
+	if err := c.GetAlertConditionInfo(); err != nil {
+		return
+	}
 
 	c.CrawlerData.PrintStr()
 }
 
 func (c *HP) preStart() error {
+	if c.Shell != nil {
+		c.Log.Debugf("使用SSH方式采集, 地址: %s", c.Shell.Address)
+		if err := c.Shell.Connect(); err != nil {
+			c.Log.Errorf("SSH登录失败, error: %v", err)
+			return err
+		}
+		return nil
+	}
+
 	// 验证授权信息
 	if isExist(c.AuthFile) {
 		c.Log.Debug("检查到授权信息文件")
@@ -145,10 +290,10 @@ func (c *HP) preStart() error {
 			}
 		} else {
 			// 需要判断授权是否过期
-			if len(cookie) > 0 {
+			if len(cookie) > 0 && hpCookieUser(string(cookie)) == c.Username {
 				c.AuthCookie = string(cookie)
 			} else {
-				c.Log.Debug("授权信息文件为空, 执行登陆操作")
+				c.Log.Debug("授权信息文件为空或登录用户已变更, 执行登陆操作")
 				if err := c.Login(); err != nil {
 					c.Log.Errorf("登陆失败, 请重试, error: %v", err)
 					return err
@@ -163,25 +308,7 @@ func (c *HP) preStart() error {
 		}
 	}
 
-	// 设置本地语言为中文
-	client := &http.Client{Transport: &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-	}}
-	localRequest, _ := http.NewRequest("POST", c.Host+"/v3/api/", strings.NewReader("/api/set/cli-parameters/locale/Chinese-Simplified"))
-	localRequest.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	localRequest.Header.Set("Cookie", c.AuthCookie)
-	if localResp, err := client.Do(localRequest); err != nil {
-		c.Log.Errorf("设置本地语言为中文失败, error: %v", err)
-		return err
-	} else {
-		defer func() {
-			_ = localResp.Body.Close()
-		}()
-		if code := localResp.StatusCode; code != 200 {
-			c.Log.Errorf("设置本地语言为中文失败, 错误码: %d", code)
-			return errors.New(fmt.Sprintf("设置本地语言为中文失败, 错误码: %d", code))
-		}
-	}
+	// 只读采集, 不修改设备的CLI参数(如语言), 状态使用 *-numeric 属性解析, 与会话语言无关
 	return nil
 }
 
@@ -219,15 +346,14 @@ func (c *HP) Login() error {
 			return err
 		}
 		// 解析授权信息
-		doc := etree.NewDocument()
-		if err := doc.ReadFromBytes(body); err != nil {
+		objects, err := ParseHPResponse(string(body))
+		if err != nil {
 			c.Log.Errorf("解析请求结果数据失败, error: %v", err)
 			return err
 		}
-		element := doc.FindElement("/RESPONSE/OBJECT/PROPERTY[@name='response']")
-		session := element.Text()
+		_, session := hpStatus(objects)
 		if len(session) > 0 {
-			c.AuthCookie = "wbisessionkey=" + session + ";wbiusername=manage"
+			c.AuthCookie = "wbisessionkey=" + session + ";wbiusername=" + c.Username
 			if err := ioutil.WriteFile(c.AuthFile, []byte(c.AuthCookie), os.ModePerm); err != nil {
 				c.Log.Errorf("写入授权信息到文件失败, error: %v", err)
 				return err
@@ -239,6 +365,31 @@ func (c *HP) Login() error {
 	}
 }
 
+// hpCookieUser 获取授权信息中的登录用户(wbiusername)
+func hpCookieUser(cookie string) string {
+	items := strings.Split(cookie, ";")
+	for i := 0; i < len(items); i++ {
+		kv := strings.SplitN(strings.TrimSpace(items[i]), "=", 2)
+		if len(kv) == 2 && kv[0] == "wbiusername" {
+			return kv[1]
+		}
+	}
+	return ""
+}
+
+// Show 执行show命令, 返回XML格式的响应, command如 disks, volume-groups
+func (c *HP) Show(command string) (string, error) {
+	if c.Shell != nil {
+		data, err := c.Shell.Run("show " + strings.ReplaceAll(command, "/", " "))
+		if err != nil {
+			c.Log.Errorf("执行命令失败, command: show %s, error: %v", command, err)
+		}
+		return data, err
+	}
+	requestUrl := fmt.Sprintf("%s/v3/api/show/%s?_=%d", c.Host, command, time.Now().UnixNano()/1e6)
+	return c.RequestJson("GET", requestUrl, nil)
+}
+
 func (c *HP) RequestJson(method, url string, params io.Reader) (string, error) {
 	// 构造请求客户端
 	client := &http.Client{Transport: &http.Transport{
@@ -271,13 +422,12 @@ func (c *HP) RequestJson(method, url string, params io.Reader) (string, error) {
 			return "", err
 		} else {
 			// 判断业务状态码
-			doc := etree.NewDocument()
-			if err := doc.ReadFromBytes(body); err != nil {
+			objects, err := ParseHPResponse(string(body))
+			if err != nil {
 				c.Log.Errorf("解析请求体数据失败, url, %s, 错误信息: %v", url, err)
 				return "", err
 			}
-			response := doc.FindElement("/RESPONSE/OBJECT[@basetype='status']/PROPERTY[@name='response']").Text()
-			returnCode := doc.FindElement("/RESPONSE/OBJECT[@basetype='status']/PROPERTY[@name='return-code']").Text()
+			returnCode, response := hpStatus(objects)
 			if returnCode != "0" {
 				if returnCode == "-10027" {
 					_ = os.Remove(c.AuthFile)
@@ -297,242 +447,170 @@ func (c *HP) RequestJson(method, url string, params io.Reader) (string, error) {
 func (c *HP) GetSystemInfo() error {
 	c.Log.Debug("[REST]系统信息")
 
-	requestUrl := fmt.Sprintf("%s/v3/api/show/system?_=%d", c.Host, time.Now().UnixNano()/1e6)
-	if data, err := c.RequestJson("GET", requestUrl, nil); err != nil {
+	objects, err := c.ShowObjects("system")
+	if err != nil {
 		c.Log.Errorf("[REST]请求系统信息失败, error: %v", err)
 		return err
-	} else {
-		doc := etree.NewDocument()
-		if err := doc.ReadFromString(data); err != nil {
-			c.Log.Errorf("[REST]解析系统信息响应数据失败, error: %v", err)
-			return err
-		}
-		element := doc.FindElement("/RESPONSE/OBJECT/PROPERTY[@name='vendor-name']")
-		c.CrawlerData.VendorName = element.Text()
-
-		return nil
 	}
+	systems := make([]*hpSystem, 0)
+	if err := DecodeHPObjects(objects, "system", &systems); err != nil {
+		c.Log.Errorf("[REST]解析系统信息响应数据失败, error: %v", err)
+		return err
+	}
+	if len(systems) > 0 {
+		c.CrawlerData.VendorName = systems[0].VendorName
+	}
+	return nil
+}
+
+type hpSystem struct {
+	VendorName string `hp:"vendor-name"`
 }
 
 func (c *HP) GetVersionInfo() error {
 	c.Log.Debug("[REST]版本信息")
 
-	requestUrl := fmt.Sprintf("%s/v3/api/show/version?_=%d", c.Host, time.Now().UnixNano()/1e6)
-	if data, err := c.RequestJson("GET", requestUrl, nil); err != nil {
+	objects, err := c.ShowObjects("version")
+	if err != nil {
 		c.Log.Errorf("[REST]请求版本信息失败, error: %v", err)
 		return err
-	} else {
-		doc := etree.NewDocument()
-		if err := doc.ReadFromString(data); err != nil {
-			c.Log.Errorf("[REST]解析版本信息响应数据失败, error: %v", err)
-			return err
-		}
-		elements := doc.FindElements("/RESPONSE/OBJECT[@basetype='versions']/PROPERTY[@name='bundle-version']")
-		for i := 0; i < len(elements); i++ {
-			c.CrawlerData.BundleVersions = append(c.CrawlerData.BundleVersions, elements[i].Text())
-		}
-
-		return nil
 	}
+	versions := make([]*hpVersion, 0)
+	if err := DecodeHPObjects(objects, "versions", &versions); err != nil {
+		c.Log.Errorf("[REST]解析版本信息响应数据失败, error: %v", err)
+		return err
+	}
+	for i := 0; i < len(versions); i++ {
+		c.CrawlerData.BundleVersions = append(c.CrawlerData.BundleVersions, versions[i].BundleVersion)
+	}
+	return nil
+}
+
+type hpVersion struct {
+	BundleVersion string `hp:"bundle-version"`
 }
 
 func (c *HP) GetComponentState() error {
 	c.Log.Debug("[REST]组件状态(不包括磁盘)")
 
-	requestUrl := fmt.Sprintf("%s/v3/api/show/enclosures?_=%d", c.Host, time.Now().UnixNano()/1e6)
-	if data, err := c.RequestJson("GET", requestUrl, nil); err != nil {
+	objects, err := c.ShowObjects("enclosures")
+	if err != nil {
 		c.Log.Errorf("[REST]请求组件状态失败, error: %v", err)
 		return err
-	} else {
-		doc := etree.NewDocument()
-		if err := doc.ReadFromString(data); err != nil {
-			c.Log.Errorf("[REST]解析组件状态响应数据失败, error: %v", err)
-			return err
-		}
+	}
+	enclosures := make([]*hpEnclosureComponents, 0)
+	if err := DecodeHPObjects(objects, "enclosures", &enclosures); err != nil {
+		c.Log.Errorf("[REST]解析组件状态响应数据失败, error: %v", err)
+		return err
+	}
 
+	for i := 0; i < len(enclosures); i++ {
 		// 控制器状态
-		cElems := doc.FindElements("/RESPONSE/OBJECT/OBJECT[@basetype='controllers']")
-		for i := 0; i < len(cElems); i++ {
-			id := cElems[i].FindElement("./PROPERTY[@name='controller-id']").Text()
-			health := cElems[i].FindElement("./PROPERTY[@name='health']").Text()
-
-			cState := make(map[string]interface{})
-			cState["id"] = id
-			cState["health"] = health
-			c.CrawlerData.ControllerStates = append(c.CrawlerData.ControllerStates, cState)
-
-			childElems := []string{
-				"./OBJECT[@basetype='network-parameters']", // 网络端口状态
-				"./OBJECT[@basetype='port']",               // 主机端口状态
-				"./OBJECT[@basetype='expander-ports']",     // 扩展端口状态
-				"./OBJECT[@basetype='compact-flash']",      // CompactFlash状态
-			}
-			for j := 0; j < len(childElems); j++ {
-				stateElems := cElems[i].FindElements(childElems[j])
-				for k := 0; k < len(stateElems); k++ {
-					id := stateElems[k].FindElement("./PROPERTY[@name='durable-id']").Text()
-					health := stateElems[k].FindElement("./PROPERTY[@name='health']").Text()
-
-					state := make(map[string]interface{})
-					state["id"] = id
-					state["health"] = health
-					switch j {
-					case 0:
-						c.CrawlerData.NetworkStates = append(c.CrawlerData.NetworkStates, state)
-					case 1:
-						c.CrawlerData.PortStates = append(c.CrawlerData.PortStates, state)
-					case 2:
-						c.CrawlerData.ExpanderPortStates = append(c.CrawlerData.ExpanderPortStates, state)
-					case 3:
-						c.CrawlerData.CompactFlashStates = append(c.CrawlerData.CompactFlashStates, state)
-					}
-				}
-			}
+		controllers := enclosures[i].Controllers
+		for j := 0; j < len(controllers); j++ {
+			ctrl := controllers[j]
+			c.CrawlerData.ControllerStates = append(c.CrawlerData.ControllerStates, &HPComponentState{Id: ctrl.Id, Health: ctrl.Health})
+			c.CrawlerData.NetworkStates = append(c.CrawlerData.NetworkStates, ctrl.NetworkStates...)
+			c.CrawlerData.PortStates = append(c.CrawlerData.PortStates, ctrl.PortStates...)
+			c.CrawlerData.ExpanderPortStates = append(c.CrawlerData.ExpanderPortStates, ctrl.ExpanderPortStates...)
+			c.CrawlerData.CompactFlashStates = append(c.CrawlerData.CompactFlashStates, ctrl.CompactFlashStates...)
 		}
 
-		// 电源状态
-		psElems := doc.FindElements("/RESPONSE/OBJECT/OBJECT[@basetype='power-supplies']")
-		for i := 0; i < len(psElems); i++ {
-			id := psElems[i].FindElement("./PROPERTY[@name='durable-id']").Text()
-			health := psElems[i].FindElement("./PROPERTY[@name='health']").Text()
-
-			psState := make(map[string]interface{})
-			psState["id"] = id
-			psState["health"] = health
-			c.CrawlerData.PowerSuppliesStates = append(c.CrawlerData.PowerSuppliesStates, psState)
-
-			// 风扇状态
-			fElems := psElems[i].FindElements("./OBJECT[@basetype='fan']")
-			for i := 0; i < len(fElems); i++ {
-				id := fElems[i].FindElement("./PROPERTY[@name='durable-id']").Text()
-				health := fElems[i].FindElement("./PROPERTY[@name='health']").Text()
-
-				fState := make(map[string]interface{})
-				fState["id"] = id
-				fState["health"] = health
-				c.CrawlerData.FanStates = append(c.CrawlerData.FanStates, fState)
-			}
+		// 电源和风扇状态
+		powerSupplies := enclosures[i].PowerSupplies
+		for j := 0; j < len(powerSupplies); j++ {
+			powerSupplies[j].convertUnits()
+			c.CrawlerData.PowerSuppliesStates = append(c.CrawlerData.PowerSuppliesStates, powerSupplies[j])
+			c.CrawlerData.FanStates = append(c.CrawlerData.FanStates, powerSupplies[j].Fans...)
 		}
-
-		return nil
 	}
+	return nil
 }
 
 func (c *HP) GetDiskInfo() error {
 	c.Log.Debug("[REST]磁盘信息")
 
-	requestUrl := fmt.Sprintf("%s/v3/api/show/disks?_=%d", c.Host, time.Now().UnixNano()/1e6)
-	if data, err := c.RequestJson("GET", requestUrl, nil); err != nil {
+	objects, err := c.ShowObjects("disks")
+	if err != nil {
 		c.Log.Errorf("[REST]请求磁盘信息失败, error: %v", err)
 		return err
-	} else {
-		doc := etree.NewDocument()
-		if err := doc.ReadFromString(data); err != nil {
-			c.Log.Errorf("[REST]解析磁盘信息响应数据失败, error: %v", err)
-			return err
-		}
+	}
+	if err := DecodeHPObjects(objects, "drives", &c.CrawlerData.DiskInfo); err != nil {
+		c.Log.Errorf("[REST]解析磁盘信息响应数据失败, error: %v", err)
+		return err
+	}
 
-		elems := doc.FindElements("/RESPONSE/OBJECT[@basetype='drives']")
-		for i := 0; i < len(elems); i++ {
-			// ID
-			id := elems[i].FindElement("./PROPERTY[@name='durable-id']").Text()
-			// 运行状态
-			health := elems[i].FindElement("./PROPERTY[@name='health']").Text()
-			// 描述
-			description := elems[i].FindElement("./PROPERTY[@name='description']").Text()
-			// 大小
-			size := elems[i].FindElement("./PROPERTY[@name='size']").Text()
-			// 状态
-			status := elems[i].FindElement("./PROPERTY[@name='status']").Text()
-
-			diskInfo := make(map[string]interface{})
-			diskInfo["id"] = id
-			diskInfo["health"] = health
-			diskInfo["description"] = description
-			diskInfo["size"] = size
-			diskInfo["status"] = status
-			c.CrawlerData.DiskInfo = append(c.CrawlerData.DiskInfo, diskInfo)
-
-			// 使用情况
-			usageNumeric := elems[i].FindElement("./PROPERTY[@name='usage-numeric']").Text()
-			// 大小
-			sizeNumeric := elems[i].FindElement("./PROPERTY[@name='size-numeric']").Text()
-			sizeNumericInt, _ := strconv.ParseInt(sizeNumeric, 10, 64)
-
-			c.CrawlerData.SizeTotal += sizeNumericInt * 512
-			switch usageNumeric {
-			case "2", "3":
-				c.CrawlerData.SizeSpares += sizeNumericInt * 512
-			case "9":
-				c.CrawlerData.SizeVirtual += sizeNumericInt * 512
-			}
-		}
+	for i := 0; i < len(c.CrawlerData.DiskInfo); i++ {
+		disk := c.CrawlerData.DiskInfo[i]
 
-		return nil
+		c.CrawlerData.SizeTotal += disk.SizeBytes
+		switch disk.UsageNumeric {
+		case "2", "3":
+			c.CrawlerData.SizeSpares += disk.SizeBytes
+		case "9":
+			c.CrawlerData.SizeVirtual += disk.SizeBytes
+		}
 	}
+	return nil
 }
 
 func (c *HP) GetPoolInfo() error {
 	c.Log.Debug("[REST]存储池信息")
 
-	requestUrl := fmt.Sprintf("%s/v3/api/show/pools?_=%d", c.Host, time.Now().UnixNano()/1e6)
-	if data, err := c.RequestJson("GET", requestUrl, nil); err != nil {
+	objects, err := c.ShowObjects("pools")
+	if err != nil {
 		c.Log.Errorf("[REST]请求存储池信息失败, error: %v", err)
 		return err
-	} else {
-		doc := etree.NewDocument()
-		if err := doc.ReadFromString(data); err != nil {
-			c.Log.Errorf("[REST]解析存储池信息响应数据失败, error: %v", err)
-			return err
-		}
-
-		elems := doc.FindElements("/RESPONSE/OBJECT[@basetype='pools']")
-		for i := 0; i < len(elems); i++ {
-			// 页面大小（块）（8192）
-			pageSize := elems[i].FindElement("./PROPERTY[@name='page-size-numeric']").Text()
-			pageSizeInt, _ := strconv.Atoi(pageSize)
-			// 分配的页数
-			allocatedPages := elems[i].FindElement("./PROPERTY[@name='allocated-pages']").Text()
-			allocatedPagesInt, _ := strconv.ParseInt(allocatedPages, 10, 64)
+	}
+	if err := DecodeHPObjects(objects, "pools", &c.CrawlerData.Pools); err != nil {
+		c.Log.Errorf("[REST]解析存储池信息响应数据失败, error: %v", err)
+		return err
+	}
 
-			c.CrawlerData.VirtPoolAllocSizeTotal += int64(pageSizeInt) * allocatedPagesInt * 512
-		}
+	for i := 0; i < len(c.CrawlerData.Pools); i++ {
+		pool := c.CrawlerData.Pools[i]
+		pool.AllocatedBytes = pool.PageSize * pool.AllocatedPages * HPBlockSize
 
-		return nil
+		c.CrawlerData.VirtPoolAllocSizeTotal += pool.AllocatedBytes
 	}
+	return nil
+}
+
+type hpVolumeGroup struct {
+	Volumes []*hpVolumeGroupVolume `hp:"volumes"`
+}
+
+type hpVolumeGroupVolume struct {
+	VolumeType string `hp:"volume-type-numeric"`
+	SizeBytes  int64  `hp:"size-numeric,blocks"`
 }
 
 func (c *HP) GetVolumeGroupInfo() error {
 	c.Log.Debug("[REST]卷组信息")
 
-	requestUrl := fmt.Sprintf("%s/v3/api/show/volume-groups?_=%d", c.Host, time.Now().UnixNano()/1e6)
-	if data, err := c.RequestJson("GET", requestUrl, nil); err != nil {
+	objects, err := c.ShowObjects("volume-groups")
+	if err != nil {
 		c.Log.Errorf("[REST]请求卷组信息失败, error: %v", err)
 		return err
-	} else {
-		doc := etree.NewDocument()
-		if err := doc.ReadFromString(data); err != nil {
-			c.Log.Errorf("[REST]解析存储池信息响应数据失败, error: %v", err)
-			return err
-		}
+	}
+	groups := make([]*hpVolumeGroup, 0)
+	if err := DecodeHPObjects(objects, "volume-groups", &groups); err != nil {
+		c.Log.Errorf("[REST]解析卷组信息响应数据失败, error: %v", err)
+		return err
+	}
 
-		var volumeTotalSize int64
-		// 计算总页数
-		elems := doc.FindElements("/RESPONSE/OBJECT/OBJECT[@basetype='volumes']")
-		for i := 0; i < len(elems); i++ {
-			sizeNumeric := elems[i].FindElement("./PROPERTY[@name='size-numeric']").Text()
-			sizeNumericInt, _ := strconv.ParseInt(sizeNumeric, 10, 64)
-
-			volumeTypeNumeric := elems[i].FindElement("./PROPERTY[@name='volume-type-numeric']").Text()
-			if volumeTypeNumeric == "0" || volumeTypeNumeric == "2" ||
-				volumeTypeNumeric == "4" || volumeTypeNumeric == "8" ||
-				volumeTypeNumeric == "13" || volumeTypeNumeric == "15" {
-				volumeTotalSize += sizeNumericInt * 512
+	var volumeTotalSize int64
+	for i := 0; i < len(groups); i++ {
+		volumes := groups[i].Volumes
+		for j := 0; j < len(volumes); j++ {
+			switch volumes[j].VolumeType {
+			case "0", "2", "4", "8", "13", "15":
+				volumeTotalSize += volumes[j].SizeBytes
 			}
 		}
+	}
 
-		c.CrawlerData.VirtUnallocSizeTotal = volumeTotalSize - c.CrawlerData.VirtPoolAllocSizeTotal
+	c.CrawlerData.VirtUnallocSizeTotal = volumeTotalSize - c.CrawlerData.VirtPoolAllocSizeTotal
 
-		return nil
-	}
+	return nil
 }
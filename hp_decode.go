@@ -0,0 +1,362 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// hpDecodeLog 解码时的警告日志, 创建采集任务时设置为任务的日志
+var hpDecodeLog = zap.NewNop().Sugar()
+
+// errHPNotInteger 整数字段的属性值不是整数, 不同固件的格式可能不同, 字段保持为0, 不影响其他数据
+var errHPNotInteger = errors.New("属性值不是整数")
+
+// hpEnumNames *-numeric属性值对应的名称, 文本属性会随会话语言变化, 解析状态时使用数值属性
+var hpEnumNames = map[string]map[string]string{
+	"health": {
+		"0": "OK",
+		"1": "Degraded",
+		"2": "Fault",
+		"3": "Unknown",
+		"4": "N/A",
+	},
+	"severity": {
+		"0": "INFORMATIONAL",
+		"1": "ERROR",
+		"2": "WARNING",
+		"3": "CRITICAL",
+		"4": "RESOLVED",
+	},
+	// 机柜, 磁盘等部件的状态
+	"status": {
+		"0":  "Unsupported",
+		"1":  "Up",
+		"2":  "Error",
+		"3":  "Warning",
+		"4":  "Unrecoverable",
+		"5":  "Not Present",
+		"6":  "Unknown",
+		"7":  "Unavailable",
+		"20": "Spun Down",
+	},
+	"controller-status": {
+		"0": "Operational",
+		"1": "Down",
+		"2": "Not Installed",
+	},
+	"disk-group-status": {
+		"0":   "FTOL",
+		"1":   "FTDN",
+		"2":   "CRIT",
+		"3":   "OFFL",
+		"4":   "QTCR",
+		"5":   "QTOF",
+		"6":   "QTDN",
+		"7":   "STOP",
+		"8":   "MSNG",
+		"9":   "DMGD",
+		"250": "UP",
+	},
+	"port-status": {
+		"0": "Up",
+		"1": "Warning",
+		"2": "Error",
+		"3": "Not Present",
+		"4": "Unknown",
+		"6": "Disconnected",
+	},
+	"sensor-status": {
+		"0": "Unsupported",
+		"1": "OK",
+		"2": "Critical",
+		"3": "Warning",
+		"4": "Unrecoverable",
+		"5": "Not Installed",
+		"6": "Unknown",
+		"7": "Unavailable",
+	},
+	"sensor-type": {
+		"0": "Temperature",
+		"1": "Current",
+		"2": "Voltage",
+		"3": "Charge Capacity",
+		"4": "Unknown",
+	},
+	"power-supply-status": {
+		"0": "Up",
+		"1": "Warning",
+		"2": "Error",
+		"3": "Not Present",
+		"4": "Unknown",
+	},
+	"fan-status": {
+		"0": "Up",
+		"1": "Error",
+		"2": "Off",
+		"3": "Missing",
+	},
+}
+
+// hpEnumName 获取数值属性对应的名称, 未定义时返回原值
+func hpEnumName(enum, value string) string {
+	if name, ok := hpEnumNames[enum][value]; ok {
+		return name
+	}
+	return value
+}
+
+// HPObject MSA接口响应中的OBJECT元素, 包含PROPERTY属性和嵌套的OBJECT子对象
+type HPObject struct {
+	BaseType   string
+	Name       string
+	Oid        string
+	Properties map[string]string
+	Keys       []string // 属性名称, 保持响应中的顺序
+	Objects    []*HPObject
+}
+
+type hpXMLResponse struct {
+	XMLName xml.Name      `xml:"RESPONSE"`
+	Objects []hpXMLObject `xml:"OBJECT"`
+}
+
+type hpXMLObject struct {
+	BaseType   string          `xml:"basetype,attr"`
+	Name       string          `xml:"name,attr"`
+	Oid        string          `xml:"oid,attr"`
+	Properties []hpXMLProperty `xml:"PROPERTY"`
+	Objects    []hpXMLObject   `xml:"OBJECT"`
+}
+
+type hpXMLProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:",chardata"`
+}
+
+// ParseHPResponse 解析MSA接口响应, 返回RESPONSE下的对象
+func ParseHPResponse(data string) ([]*HPObject, error) {
+	resp := new(hpXMLResponse)
+	if err := xml.Unmarshal([]byte(data), resp); err != nil {
+		return nil, err
+	}
+	objects := make([]*HPObject, len(resp.Objects))
+	for i := 0; i < len(resp.Objects); i++ {
+		objects[i] = newHPObject(&resp.Objects[i])
+	}
+	return objects, nil
+}
+
+func newHPObject(e *hpXMLObject) *HPObject {
+	o := new(HPObject)
+	o.BaseType = e.BaseType
+	o.Name = e.Name
+	o.Oid = e.Oid
+	o.Properties = make(map[string]string)
+	for i := 0; i < len(e.Properties); i++ {
+		name := e.Properties[i].Name
+		if _, ok := o.Properties[name]; !ok {
+			o.Keys = append(o.Keys, name)
+		}
+		o.Properties[name] = e.Properties[i].Value
+	}
+	for i := 0; i < len(e.Objects); i++ {
+		o.Objects = append(o.Objects, newHPObject(&e.Objects[i]))
+	}
+	return o
+}
+
+// hpStatus 获取响应中status对象的返回码和返回信息
+func hpStatus(objects []*HPObject) (string, string) {
+	statuses := filterHPObjects(objects, "status")
+	if len(statuses) == 0 {
+		return "", ""
+	}
+	return statuses[0].Property("return-code"), statuses[0].Property("response")
+}
+
+// Property 获取属性值, 属性不存在时返回空字符串
+func (o *HPObject) Property(name string) string {
+	return o.Properties[name]
+}
+
+// Children 获取指定basetype的子对象
+func (o *HPObject) Children(basetype string) []*HPObject {
+	return filterHPObjects(o.Objects, basetype)
+}
+
+// MarshalJSON 属性按名称输出, 子对象按basetype分组
+func (o *HPObject) MarshalJSON() ([]byte, error) {
+	m := make(map[string]interface{})
+	m["oid"] = o.Oid
+	m["basetype"] = o.BaseType
+	for i := 0; i < len(o.Keys); i++ {
+		m[o.Keys[i]] = o.Properties[o.Keys[i]]
+	}
+	for i := 0; i < len(o.Objects); i++ {
+		child := o.Objects[i]
+		children, _ := m[child.BaseType].([]*HPObject)
+		m[child.BaseType] = append(children, child)
+	}
+	return json.Marshal(m)
+}
+
+func filterHPObjects(objects []*HPObject, basetype string) []*HPObject {
+	result := make([]*HPObject, 0)
+	for i := 0; i < len(objects); i++ {
+		if objects[i].BaseType == basetype {
+			result = append(result, objects[i])
+		}
+	}
+	return result
+}
+
+// ShowObjects 执行show命令并解析响应中的对象, command如 volumes, disk-groups
+func (c *HP) ShowObjects(command string) ([]*HPObject, error) {
+	data, err := c.Show(command)
+	if err != nil {
+		return nil, err
+	}
+	return ParseHPResponse(data)
+}
+
+// DecodeHPObjects 将指定basetype的对象解码到结构体切片, v为切片指针, 元素为结构体或结构体指针
+//
+// 结构体字段通过hp标签指定属性名称, 如 hp:"volume-name", 没有hp标签的字段不解码.
+// 整数字段只接受整数值, 其他格式的值记录警告日志, 字段为0; 容量使用 *-numeric 属性并指定标签选项blocks(乘以块大小HPBlockSize转换为字节).
+// 浮点数字段支持带单位的读数(如 "35 C", "100%"), 解码时去掉单位.
+// 标签选项为hpEnumNames中的名称时, 将数值属性转换为名称, 如 hp:"health-numeric,health".
+// hp:"@oid", hp:"@basetype", hp:"@name" 对应对象的属性, 切片字段对应basetype为标签名称的子对象.
+func DecodeHPObjects(objects []*HPObject, basetype string, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Slice {
+		return errors.New("解码目标必须是切片指针")
+	}
+	return decodeHPSlice(filterHPObjects(objects, basetype), rv.Elem())
+}
+
+// DecodeHPObject 将对象解码到结构体, v为结构体指针
+func DecodeHPObject(o *HPObject, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("解码目标必须是结构体指针")
+	}
+	return decodeHPStruct(o, rv.Elem())
+}
+
+func decodeHPSlice(objects []*HPObject, slice reflect.Value) error {
+	elemType := slice.Type().Elem()
+	isPtr := elemType.Kind() == reflect.Ptr
+	if isPtr {
+		elemType = elemType.Elem()
+	}
+	if elemType.Kind() != reflect.Struct {
+		return fmt.Errorf("不支持的切片元素类型: %s", slice.Type().Elem())
+	}
+	for i := 0; i < len(objects); i++ {
+		elem := reflect.New(elemType)
+		if err := decodeHPStruct(objects[i], elem.Elem()); err != nil {
+			return err
+		}
+		if isPtr {
+			slice.Set(reflect.Append(slice, elem))
+		} else {
+			slice.Set(reflect.Append(slice, elem.Elem()))
+		}
+	}
+	return nil
+}
+
+func decodeHPStruct(o *HPObject, rv reflect.Value) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		tag, ok := field.Tag.Lookup("hp")
+		if !ok || tag == "-" {
+			continue
+		}
+		name, option := tag, ""
+		if idx := strings.Index(tag, ","); idx >= 0 {
+			name, option = tag[:idx], tag[idx+1:]
+		}
+
+		fv := rv.Field(i)
+		if fv.Kind() == reflect.Slice {
+			// 子对象
+			if err := decodeHPSlice(o.Children(name), fv); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var value string
+		switch name {
+		case "@oid":
+			value = o.Oid
+		case "@basetype":
+			value = o.BaseType
+		case "@name":
+			value = o.Name
+		default:
+			value = o.Property(name)
+			if _, ok := hpEnumNames[option]; ok {
+				// 设备不返回数值属性时使用文本属性
+				if _, ok := o.Properties[name]; !ok {
+					value = o.Property(strings.TrimSuffix(name, "-numeric"))
+				}
+				value = hpEnumName(option, value)
+			}
+		}
+		if err := setHPValue(fv, value, option); errors.Is(err, errHPNotInteger) {
+			hpDecodeLog.Warnf("解码对象[%s]的属性[%s]失败, 使用0, error: %v", o.BaseType, name, err)
+		} else if err != nil {
+			return fmt.Errorf("解码属性[%s]失败, error: %v", name, err)
+		}
+	}
+	return nil
+}
+
+func setHPValue(fv reflect.Value, value, option string) error {
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var number int64
+		if len(value) > 0 && value != "N/A" {
+			n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			if err != nil {
+				// 带单位的容量(如 1.2TB)的换算方式取决于会话的单位设置, 需要使用 *-numeric 属性
+				fv.SetInt(0)
+				return fmt.Errorf("%w: %s", errHPNotInteger, value)
+			}
+			number = n
+		}
+		if option == "blocks" {
+			number *= HPBlockSize
+		}
+		fv.SetInt(number)
+	case reflect.Float32, reflect.Float64:
+		number, _, _ := parseHPSensorValue(value)
+		if option == "blocks" {
+			number *= HPBlockSize
+		}
+		fv.SetFloat(number)
+	case reflect.Bool:
+		// 布尔属性在接口中为 true/false 或 Enabled/Disabled 等
+		switch strings.ToLower(value) {
+		case "true", "yes", "enabled", "on", "1":
+			fv.SetBool(true)
+		default:
+			fv.SetBool(false)
+		}
+	default:
+		return fmt.Errorf("不支持的字段类型: %s", fv.Type())
+	}
+	return nil
+}
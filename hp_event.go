@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"time"
+)
+
+type HPEvent struct {
+	EventId           string `json:"eventId" hp:"event-id"` // 事件ID, 控制器 + 序号, 如 A1234
+	Controller        string `json:"controller"`
+	Serial            int64  `json:"serial"` // 事件序号
+	EventCode         string `json:"eventCode" hp:"event-code"`
+	Severity          string `json:"severity" hp:"severity-numeric,severity"` // 级别(INFORMATIONAL, WARNING, ERROR, CRITICAL, RESOLVED)
+	TimeStamp         int64  `json:"timeStamp" hp:"time-stamp-numeric"`
+	Message           string `json:"message" hp:"message"`
+	RecommendedAction string `json:"recommendedAction" hp:"recommended-action"`
+}
+
+type HPAlertCondition struct {
+	Id           string `json:"id" hp:"id"`
+	Severity     string `json:"severity" hp:"severity-numeric,severity"`
+	Component    string `json:"component" hp:"component"`
+	Reason       string `json:"reason" hp:"reason"`
+	DetectedTime string `json:"detectedTime" hp:"detected-time"`
+	Resolved     string `json:"resolved" hp:"resolved"`
+}
+
+// HPEventLastCount 每次读取最近的事件数量, show events 不指定数量时只返回设备默认的少量事件
+const HPEventLastCount = 1000
+
+// GetEventInfo 读取上次采集之后的事件, 以各控制器的事件序号作为游标
+func (c *HP) GetEventInfo() error {
+	c.Log.Debug("[REST]事件日志")
+
+	objects, err := c.ShowObjects("events/last/" + strconv.Itoa(HPEventLastCount))
+	if err != nil {
+		c.Log.Errorf("[REST]请求事件日志失败, error: %v", err)
+		return err
+	}
+	items := make([]*HPEvent, 0)
+	if err := DecodeHPObjects(objects, "events", &items); err != nil {
+		c.Log.Errorf("[REST]解析事件日志失败, error: %v", err)
+		return err
+	}
+
+	cursor := make(map[string]int64)
+	if data, err := ioutil.ReadFile(c.EventCursorFile); err == nil {
+		if err := json.Unmarshal(data, &cursor); err != nil {
+			c.Log.Errorf("解析事件游标文件失败, error: %v", err)
+		}
+	}
+
+	// 本次返回的各控制器最小和最大事件序号
+	minSerial := make(map[string]int64)
+	maxSerial := make(map[string]int64)
+	for i := 0; i < len(items); i++ {
+		event := items[i]
+		event.Controller, event.Serial = parseHPEventId(event.EventId)
+		if v, ok := minSerial[event.Controller]; !ok || event.Serial < v {
+			minSerial[event.Controller] = event.Serial
+		}
+		if event.Serial > maxSerial[event.Controller] {
+			maxSerial[event.Controller] = event.Serial
+		}
+	}
+	for controller, serial := range maxSerial {
+		// 更换控制器后事件序号重新开始, 重置游标
+		if serial < cursor[controller] {
+			c.Log.Warnf("控制器%s的事件序号(%d)小于游标(%d), 重置游标", controller, serial, cursor[controller])
+			cursor[controller] = 0
+			continue
+		}
+		// 两次采集之间的事件超过了读取数量
+		if cursor[controller] > 0 && minSerial[controller] > cursor[controller]+1 {
+			c.Log.Warnf("控制器%s的事件序号%d-%d未能读取, 可能丢失事件", controller, cursor[controller]+1, minSerial[controller]-1)
+		}
+	}
+
+	nextCursor := make(map[string]int64)
+	for k, v := range cursor {
+		nextCursor[k] = v
+	}
+
+	events := make([]*Event, 0)
+	for i := 0; i < len(items); i++ {
+		event := items[i]
+
+		// 已采集过的事件
+		if event.Serial <= cursor[event.Controller] {
+			continue
+		}
+		if event.Serial > nextCursor[event.Controller] {
+			nextCursor[event.Controller] = event.Serial
+		}
+
+		c.CrawlerData.Events = append(c.CrawlerData.Events, event)
+		switch event.Severity {
+		case "CRITICAL":
+			c.CrawlerData.CriticalEventCount++
+		case "ERROR":
+			c.CrawlerData.ErrorEventCount++
+		case "WARNING":
+			c.CrawlerData.WarningEventCount++
+		}
+		events = append(events, &Event{
+			Time:     time.Unix(event.TimeStamp, 0).Format("2006-01-02 15:04:05"),
+			Source:   "hp",
+			Type:     EventLog,
+			Id:       event.EventCode,
+			Level:    event.Severity,
+			Location: event.Controller,
+			Message:  event.Message,
+		})
+	}
+
+	if err := AppendEvents(c.EventFile, events); err != nil {
+		c.Log.Errorf("写入事件失败, error: %v", err)
+		return err
+	}
+	data, _ := json.Marshal(nextCursor)
+	if err := ioutil.WriteFile(c.EventCursorFile, data, os.ModePerm); err != nil {
+		c.Log.Errorf("写入事件游标文件失败, error: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (c *HP) GetAlertConditionInfo() error {
+	c.Log.Debug("[REST]告警条件历史")
+
+	objects, err := c.ShowObjects("alert-condition-history")
+	if err != nil {
+		c.Log.Errorf("[REST]请求告警条件历史失败, error: %v", err)
+		return err
+	}
+	if err := DecodeHPObjects(objects, "alert-condition-history", &c.CrawlerData.AlertConditions); err != nil {
+		c.Log.Errorf("[REST]解析告警条件历史失败, error: %v", err)
+		return err
+	}
+	return nil
+}
+
+// parseHPEventId 解析事件ID, A1234 -> (A, 1234)
+func parseHPEventId(eventId string) (string, int64) {
+	if len(eventId) < 2 {
+		return eventId, 0
+	}
+	serial, _ := strconv.ParseInt(eventId[1:], 10, 64)
+	return eventId[:1], serial
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// HPBlockSize MSA接口中 *-numeric 容量属性的单位(512字节块)
+const HPBlockSize = 512
+
+type HPDisk struct {
+	Id           string `json:"id" hp:"durable-id"`
+	Location     string `json:"location" hp:"location"`
+	SerialNumber string `json:"serialNumber" hp:"serial-number"`
+	Vendor       string `json:"vendor" hp:"vendor"`
+	Model        string `json:"model" hp:"model"`
+	Revision     string `json:"revision" hp:"revision"` // 固件版本
+	Description  string `json:"description" hp:"description"`
+	Usage        string `json:"usage" hp:"usage"`
+	UsageNumeric string `json:"-" hp:"usage-numeric"` // 使用情况(2, 3为全局备用磁盘, 9为虚拟磁盘组)
+	DiskGroup    string `json:"diskGroup" hp:"disk-group"`
+	StorageTier  string `json:"storageTier" hp:"storage-tier"`
+	Size         string `json:"size" hp:"size"`
+	SizeBytes    int64  `json:"sizeBytes" hp:"size-numeric,blocks"`
+	Status       string `json:"status" hp:"status-numeric,status"`
+	Health       string `json:"health" hp:"health-numeric,health"`
+	HealthReason string `json:"healthReason" hp:"health-reason"`
+}
+
+type HPPool struct {
+	Name            string `json:"name" hp:"name"`
+	SerialNumber    string `json:"serialNumber" hp:"serial-number"`
+	StorageType     string `json:"storageType" hp:"storage-type"` // 存储类型(Virtual, Linear)
+	Owner           string `json:"owner" hp:"owner"`
+	TotalSize       string `json:"totalSize" hp:"total-size"`
+	TotalSizeBytes  int64  `json:"totalSizeBytes" hp:"total-size-numeric,blocks"`
+	TotalAvail      string `json:"totalAvail" hp:"total-avail"`
+	TotalAvailBytes int64  `json:"totalAvailBytes" hp:"total-avail-numeric,blocks"`
+	PageSize        int64  `json:"-" hp:"page-size-numeric"` // 页面大小(块)(8192)
+	AllocatedPages  int64  `json:"-" hp:"allocated-pages"`   // 已分配页数
+	AllocatedBytes  int64  `json:"allocatedBytes"`           // 已分配容量(Byte), 页面大小 * 已分配页数
+	Health          string `json:"health" hp:"health-numeric,health"`
+	HealthReason    string `json:"healthReason" hp:"health-reason"`
+}
+
+type HPVolume struct {
+	DurableId          string `json:"durableId" hp:"durable-id"`
+	Name               string `json:"name" hp:"volume-name"`
+	SerialNumber       string `json:"serialNumber" hp:"serial-number"`
+	Wwn                string `json:"wwn" hp:"wwn"`
+	PoolName           string `json:"poolName" hp:"storage-pool-name"`
+	VolumeType         string `json:"volumeType" hp:"volume-type"` // 卷类型(base, standard, snapshot)
+	Owner              string `json:"owner" hp:"owner"`            // 所属控制器
+	TierAffinity       string `json:"tierAffinity" hp:"tier-affinity"`
+	Size               string `json:"size" hp:"size"`                                        // 容量(带单位)
+	SizeBytes          int64  `json:"sizeBytes" hp:"size-numeric,blocks"`                    // 容量(Byte)
+	AllocatedSize      string `json:"allocatedSize" hp:"allocated-size"`                     // 已分配容量(带单位)
+	AllocatedSizeBytes int64  `json:"allocatedSizeBytes" hp:"allocated-size-numeric,blocks"` // 已分配容量(Byte)
+	Health             string `json:"health" hp:"health-numeric,health"`
+	HealthReason       string `json:"healthReason" hp:"health-reason"`
+}
+
+type HPDiskGroup struct {
+	Name              string `json:"name" hp:"name"`
+	SerialNumber      string `json:"serialNumber" hp:"serial-number"`
+	PoolName          string `json:"poolName" hp:"pool"`
+	RaidType          string `json:"raidType" hp:"raidtype"`
+	StorageTier       string `json:"storageTier" hp:"storage-tier"`
+	Owner             string `json:"owner" hp:"owner"`
+	DiskCount         int64  `json:"diskCount" hp:"diskcount"`
+	Size              string `json:"size" hp:"size"`
+	SizeBytes         int64  `json:"sizeBytes" hp:"size-numeric,blocks"`
+	FreeSpace         string `json:"freeSpace" hp:"freespace"`
+	FreeSpaceBytes    int64  `json:"freeSpaceBytes" hp:"freespace-numeric,blocks"`
+	Status            string `json:"status" hp:"status-numeric,disk-group-status"`
+	CurrentJob        string `json:"currentJob" hp:"current-job"`                   // 当前任务(重构, 校验等)
+	CurrentJobPercent string `json:"currentJobPercent" hp:"current-job-completion"` // 当前任务进度
+	Health            string `json:"health" hp:"health-numeric,health"`
+	HealthReason      string `json:"healthReason" hp:"health-reason"`
+}
+
+type HPPort struct {
+	DurableId       string `json:"durableId" hp:"durable-id"`
+	Controller      string `json:"controller" hp:"controller"`
+	Port            string `json:"port" hp:"port"`
+	PortType        string `json:"portType" hp:"port-type"` // 端口类型(FC, iSCSI, SAS)
+	Media           string `json:"media" hp:"media"`
+	TargetId        string `json:"targetId" hp:"target-id"`
+	Status          string `json:"status" hp:"status-numeric,port-status"`
+	ActualSpeed     string `json:"actualSpeed" hp:"actual-speed"`
+	ConfiguredSpeed string `json:"configuredSpeed" hp:"configured-speed"`
+	Health          string `json:"health" hp:"health-numeric,health"`
+	HealthReason    string `json:"healthReason" hp:"health-reason"`
+}
+
+type HPSensor struct {
+	DurableId    string   `json:"durableId" hp:"durable-id"`
+	EnclosureId  string   `json:"enclosureId" hp:"enclosure-id"`
+	ControllerId string   `json:"controllerId" hp:"controller-id"`
+	SensorName   string   `json:"sensorName" hp:"sensor-name"`
+	SensorType   string   `json:"sensorType" hp:"sensor-type-numeric,sensor-type"` // 类型(Temperature, Current, Voltage, Charge Capacity)
+	Location     string   `json:"location"`                                        // 位置, 如 Enclosure 0 / Controller A
+	Value        string   `json:"value" hp:"value"`                                // 传感器读数(带单位)
+	NumericValue *float64 `json:"numericValue,omitempty"`                          // 数值读数, 非数值读数(如 N/A)时为空
+	Unit         string   `json:"unit"`                                            // 读数单位
+	Status       string   `json:"status" hp:"status-numeric,sensor-status"`
+}
+
+type HPEnclosure struct {
+	DurableId      string `json:"durableId" hp:"durable-id"`
+	EnclosureId    string `json:"enclosureId" hp:"enclosure-id"`
+	Name           string `json:"name" hp:"name"`
+	Wwn            string `json:"wwn" hp:"enclosure-wwn"`
+	Vendor         string `json:"vendor" hp:"vendor"`
+	Model          string `json:"model" hp:"model"`
+	Slots          int64  `json:"slots" hp:"number-of-disks"`
+	EnclosurePower string `json:"enclosurePower" hp:"enclosure-power"` // 功率(W)
+	Status         string `json:"status" hp:"status-numeric,status"`
+	Health         string `json:"health" hp:"health-numeric,health"`
+	HealthReason   string `json:"healthReason" hp:"health-reason"`
+}
+
+type HPController struct {
+	DurableId       string `json:"durableId" hp:"durable-id"`
+	ControllerId    string `json:"controllerId" hp:"controller-id"`
+	SerialNumber    string `json:"serialNumber" hp:"serial-number"`
+	Position        string `json:"position" hp:"position"`
+	IpAddress       string `json:"ipAddress" hp:"ip-address"`
+	ScFirmware      string `json:"scFirmware" hp:"sc-fw"` // 存储控制器固件版本
+	CacheMemorySize string `json:"cacheMemorySize" hp:"cache-memory-size"`
+	Disks           int64  `json:"disks" hp:"disks"`
+	Status          string `json:"status" hp:"status-numeric,controller-status"`
+	Health          string `json:"health" hp:"health-numeric,health"`
+	HealthReason    string `json:"healthReason" hp:"health-reason"`
+}
+
+func (c *HP) GetVolumeInfo() error {
+	c.Log.Debug("[REST]卷信息")
+
+	objects, err := c.ShowObjects("volumes")
+	if err != nil {
+		c.Log.Errorf("[REST]请求卷信息失败, error: %v", err)
+		return err
+	}
+	if err := DecodeHPObjects(objects, "volumes", &c.CrawlerData.Volumes); err != nil {
+		c.Log.Errorf("[REST]解析卷信息失败, error: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (c *HP) GetDiskGroupInfo() error {
+	c.Log.Debug("[REST]磁盘组信息")
+
+	objects, err := c.ShowObjects("disk-groups")
+	if err != nil {
+		c.Log.Errorf("[REST]请求磁盘组信息失败, error: %v", err)
+		return err
+	}
+	if err := DecodeHPObjects(objects, "disk-groups", &c.CrawlerData.DiskGroups); err != nil {
+		c.Log.Errorf("[REST]解析磁盘组信息失败, error: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (c *HP) GetPortInfo() error {
+	c.Log.Debug("[REST]主机端口信息")
+
+	objects, err := c.ShowObjects("ports")
+	if err != nil {
+		c.Log.Errorf("[REST]请求主机端口信息失败, error: %v", err)
+		return err
+	}
+	if err := DecodeHPObjects(objects, "port", &c.CrawlerData.Ports); err != nil {
+		c.Log.Errorf("[REST]解析主机端口信息失败, error: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (c *HP) GetSensorInfo() error {
+	c.Log.Debug("[REST]传感器信息")
+
+	objects, err := c.ShowObjects("sensor-status")
+	if err != nil {
+		c.Log.Errorf("[REST]请求传感器信息失败, error: %v", err)
+		return err
+	}
+	if err := DecodeHPObjects(objects, "sensors", &c.CrawlerData.Sensors); err != nil {
+		c.Log.Errorf("[REST]解析传感器信息失败, error: %v", err)
+		return err
+	}
+	for i := 0; i < len(c.CrawlerData.Sensors); i++ {
+		sensor := c.CrawlerData.Sensors[i]
+		if number, unit, ok := parseHPSensorValue(sensor.Value); ok {
+			sensor.NumericValue = &number
+			sensor.Unit = unit
+		}
+		sensor.Location = "Enclosure " + sensor.EnclosureId
+		if len(sensor.ControllerId) > 0 && sensor.ControllerId != "N/A" {
+			sensor.Location += " / Controller " + sensor.ControllerId
+		}
+	}
+	return nil
+}
+
+// parseHPSensorValue 解析传感器读数, 如 "35 C" -> (35, C), "100%" -> (100, %), 非数值读数(如 N/A)返回false
+func parseHPSensorValue(value string) (float64, string, bool) {
+	value = strings.TrimSpace(value)
+	end := 0
+	for end < len(value) && (value[end] == '-' || value[end] == '.' || (value[end] >= '0' && value[end] <= '9')) {
+		end++
+	}
+	number, err := strconv.ParseFloat(value[:end], 64)
+	if err != nil {
+		return 0, "", false
+	}
+	return number, strings.TrimSpace(value[end:]), true
+}
+
+func (c *HP) GetEnclosureInfo() error {
+	c.Log.Debug("[REST]机柜信息")
+
+	objects, err := c.ShowObjects("enclosures")
+	if err != nil {
+		c.Log.Errorf("[REST]请求机柜信息失败, error: %v", err)
+		return err
+	}
+	if err := DecodeHPObjects(objects, "enclosures", &c.CrawlerData.Enclosures); err != nil {
+		c.Log.Errorf("[REST]解析机柜信息失败, error: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (c *HP) GetControllerInfo() error {
+	c.Log.Debug("[REST]控制器信息")
+
+	objects, err := c.ShowObjects("controllers")
+	if err != nil {
+		c.Log.Errorf("[REST]请求控制器信息失败, error: %v", err)
+		return err
+	}
+	if err := DecodeHPObjects(objects, "controllers", &c.CrawlerData.Controllers); err != nil {
+		c.Log.Errorf("[REST]解析控制器信息失败, error: %v", err)
+		return err
+	}
+	return nil
+}
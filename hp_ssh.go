@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// HPShellPrompt MSA CLI的命令提示符
+const HPShellPrompt = "# "
+
+// HPShell 通过SSH登录MSA CLI执行show命令, 用于禁用了Web接口的设备
+//
+// 登录后设置当前会话的输出格式为XML(api-embed), 子对象嵌套在父对象中, 与REST接口返回的格式相同, 使用同样的方式解析.
+// api格式的子对象与父对象平级, 机柜中的控制器, 电源等会解析为空
+type HPShell struct {
+	Address  string // host:port
+	Username string
+	Password string
+	Timeout  time.Duration // 单个命令的超时时间
+
+	client  *ssh.Client
+	session *ssh.Session
+	stdin   io.WriteCloser
+	output  chan []byte
+}
+
+func NewHPShell(address, username, password string) *HPShell {
+	s := new(HPShell)
+	s.Address = address
+	s.Username = username
+	s.Password = password
+	s.Timeout = 60 * time.Second
+	return s
+}
+
+func (s *HPShell) Connect() error {
+	auth := []ssh.AuthMethod{
+		ssh.Password(s.Password),
+		// 部分固件版本只支持keyboard-interactive方式
+		ssh.KeyboardInteractive(func(user, instruction string, questions []string, echos []bool) ([]string, error) {
+			answers := make([]string, len(questions))
+			for i := 0; i < len(questions); i++ {
+				answers[i] = s.Password
+			}
+			return answers, nil
+		}),
+	}
+	client, err := ssh.Dial("tcp", s.Address, &ssh.ClientConfig{
+		User:            s.Username,
+		Auth:            auth,
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         s.Timeout,
+	})
+	if err != nil {
+		return err
+	}
+	s.client = client
+
+	session, err := client.NewSession()
+	if err != nil {
+		_ = s.Close()
+		return err
+	}
+	s.session = session
+
+	if s.stdin, err = session.StdinPipe(); err != nil {
+		_ = s.Close()
+		return err
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		_ = s.Close()
+		return err
+	}
+	// CLI需要终端才会输出提示符, 使用较宽的终端避免输出被折行
+	if err := session.RequestPty("vt100", 0, 4096, ssh.TerminalModes{ssh.ECHO: 0}); err != nil {
+		_ = s.Close()
+		return err
+	}
+	if err := session.Shell(); err != nil {
+		_ = s.Close()
+		return err
+	}
+
+	s.output = make(chan []byte, 64)
+	go func() {
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := stdout.Read(buf)
+			if n > 0 {
+				data := make([]byte, n)
+				copy(data, buf[:n])
+				s.output <- data
+			}
+			if err != nil {
+				close(s.output)
+				return
+			}
+		}
+	}()
+
+	// 登录信息
+	if _, err := s.readUntilPrompt(); err != nil {
+		_ = s.Close()
+		return err
+	}
+	// 只修改当前会话的参数(temp), 不影响设备上该用户的配置
+	if _, err := s.exec("set cli-parameters api-embed pager off temp"); err != nil {
+		_ = s.Close()
+		return err
+	}
+	return nil
+}
+
+// Run 执行show命令, 返回XML格式的响应
+func (s *HPShell) Run(command string) (string, error) {
+	out, err := s.exec(command)
+	if err != nil {
+		return "", err
+	}
+	start := strings.Index(out, "<RESPONSE")
+	end := strings.LastIndex(out, "</RESPONSE>")
+	if start < 0 || end < start {
+		return "", fmt.Errorf("命令[%s]的输出不是XML格式", command)
+	}
+	data := out[start : end+len("</RESPONSE>")]
+
+	// 判断业务状态码
+	objects, err := ParseHPResponse(data)
+	if err != nil {
+		return "", err
+	}
+	for i := 0; i < len(objects); i++ {
+		if objects[i].BaseType != "status" {
+			continue
+		}
+		if code := objects[i].Property("return-code"); code != "0" {
+			return "", fmt.Errorf("命令[%s]执行失败, 错误码: %s, 错误信息: %s", command, code, objects[i].Property("response"))
+		}
+	}
+	return data, nil
+}
+
+func (s *HPShell) exec(command string) (string, error) {
+	if s.stdin == nil {
+		return "", errors.New("SSH会话未连接")
+	}
+	if _, err := io.WriteString(s.stdin, command+"\n"); err != nil {
+		return "", err
+	}
+	return s.readUntilPrompt()
+}
+
+// readUntilPrompt 读取输出直到出现命令提示符
+//
+// 超时后剩余的输出会被下一个命令读取, 需要关闭会话, 之后的命令返回错误, 下次采集时重新连接
+func (s *HPShell) readUntilPrompt() (string, error) {
+	var buf bytes.Buffer
+	timeout := time.After(s.Timeout)
+	for {
+		select {
+		case data, ok := <-s.output:
+			if !ok {
+				_ = s.Close()
+				return buf.String(), errors.New("SSH会话已关闭")
+			}
+			buf.Write(data)
+			if bytes.HasSuffix(buf.Bytes(), []byte(HPShellPrompt)) {
+				return buf.String(), nil
+			}
+		case <-timeout:
+			_ = s.Close()
+			return buf.String(), errors.New("等待命令输出超时, 关闭SSH会话")
+		}
+	}
+}
+
+func (s *HPShell) Close() error {
+	if s.stdin != nil {
+		_, _ = io.WriteString(s.stdin, "exit\n")
+		s.stdin = nil
+	}
+	if s.session != nil {
+		_ = s.session.Close()
+		s.session = nil
+	}
+	if s.client != nil {
+		err := s.client.Close()
+		s.client = nil
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+)
+
+type HPStatistic struct {
+	Type       string `json:"type"`       // 对象类型(controller, host-port, volume, disk, pool)
+	Name       string `json:"name"`       // 对象名称
+	Controller string `json:"controller"` // 所属控制器
+	SampleTime int64  `json:"sampleTime"` // 采样时间(秒)
+
+	// 累计计数
+	NumberOfReads  int64 `json:"numberOfReads"`
+	NumberOfWrites int64 `json:"numberOfWrites"`
+	DataRead       int64 `json:"dataRead"`    // 累计读取(Byte)
+	DataWritten    int64 `json:"dataWritten"` // 累计写入(Byte)
+
+	// 与上次采集比较计算的速率
+	ReadIops            float64 `json:"readIops"`
+	WriteIops           float64 `json:"writeIops"`
+	ReadBytesPerSecond  float64 `json:"readBytesPerSecond"`
+	WriteBytesPerSecond float64 `json:"writeBytesPerSecond"`
+	RateAvailable       bool    `json:"rateAvailable"` // 首次采集或计数器重置时无法计算速率
+}
+
+type hpStatisticIndex struct {
+	command  string // show命令
+	typ      string
+	basetype string
+	nameKey  string // 对象名称属性
+}
+
+var hpStatisticIndexList = []hpStatisticIndex{
+	{"controller-statistics", "controller", "controller-statistics", "durable-id"},
+	{"host-port-statistics", "host-port", "host-port-statistics", "durable-id"},
+	{"volume-statistics", "volume", "volume-statistics", "volume-name"},
+	{"disk-statistics", "disk", "disk-statistics", "durable-id"},
+	{"pool-statistics", "pool", "pool-statistics", "pool"},
+}
+
+func (c *HP) GetStatisticInfo() error {
+	now := time.Now().Unix()
+	for i := 0; i < len(hpStatisticIndexList); i++ {
+		index := hpStatisticIndexList[i]
+
+		c.Log.Debugf("[REST]性能统计[%s]", index.command)
+		objects, err := c.ShowObjects(index.command)
+		if err != nil {
+			c.Log.Errorf("[REST]请求性能统计[%s]失败, error: %v", index.command, err)
+			return err
+		}
+		statistics, err := parseHPStatistics(index, objects, now)
+		if err != nil {
+			c.Log.Errorf("[REST]解析性能统计[%s]失败, error: %v", index.command, err)
+			return err
+		}
+		c.CrawlerData.Statistics = append(c.CrawlerData.Statistics, statistics...)
+	}
+
+	// 与上次采集的计数比较计算速率
+	previous := make(map[string]*HPStatistic)
+	if data, err := ioutil.ReadFile(c.StatisticFile); err == nil {
+		statistics := make([]*HPStatistic, 0)
+		if err := json.Unmarshal(data, &statistics); err != nil {
+			c.Log.Errorf("解析性能统计文件失败, error: %v", err)
+		}
+		for i := 0; i < len(statistics); i++ {
+			previous[statistics[i].Type+"/"+statistics[i].Name] = statistics[i]
+		}
+	}
+	for i := 0; i < len(c.CrawlerData.Statistics); i++ {
+		cur := c.CrawlerData.Statistics[i]
+		if prev, ok := previous[cur.Type+"/"+cur.Name]; ok {
+			cur.CalcRate(prev)
+		}
+	}
+
+	data, _ := json.Marshal(c.CrawlerData.Statistics)
+	if err := ioutil.WriteFile(c.StatisticFile, data, os.ModePerm); err != nil {
+		c.Log.Errorf("写入性能统计文件失败, error: %v", err)
+		return err
+	}
+	return nil
+}
+
+// hpStatisticCounts 统计对象的累计计数, 存储池统计的计数在resettable-statistics子对象中
+type hpStatisticCounts struct {
+	SampleTime     int64                `hp:"sample-time-numeric"`
+	NumberOfReads  int64                `hp:"number-of-reads"`
+	NumberOfWrites int64                `hp:"number-of-writes"`
+	DataRead       int64                `hp:"data-read-numeric"`
+	DataWritten    int64                `hp:"data-written-numeric"`
+	Resettable     []*hpStatisticCounts `hp:"resettable-statistics"`
+}
+
+func parseHPStatistics(index hpStatisticIndex, objects []*HPObject, now int64) ([]*HPStatistic, error) {
+	objects = filterHPObjects(objects, index.basetype)
+	statistics := make([]*HPStatistic, len(objects))
+	for i := 0; i < len(objects); i++ {
+		counts := new(hpStatisticCounts)
+		if err := DecodeHPObject(objects[i], counts); err != nil {
+			return nil, err
+		}
+		statistics[i] = newHPStatistic(index, objects[i].Property(index.nameKey), counts, now)
+	}
+	return statistics, nil
+}
+
+func newHPStatistic(index hpStatisticIndex, name string, counts *hpStatisticCounts, now int64) *HPStatistic {
+	s := new(HPStatistic)
+	s.Type = index.typ
+	s.Name = name
+	switch s.Type {
+	case "controller":
+		// controller_A
+		s.Controller = strings.TrimPrefix(s.Name, "controller_")
+	case "host-port":
+		// hostport_A1
+		if port := strings.TrimPrefix(s.Name, "hostport_"); len(port) > 0 {
+			s.Controller = port[:1]
+		}
+	}
+
+	s.SampleTime = counts.SampleTime
+	if len(counts.Resettable) > 0 {
+		if s.SampleTime == 0 {
+			s.SampleTime = counts.Resettable[0].SampleTime
+		}
+		counts = counts.Resettable[0]
+	}
+	if s.SampleTime == 0 {
+		s.SampleTime = now
+	}
+	s.NumberOfReads = counts.NumberOfReads
+	s.NumberOfWrites = counts.NumberOfWrites
+	s.DataRead = counts.DataRead
+	s.DataWritten = counts.DataWritten
+	return s
+}
+
+// CalcRate 根据上次采集的累计计数计算速率
+func (s *HPStatistic) CalcRate(prev *HPStatistic) {
+	interval := float64(s.SampleTime - prev.SampleTime)
+	if interval <= 0 {
+		return
+	}
+	if s.NumberOfReads < prev.NumberOfReads || s.NumberOfWrites < prev.NumberOfWrites ||
+		s.DataRead < prev.DataRead || s.DataWritten < prev.DataWritten {
+		// 计数器被重置
+		return
+	}
+	s.ReadIops = float64(s.NumberOfReads-prev.NumberOfReads) / interval
+	s.WriteIops = float64(s.NumberOfWrites-prev.NumberOfWrites) / interval
+	s.ReadBytesPerSecond = float64(s.DataRead-prev.DataRead) / interval
+	s.WriteBytesPerSecond = float64(s.DataWritten-prev.DataWritten) / interval
+	s.RateAvailable = true
+}
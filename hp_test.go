@@ -1,15 +1,24 @@
 package main
 
 import (
+	"bufio"
+	"crypto/ed25519"
 	"crypto/md5"
+	"crypto/rand"
 	_ "embed"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
-	"github.com/beevik/etree"
-	"strconv"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
-	"github.com/robertkrimen/otto"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/ssh"
 )
 
 func TestHP_MD5(t *testing.T) {
@@ -24,167 +33,671 @@ var (
 )
 
 func TestHP_ParseResp(t *testing.T) {
-	// 执行JS函数处理返回值
-	// 格式化成JSON
-	vm := otto.New()
-	_, err := vm.Run(HPScript)
+	// 解析响应数据并格式化成JSON
+	objects, err := ParseHPResponse(TestResp)
 	if err != nil {
-		t.Errorf("解析请求体数据失败[加载脚本], error: %v", err)
-	}
-	if respJsObj, err := vm.Object(TestResp); err != nil {
-		t.Errorf("解析请求体数据失败[响应字符串转JS对象], error: %v", err)
-	} else {
-		value, err := vm.Call("ObjectToJson", nil, respJsObj)
-		if err != nil {
-			t.Errorf("解析请求体数据失败[执行解析方法], error: %v", err)
-		}
-		t.Log(value.String())
+		t.Errorf("解析请求体数据失败, error: %v", err)
+		return
+	}
+	data, err := json.Marshal(objects)
+	if err != nil {
+		t.Errorf("格式化请求体数据失败, error: %v", err)
+	}
+	t.Log(string(data))
+}
+
+func TestHP_DecodeObjects(t *testing.T) {
+	resp := `<RESPONSE VERSION="L100">
+<OBJECT basetype="power-supplies" name="power-supply" oid="1">
+	<PROPERTY name="durable-id">psu_1.0</PROPERTY>
+	<PROPERTY name="dctemp">35 C</PROPERTY>
+	<PROPERTY name="size-numeric">2048</PROPERTY>
+	<OBJECT basetype="fan" name="fan-details" oid="2">
+		<PROPERTY name="durable-id">fan_1.0</PROPERTY>
+		<PROPERTY name="speed">4200</PROPERTY>
+	</OBJECT>
+</OBJECT>
+<OBJECT basetype="status" name="status" oid="3">
+	<PROPERTY name="response-type">Success</PROPERTY>
+</OBJECT>
+</RESPONSE>`
+
+	type fan struct {
+		Id    string `hp:"durable-id"`
+		Speed int64  `hp:"speed"`
+	}
+	type powerSupply struct {
+		Oid   string  `hp:"@oid"`
+		Id    string  `hp:"durable-id"`
+		Temp  float64 `hp:"dctemp"`
+		Bytes int64   `hp:"size-numeric,blocks"`
+		Fans  []*fan  `hp:"fan"`
+	}
+
+	objects, err := ParseHPResponse(resp)
+	if err != nil {
+		t.Errorf("解析请求体数据失败, error: %v", err)
+		return
+	}
+	items := make([]*powerSupply, 0)
+	if err := DecodeHPObjects(objects, "power-supplies", &items); err != nil {
+		t.Errorf("解码对象失败, error: %v", err)
+		return
+	}
+	if len(items) != 1 {
+		t.Errorf("解码对象数量错误, %d", len(items))
+		return
+	}
+	ps := items[0]
+	if ps.Oid != "1" || ps.Id != "psu_1.0" || ps.Temp != 35 || ps.Bytes != 2048*HPBlockSize {
+		t.Errorf("解码属性错误, %+v", ps)
+	}
+	if len(ps.Fans) != 1 || ps.Fans[0].Id != "fan_1.0" || ps.Fans[0].Speed != 4200 {
+		t.Errorf("解码子对象错误, %+v", ps.Fans)
 	}
 }
 
+// newHPTestCrawler 启动模拟MSA接口的服务, responses为show命令对应的对象, 响应中自动添加成功的status对象
+func newHPTestCrawler(t *testing.T, responses map[string]string) *HP {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		objects, ok := responses[strings.TrimPrefix(r.URL.Path, "/v3/api/show/")]
+		code := "0"
+		if !ok {
+			code = "-1"
+		}
+		_, _ = fmt.Fprintf(w, `<RESPONSE VERSION="L100">%s<OBJECT basetype="status" name="status" oid="0">`+
+			`<PROPERTY name="response">Command completed successfully.</PROPERTY>`+
+			`<PROPERTY name="return-code">%s</PROPERTY></OBJECT></RESPONSE>`, objects, code)
+	}))
+	t.Cleanup(server.Close)
+
+	c := new(HP)
+	c.Log = zap.NewNop().Sugar()
+	c.Host = server.URL
+	c.AuthFile = t.TempDir() + "/hp.cookie"
+	c.StatisticFile = t.TempDir() + "/hp_statistics.json"
+	c.CrawlerData = new(HPCrawlerData)
+	return c
+}
+
 func TestHP_GetComponentState(t *testing.T) {
-	doc := etree.NewDocument()
-	if err := doc.ReadFromString(TestResp); err != nil {
-		t.Errorf("[REST]解析组件状态响应数据失败, error: %v", err)
+	c := newHPTestCrawler(t, map[string]string{
+		"enclosures": `<OBJECT basetype="enclosures" name="enclosure" oid="1">
+	<PROPERTY name="durable-id">enclosure_0</PROPERTY>
+	<OBJECT basetype="controllers" name="controller" oid="2">
+		<PROPERTY name="controller-id">A</PROPERTY>
+		<PROPERTY name="health-numeric">0</PROPERTY>
+		<OBJECT basetype="network-parameters" name="ip" oid="3">
+			<PROPERTY name="durable-id">mgmtport_a</PROPERTY>
+			<PROPERTY name="health-numeric">0</PROPERTY>
+		</OBJECT>
+		<OBJECT basetype="port" name="ports" oid="4">
+			<PROPERTY name="durable-id">hostport_A1</PROPERTY>
+			<PROPERTY name="health-numeric">1</PROPERTY>
+		</OBJECT>
+		<OBJECT basetype="expander-ports" name="expander-port" oid="5">
+			<PROPERTY name="durable-id">drawer_0_egress_a</PROPERTY>
+			<PROPERTY name="health-numeric">4</PROPERTY>
+		</OBJECT>
+		<OBJECT basetype="compact-flash" name="compact-flash" oid="6">
+			<PROPERTY name="durable-id">flash_a</PROPERTY>
+			<PROPERTY name="health-numeric">0</PROPERTY>
+		</OBJECT>
+	</OBJECT>
+	<OBJECT basetype="power-supplies" name="power-supplies" oid="7">
+		<PROPERTY name="durable-id">psu_0.0</PROPERTY>
+		<PROPERTY name="health-numeric">2</PROPERTY>
+		<PROPERTY name="status">错误</PROPERTY>
+		<PROPERTY name="status-numeric">2</PROPERTY>
+		<PROPERTY name="dc12v">1220</PROPERTY>
+		<PROPERTY name="dc5v">512</PROPERTY>
+		<PROPERTY name="dc33v">335</PROPERTY>
+		<PROPERTY name="dc12i">1037</PROPERTY>
+		<PROPERTY name="dc5i">650</PROPERTY>
+		<PROPERTY name="dctemp">35 C</PROPERTY>
+		<OBJECT basetype="fan" name="fan-details" oid="8">
+			<PROPERTY name="durable-id">fan_0.0</PROPERTY>
+			<PROPERTY name="health-numeric">0</PROPERTY>
+			<PROPERTY name="status-numeric">2</PROPERTY>
+			<PROPERTY name="speed">4200</PROPERTY>
+		</OBJECT>
+	</OBJECT>
+</OBJECT>`,
+	})
+	if err := c.GetComponentState(); err != nil {
+		t.Errorf("[REST]请求组件状态失败, error: %v", err)
 		return
 	}
 
-	controllerElems := doc.FindElements("/RESPONSE/OBJECT/OBJECT[@basetype='controllers']")
-	for i := 0; i < len(controllerElems); i++ {
-		id := controllerElems[i].FindElement("./PROPERTY[@name='controller-id']").Text()
-		health := controllerElems[i].FindElement("./PROPERTY[@name='health']").Text()
+	h := c.CrawlerData
+	if len(h.ControllerStates) != 1 || h.ControllerStates[0].Id != "A" || h.ControllerStates[0].Health != "OK" {
+		t.Errorf("控制器状态错误, %+v", h.ControllerStates)
+	}
+	if len(h.NetworkStates) != 1 || len(h.PortStates) != 1 || len(h.ExpanderPortStates) != 1 || len(h.CompactFlashStates) != 1 {
+		t.Errorf("控制器子组件数量错误, %+v", h)
+		return
+	}
+	if h.PortStates[0].Id != "hostport_A1" || h.PortStates[0].Health != "Degraded" || h.ExpanderPortStates[0].Health != "N/A" {
+		t.Errorf("端口状态错误, %+v, %+v", h.PortStates[0], h.ExpanderPortStates[0])
+	}
+	if len(h.PowerSuppliesStates) != 1 || h.PowerSuppliesStates[0].Health != "Fault" || h.PowerSuppliesStates[0].Status != "Error" {
+		t.Errorf("电源状态错误, %+v", h.PowerSuppliesStates)
+		return
+	}
+	if ps := h.PowerSuppliesStates[0]; ps.Voltage12 != 12.2 || ps.Voltage5 != 5.12 || ps.Voltage33 != 3.35 ||
+		ps.Current12 != 10.37 || ps.Current5 != 6.5 || ps.Temperature != 35 {
+		t.Errorf("电源读数换算错误, %+v", ps)
+	}
+	if len(h.FanStates) != 1 || h.FanStates[0].Id != "fan_0.0" || h.FanStates[0].Speed != 4200 || h.FanStates[0].Status != "Off" {
+		t.Errorf("风扇状态错误, %+v", h.FanStates)
+	}
+}
 
-		t.Log("控制器状态", id, health)
+func TestHP_GetDiskInfo(t *testing.T) {
+	c := newHPTestCrawler(t, map[string]string{
+		"disks": `<OBJECT basetype="drives" name="drive" oid="1">
+	<PROPERTY name="durable-id">disk_01.01</PROPERTY>
+	<PROPERTY name="usage-numeric">9</PROPERTY>
+	<PROPERTY name="size-numeric">1172123568</PROPERTY>
+	<PROPERTY name="health-numeric">0</PROPERTY>
+</OBJECT>
+<OBJECT basetype="drives" name="drive" oid="2">
+	<PROPERTY name="durable-id">disk_01.02</PROPERTY>
+	<PROPERTY name="usage-numeric">2</PROPERTY>
+	<PROPERTY name="size-numeric">1172123568</PROPERTY>
+	<PROPERTY name="health-numeric">0</PROPERTY>
+</OBJECT>`,
+	})
+	if err := c.GetDiskInfo(); err != nil {
+		t.Errorf("[REST]请求磁盘信息失败, error: %v", err)
+		return
+	}
 
-		childElems := []string{
-			"./OBJECT[@basetype='network-parameters']", // 网络端口状态
-			"./OBJECT[@basetype='port']",               // 主机端口状态
-			"./OBJECT[@basetype='expander-ports']",     // 扩展端口状态
-			"./OBJECT[@basetype='compact-flash']",      // CompactFlash状态
-		}
-		for j := 0; j < len(childElems); j++ {
-			stateElems := controllerElems[i].FindElements(childElems[j])
-			for k := 0; k < len(stateElems); k++ {
-				id := stateElems[k].FindElement("./PROPERTY[@name='durable-id']").Text()
-				health := stateElems[k].FindElement("./PROPERTY[@name='health']").Text()
-
-				switch j {
-				case 0:
-					t.Log("网络端口状态", id, health)
-				case 1:
-					t.Log("主机端口状态", id, health)
-				case 2:
-					t.Log("扩展端口状态", id, health)
-				case 3:
-					t.Log("CompactFlash状态", id, health)
-				}
-			}
-		}
+	h := c.CrawlerData
+	size := int64(1172123568 * HPBlockSize)
+	if len(h.DiskInfo) != 2 || h.DiskInfo[0].Id != "disk_01.01" || h.DiskInfo[0].Health != "OK" {
+		t.Errorf("磁盘信息错误, %+v", h.DiskInfo)
+	}
+	if h.SizeTotal != 2*size || h.SizeSpares != size || h.SizeVirtual != size {
+		t.Errorf("磁盘容量统计错误, %d, %d, %d", h.SizeTotal, h.SizeSpares, h.SizeVirtual)
 	}
+}
 
-	powerSuppliesElems := doc.FindElements("/RESPONSE/OBJECT/OBJECT[@basetype='power-supplies']")
-	for i := 0; i < len(powerSuppliesElems); i++ {
-		id := powerSuppliesElems[i].FindElement("./PROPERTY[@name='durable-id']").Text()
-		health := powerSuppliesElems[i].FindElement("./PROPERTY[@name='health']").Text()
+func TestHP_GetPoolInfo(t *testing.T) {
+	c := newHPTestCrawler(t, map[string]string{
+		"pools": `<OBJECT basetype="pools" name="pools" oid="1">
+	<PROPERTY name="name">A</PROPERTY>
+	<PROPERTY name="total-size-numeric">11720982528</PROPERTY>
+	<PROPERTY name="page-size-numeric">8192</PROPERTY>
+	<PROPERTY name="allocated-pages">1000</PROPERTY>
+	<PROPERTY name="health-numeric">0</PROPERTY>
+</OBJECT>`,
+	})
+	if err := c.GetPoolInfo(); err != nil {
+		t.Errorf("[REST]请求存储池信息失败, error: %v", err)
+		return
+	}
 
-		t.Log("电源状态", id, health)
+	h := c.CrawlerData
+	if len(h.Pools) != 1 || h.Pools[0].Name != "A" || h.Pools[0].TotalSizeBytes != 11720982528*HPBlockSize {
+		t.Errorf("存储池信息错误, %+v", h.Pools)
+		return
+	}
+	if h.Pools[0].AllocatedBytes != 8192*1000*HPBlockSize || h.VirtPoolAllocSizeTotal != h.Pools[0].AllocatedBytes {
+		t.Errorf("已分配容量错误, %d, %d", h.Pools[0].AllocatedBytes, h.VirtPoolAllocSizeTotal)
+	}
+}
 
-		fElems := powerSuppliesElems[i].FindElements("./OBJECT[@basetype='fan']")
-		for i := 0; i < len(fElems); i++ {
-			id := fElems[i].FindElement("./PROPERTY[@name='durable-id']").Text()
-			health := fElems[i].FindElement("./PROPERTY[@name='health']").Text()
+func TestHP_GetVolumeGroupInfo(t *testing.T) {
+	c := newHPTestCrawler(t, map[string]string{
+		"volume-groups": `<OBJECT basetype="volume-groups" name="volume-groups" oid="1">
+	<PROPERTY name="group-name">UNGROUPEDVOLUMES</PROPERTY>
+	<OBJECT basetype="volumes" name="volume" oid="2">
+		<PROPERTY name="volume-type-numeric">15</PROPERTY>
+		<PROPERTY name="size-numeric">2000</PROPERTY>
+	</OBJECT>
+	<OBJECT basetype="volumes" name="volume" oid="3">
+		<PROPERTY name="volume-type-numeric">3</PROPERTY>
+		<PROPERTY name="size-numeric">1000</PROPERTY>
+	</OBJECT>
+</OBJECT>`,
+	})
+	c.CrawlerData.VirtPoolAllocSizeTotal = 500 * HPBlockSize
+	if err := c.GetVolumeGroupInfo(); err != nil {
+		t.Errorf("[REST]请求卷组信息失败, error: %v", err)
+		return
+	}
+	if size := c.CrawlerData.VirtUnallocSizeTotal; size != 1500*HPBlockSize {
+		t.Errorf("未分配容量错误, %d", size)
+	}
+}
 
-			t.Log("风扇状态", id, health)
-		}
+func TestHP_GetStatisticInfo(t *testing.T) {
+	c := newHPTestCrawler(t, map[string]string{
+		"controller-statistics": `<OBJECT basetype="controller-statistics" name="controller-statistics" oid="1">
+	<PROPERTY name="durable-id">controller_A</PROPERTY>
+	<PROPERTY name="number-of-reads">1500</PROPERTY>
+	<PROPERTY name="data-read-numeric">5120000</PROPERTY>
+	<PROPERTY name="sample-time-numeric">1634567890</PROPERTY>
+</OBJECT>`,
+		"host-port-statistics": ``,
+		"volume-statistics":    ``,
+		"disk-statistics":      ``,
+		"pool-statistics": `<OBJECT basetype="pool-statistics" name="pool-statistics" oid="2">
+	<PROPERTY name="pool">A</PROPERTY>
+	<PROPERTY name="sample-time-numeric">1634567890</PROPERTY>
+	<OBJECT basetype="resettable-statistics" name="resettable-statistics" oid="3">
+		<PROPERTY name="number-of-reads">300</PROPERTY>
+		<PROPERTY name="number-of-writes">200</PROPERTY>
+		<PROPERTY name="data-written-numeric">409600</PROPERTY>
+	</OBJECT>
+</OBJECT>`,
+	})
+	if err := c.GetStatisticInfo(); err != nil {
+		t.Errorf("[REST]请求性能统计失败, error: %v", err)
+		return
+	}
+
+	statistics := c.CrawlerData.Statistics
+	if len(statistics) != 2 {
+		t.Errorf("性能统计数量错误, %+v", statistics)
+		return
+	}
+	if s := statistics[0]; s.Name != "controller_A" || s.Controller != "A" || s.NumberOfReads != 1500 || s.DataRead != 5120000 {
+		t.Errorf("控制器统计错误, %+v", s)
+	}
+	if s := statistics[1]; s.Name != "A" || s.SampleTime != 1634567890 || s.NumberOfReads != 300 || s.NumberOfWrites != 200 || s.DataWritten != 409600 {
+		t.Errorf("存储池统计错误, %+v", s)
 	}
 }
 
-func TestHP_GetDiskInfo(t *testing.T) {
-	doc := etree.NewDocument()
-	if err := doc.ReadFromString(TestResp); err != nil {
-		t.Errorf("[REST]解析磁盘状态响应数据失败, error: %v", err)
-		return
-	}
-
-	var (
-		sizeTotal   int
-		sizeSpares  int
-		sizeVirtual int
-	)
-
-	elems := doc.FindElements("/RESPONSE/OBJECT[@basetype='drives']")
-	for i := 0; i < len(elems); i++ {
-		// 使用情况
-		usageNumeric := elems[i].FindElement("./PROPERTY[@name='usage-numeric']").Text()
-		// 大小
-		sizeNumeric := elems[i].FindElement("./PROPERTY[@name='size-numeric']").Text()
-		sizeNumericInt, _ := strconv.Atoi(sizeNumeric)
-
-		sizeTotal += sizeNumericInt * 512
-		switch usageNumeric {
-		case "2", "3":
-			sizeSpares += sizeNumericInt * 512
-		case "9":
-			sizeVirtual += sizeNumericInt * 512
+func TestHP_DecodeInt(t *testing.T) {
+	type volume struct {
+		Name      string `hp:"volume-name"`
+		SizeBytes int64  `hp:"size-numeric,blocks"`
+		Size      int64  `hp:"size"`
+	}
+
+	objects, err := ParseHPResponse(`<RESPONSE><OBJECT basetype="volumes" name="volume" oid="1">
+<PROPERTY name="volume-name" type="string">vd01_v0001</PROPERTY>
+<PROPERTY name="size-numeric" type="uint64">1953120256</PROPERTY>
+<PROPERTY name="size" type="string">N/A</PROPERTY>
+</OBJECT></RESPONSE>`)
+	if err != nil {
+		t.Fatalf("解析响应数据失败, error: %v", err)
+	}
+	v := new(volume)
+	if err := DecodeHPObject(objects[0], v); err != nil {
+		t.Fatalf("解码对象失败, error: %v", err)
+	}
+	if v.Name != "vd01_v0001" || v.SizeBytes != 999997571072 || v.Size != 0 {
+		t.Errorf("解码属性错误, %+v", v)
+	}
+
+	// 带单位的容量或小数不能解码为整数, 字段为0, 其他字段正常解码
+	objects[0].Properties["size"] = "1.2TB"
+	v.Size = 1
+	if err := DecodeHPObject(objects[0], v); err != nil || v.Size != 0 || v.SizeBytes != 999997571072 {
+		t.Errorf("非整数的值应解码为0, %+v, %v", v, err)
+	}
+}
+
+func TestHP_CookieUser(t *testing.T) {
+	cases := map[string]string{
+		"wbisessionkey=0ab1c2;wbiusername=monitor":  "monitor",
+		"wbisessionkey=0ab1c2; wbiusername=manage":  "manage",
+		"wbisessionkey=0ab1c2;wbiusername=monitor2": "monitor2",
+		"wbisessionkey=0ab1c2":                      "",
+	}
+	for cookie, user := range cases {
+		if u := hpCookieUser(cookie); u != user {
+			t.Errorf("授权信息中的用户解析错误, %s -> %s", cookie, u)
 		}
 	}
+}
+
+func TestHP_StatisticRate(t *testing.T) {
+	prev := &HPStatistic{SampleTime: 100, NumberOfReads: 1000, NumberOfWrites: 500, DataRead: 4096000, DataWritten: 2048000}
+	cur := &HPStatistic{SampleTime: 110, NumberOfReads: 1500, NumberOfWrites: 600, DataRead: 5120000, DataWritten: 2048000}
+	cur.CalcRate(prev)
+	if !cur.RateAvailable || cur.ReadIops != 50 || cur.WriteIops != 10 || cur.ReadBytesPerSecond != 102400 || cur.WriteBytesPerSecond != 0 {
+		t.Errorf("速率计算错误, %+v", cur)
+	}
 
-	t.Log(sizeTotal, sizeSpares, sizeVirtual)
+	// 计数器重置
+	reset := &HPStatistic{SampleTime: 120, NumberOfReads: 10}
+	reset.CalcRate(cur)
+	if reset.RateAvailable {
+		t.Errorf("计数器重置时不应计算速率, %+v", reset)
+	}
 }
 
-func TestHP_GetPoolInfo(t *testing.T) {
-	doc := etree.NewDocument()
-	if err := doc.ReadFromString(TestResp); err != nil {
-		t.Errorf("[REST]请求存储池信息失败, error: %v", err)
-		return
+func hpEventObjects(ids ...string) string {
+	objects := ""
+	for i := 0; i < len(ids); i++ {
+		objects += fmt.Sprintf(`<OBJECT basetype="events" name="event" oid="%d">
+	<PROPERTY name="event-id">%s</PROPERTY>
+	<PROPERTY name="event-code">%d</PROPERTY>
+	<PROPERTY name="severity">ERROR</PROPERTY>
+	<PROPERTY name="severity-numeric">1</PROPERTY>
+	<PROPERTY name="time-stamp-numeric">1700000000</PROPERTY>
+	<PROPERTY name="message">event %s</PROPERTY>
+</OBJECT>`, i+1, ids[i], 300+i, ids[i])
 	}
+	return objects
+}
 
-	var virtPoolAllocSizeTotal int64
+func TestHP_GetEventInfo(t *testing.T) {
+	responses := map[string]string{"events/last/1000": hpEventObjects("A10", "A11", "B5")}
+	c := newHPTestCrawler(t, responses)
+	dir := t.TempDir()
+	c.EventFile = dir + "/event.json"
+	c.EventCursorFile = dir + "/event_cursor.json"
 
-	elems := doc.FindElements("/RESPONSE/OBJECT[@basetype='pools']")
-	for i := 0; i < len(elems); i++ {
-		// 页面大小（块）
-		pageSize := elems[i].FindElement("./PROPERTY[@name='page-size-numeric']").Text()
-		pageSizeInt, _ := strconv.Atoi(pageSize)
-		// 分配的页数
-		allocatedPages := elems[i].FindElement("./PROPERTY[@name='allocated-pages']").Text()
-		allocatedPagesInt, _ := strconv.ParseInt(allocatedPages, 10, 64)
+	if err := c.GetEventInfo(); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.CrawlerData.Events) != 3 || c.CrawlerData.ErrorEventCount != 3 {
+		t.Errorf("事件数量错误, %d, %d", len(c.CrawlerData.Events), c.CrawlerData.ErrorEventCount)
+	}
+	data, _ := ioutil.ReadFile(c.EventCursorFile)
+	if string(data) != `{"A":11,"B":5}` {
+		t.Errorf("事件游标错误, %s", data)
+	}
 
-		virtPoolAllocSizeTotal += int64(pageSizeInt) * allocatedPagesInt * 512
+	// 已采集的事件不再写入, B控制器更换后序号重新开始
+	responses["events/last/1000"] = hpEventObjects("A11", "A12", "B1")
+	c.CrawlerData = new(HPCrawlerData)
+	if err := c.GetEventInfo(); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.CrawlerData.Events) != 2 {
+		t.Errorf("事件数量错误, %d", len(c.CrawlerData.Events))
+	}
+	data, _ = ioutil.ReadFile(c.EventCursorFile)
+	if string(data) != `{"A":12,"B":1}` {
+		t.Errorf("事件游标错误, %s", data)
 	}
 
-	t.Log(virtPoolAllocSizeTotal)
+	data, _ = ioutil.ReadFile(c.EventFile)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("事件文件行数错误, %d", len(lines))
+	}
+	event := new(Event)
+	if err := json.Unmarshal([]byte(lines[4]), event); err != nil {
+		t.Fatal(err)
+	}
+	if event.Source != "hp" || event.Type != EventLog || event.Location != "B" || event.Level != "ERROR" || event.Message != "event B1" {
+		t.Errorf("事件内容错误, %+v", event)
+	}
 }
 
-func TestHP_GetVolumeGroupInfo(t *testing.T) {
-	doc := etree.NewDocument()
-	if err := doc.ReadFromString(TestResp); err != nil {
-		t.Errorf("[REST]请求卷组信息失败, error: %v", err)
-		return
+func TestHP_ParseEventId(t *testing.T) {
+	if controller, serial := parseHPEventId("B10345"); controller != "B" || serial != 10345 {
+		t.Errorf("事件ID解析错误, %s, %d", controller, serial)
 	}
+	if _, serial := parseHPEventId(""); serial != 0 {
+		t.Errorf("空事件ID解析错误, %d", serial)
+	}
+}
 
-	var (
-		totalSize            int64
-		virtUnallocSizeTotal int64
-	)
+func TestHP_GetSensorInfo(t *testing.T) {
+	c := newHPTestCrawler(t, map[string]string{
+		"sensor-status": `<OBJECT basetype="sensors" name="sensor" oid="1">
+	<PROPERTY name="enclosure-id">0</PROPERTY>
+	<PROPERTY name="controller-id">A</PROPERTY>
+	<PROPERTY name="sensor-name">CPU Temperature-Ctlr A</PROPERTY>
+	<PROPERTY name="value">45 C</PROPERTY>
+</OBJECT>
+<OBJECT basetype="sensors" name="sensor" oid="2">
+	<PROPERTY name="enclosure-id">0</PROPERTY>
+	<PROPERTY name="controller-id">B</PROPERTY>
+	<PROPERTY name="sensor-name">CPU Temperature-Ctlr B</PROPERTY>
+	<PROPERTY name="value">N/A</PROPERTY>
+</OBJECT>`,
+	})
+	if err := c.GetSensorInfo(); err != nil {
+		t.Fatal(err)
+	}
+	sensors := c.CrawlerData.Sensors
+	if len(sensors) != 2 || sensors[0].NumericValue == nil || *sensors[0].NumericValue != 45 || sensors[0].Unit != "C" {
+		t.Fatalf("传感器读数错误, %+v", sensors)
+	}
+	// 没有读数时不输出数值
+	if sensors[1].NumericValue != nil || sensors[1].Location != "Enclosure 0 / Controller B" {
+		t.Errorf("N/A读数应为空, %+v", sensors[1])
+	}
+}
+
+func TestHP_ParseSensorValue(t *testing.T) {
+	cases := map[string]struct {
+		value float64
+		unit  string
+		ok    bool
+	}{
+		"35 C":  {35, "C", true},
+		"12.05": {12.05, "", true},
+		"100%":  {100, "%", true},
+		"OK":    {0, "", false},
+		"N/A":   {0, "", false},
+	}
+	for input, expect := range cases {
+		if value, unit, ok := parseHPSensorValue(input); value != expect.value || unit != expect.unit || ok != expect.ok {
+			t.Errorf("传感器读数解析错误, %s -> %v, %s, %v", input, value, unit, ok)
+		}
+	}
+}
 
-	elems := doc.FindElements("/RESPONSE/OBJECT/OBJECT[@basetype='volumes']")
-	for i := 0; i < len(elems); i++ {
-		volumeTypeNumeric := elems[i].FindElement("./PROPERTY[@name='volume-type-numeric']").Text()
+func TestHP_DecodeEnum(t *testing.T) {
+	resp := `<RESPONSE VERSION="L100">
+<OBJECT basetype="events" name="event" oid="1">
+	<PROPERTY name="severity">严重</PROPERTY>
+	<PROPERTY name="severity-numeric">3</PROPERTY>
+</OBJECT>
+<OBJECT basetype="events" name="event" oid="2">
+	<PROPERTY name="severity">WARNING</PROPERTY>
+</OBJECT>
+</RESPONSE>`
 
-		sizeNumeric := elems[i].FindElement("./PROPERTY[@name='size-numeric']").Text()
-		sizeNumericInt, _ := strconv.ParseInt(sizeNumeric, 10, 64)
+	objects, err := ParseHPResponse(resp)
+	if err != nil {
+		t.Errorf("解析请求体数据失败, error: %v", err)
+		return
+	}
+	events := make([]*HPEvent, 0)
+	if err := DecodeHPObjects(objects, "events", &events); err != nil {
+		t.Errorf("解码对象失败, error: %v", err)
+		return
+	}
+	if len(events) != 2 || events[0].Severity != "CRITICAL" || events[1].Severity != "WARNING" {
+		t.Errorf("解码级别错误, %+v", events)
+	}
+}
 
-		if volumeTypeNumeric == "0" ||
-			volumeTypeNumeric == "2" ||
-			volumeTypeNumeric == "4" ||
-			volumeTypeNumeric == "8" ||
-			volumeTypeNumeric == "13" ||
-			volumeTypeNumeric == "15" {
-			totalSize += sizeNumericInt
+// startHPShellServer 启动模拟MSA CLI的SSH服务, 返回监听地址
+func startHPShellServer(t *testing.T, responses map[string]string) string {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if conn.User() == "monitor" && string(password) == "!monitor" {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("密码错误")
+		},
+	}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, channels, requests, err := ssh.NewServerConn(conn, config)
+				if err != nil {
+					return
+				}
+				go ssh.DiscardRequests(requests)
+				for newChannel := range channels {
+					channel, requests, err := newChannel.Accept()
+					if err != nil {
+						return
+					}
+					go func() {
+						for req := range requests {
+							_ = req.Reply(req.Type == "pty-req" || req.Type == "shell", nil)
+						}
+					}()
+					go func() {
+						defer func() {
+							_ = channel.Close()
+						}()
+						_, _ = channel.Write([]byte("HPE MSA Storage MSA 2050 SAN\r\nSystem Version: VL270P005\r\n# "))
+						scanner := bufio.NewScanner(channel)
+						for scanner.Scan() {
+							command := strings.TrimSpace(scanner.Text())
+							if command == "exit" {
+								return
+							}
+							resp, ok := responses[command]
+							if command == "show hang" {
+								// 输出不完整, 没有提示符
+								_, _ = channel.Write([]byte(resp))
+								continue
+							}
+							if !ok {
+								resp = `<RESPONSE><OBJECT basetype="status" name="status" oid="1">` +
+									`<PROPERTY name="response">Error: The command is not recognized.</PROPERTY>` +
+									`<PROPERTY name="return-code">-1</PROPERTY></OBJECT></RESPONSE>`
+							}
+							_, _ = channel.Write([]byte(resp + "\r\n# "))
+						}
+					}()
+				}
+			}()
 		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestHP_Shell(t *testing.T) {
+	address := startHPShellServer(t, map[string]string{
+		"set cli-parameters api-embed pager off temp": "Success: Command completed successfully.",
+		// api-embed格式, 子对象嵌套在父对象中
+		"show enclosures": `<RESPONSE VERSION="L100">
+<OBJECT basetype="enclosures" name="enclosure" oid="1">
+	<PROPERTY name="durable-id">enclosure_0</PROPERTY>
+	<OBJECT basetype="controllers" name="controller" oid="2">
+		<PROPERTY name="controller-id">A</PROPERTY>
+		<PROPERTY name="health-numeric">0</PROPERTY>
+		<OBJECT basetype="port" name="ports" oid="3">
+			<PROPERTY name="durable-id">hostport_A1</PROPERTY>
+			<PROPERTY name="health-numeric">1</PROPERTY>
+		</OBJECT>
+	</OBJECT>
+	<OBJECT basetype="power-supplies" name="power-supplies" oid="4">
+		<PROPERTY name="durable-id">psu_0.0</PROPERTY>
+		<PROPERTY name="health-numeric">0</PROPERTY>
+		<OBJECT basetype="fan" name="fan-details" oid="5">
+			<PROPERTY name="durable-id">fan_0.0</PROPERTY>
+			<PROPERTY name="health-numeric">0</PROPERTY>
+		</OBJECT>
+	</OBJECT>
+</OBJECT>
+<OBJECT basetype="status" name="status" oid="6">
+	<PROPERTY name="response">Command completed successfully.</PROPERTY>
+	<PROPERTY name="return-code">0</PROPERTY>
+</OBJECT>
+</RESPONSE>`,
+		"show hang": `<RESPONSE VERSION="L100">`,
+		"show disks": `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<RESPONSE VERSION="L100">
+<OBJECT basetype="drives" name="drive" oid="1">
+	<PROPERTY name="durable-id">disk_01.01</PROPERTY>
+	<PROPERTY name="size-numeric">1172123568</PROPERTY>
+	<PROPERTY name="health-numeric">0</PROPERTY>
+</OBJECT>
+<OBJECT basetype="status" name="status" oid="2">
+	<PROPERTY name="response">Command completed successfully.</PROPERTY>
+	<PROPERTY name="return-code">0</PROPERTY>
+</OBJECT>
+</RESPONSE>`,
+	})
+
+	shell := NewHPShell(address, "monitor", "!monitor")
+	if err := shell.Connect(); err != nil {
+		t.Errorf("SSH登录失败, error: %v", err)
+		return
+	}
+	defer func() {
+		_ = shell.Close()
+	}()
+
+	data, err := shell.Run("show disks")
+	if err != nil {
+		t.Errorf("执行命令失败, error: %v", err)
+		return
+	}
+	objects, err := ParseHPResponse(data)
+	if err != nil {
+		t.Errorf("解析命令输出失败, error: %v", err)
+		return
+	}
+	type disk struct {
+		Id        string `hp:"durable-id"`
+		SizeBytes int64  `hp:"size-numeric,blocks"`
+		Health    string `hp:"health-numeric,health"`
+	}
+	disks := make([]*disk, 0)
+	if err := DecodeHPObjects(objects, "drives", &disks); err != nil {
+		t.Errorf("解码磁盘信息失败, error: %v", err)
+		return
+	}
+	if len(disks) != 1 || disks[0].Id != "disk_01.01" || disks[0].SizeBytes != 1172123568*HPBlockSize || disks[0].Health != "OK" {
+		t.Errorf("解析磁盘信息错误, %+v", disks)
+	}
+
+	// 设备返回错误码
+	if _, err := shell.Run("show unknown"); err == nil {
+		t.Errorf("未识别的命令应返回错误")
 	}
 
-	// 11832412602368
-	t.Log(totalSize, virtUnallocSizeTotal)
+	// 嵌套的组件与Web接口使用相同的解析
+	c := new(HP)
+	c.Log = zap.NewNop().Sugar()
+	c.Shell = shell
+	c.CrawlerData = new(HPCrawlerData)
+	if err := c.GetComponentState(); err != nil {
+		t.Errorf("[SSH]请求组件状态失败, error: %v", err)
+		return
+	}
+	if h := c.CrawlerData; len(h.ControllerStates) != 1 || len(h.PortStates) != 1 || len(h.PowerSuppliesStates) != 1 || len(h.FanStates) != 1 {
+		t.Errorf("[SSH]嵌套的组件解析错误, %+v", h)
+	}
+
+	// 超时后关闭会话, 剩余的输出不能作为下一个命令的输出
+	shell.Timeout = 200 * time.Millisecond
+	if _, err := shell.Run("show hang"); err == nil {
+		t.Errorf("命令输出超时应返回错误")
+	}
+	if _, err := shell.Run("show disks"); err == nil {
+		t.Errorf("超时后会话已关闭, 命令应返回错误")
+	}
 }
@@ -12,7 +12,9 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/buger/jsonparser"
@@ -32,9 +34,14 @@ var (
 	HuaweiPassword string
 )
 
+// HuaweiDefaultSectorSize 系统信息中未返回扇区大小时使用
+const HuaweiDefaultSectorSize = 512
+
 type HuaweiCrawlerData struct {
 	SectorSize int64 `json:"sectorSize"`
 
+	MemberDiskCapacity         int64 `json:"memberDiskCapacity"`
+	FreeDiskCapacity           int64 `json:"freeDiskCapacity"`
 	UsableDiskpoolCapacityData int64 `json:"usableDiskpoolCapacityData"`
 
 	ServerStatus            string `json:"serverStatus"`
@@ -49,10 +56,40 @@ type HuaweiCrawlerData struct {
 	UsableCapacity          int64  `json:"usableCapacity"`
 	TotalCapacity           int64  `json:"totalCapacity"`
 
-	StoragePoolInfo []interface{} `json:"storagePoolInfo"`
-	FanInfo         []interface{} `json:"fanInfo"`
-	PowerInfo       []interface{} `json:"powerInfo"`
-	FcPortInfo      []interface{} `json:"fcPortInfo"`
+	DiskPoolInfo    []*HuaweiDiskPool    `json:"diskPoolInfo"`
+	StoragePoolInfo []*HuaweiStoragePool `json:"storagePoolInfo"`
+	FanInfo         []interface{}        `json:"fanInfo"`
+	PowerInfo       []interface{}        `json:"powerInfo"`
+	FcPortInfo      []interface{}        `json:"fcPortInfo"`
+
+	LunInfo         []*HuaweiLun         `json:"lunInfo"`
+	HostInfo        []*HuaweiHost        `json:"hostInfo"`
+	HostGroupInfo   []*HuaweiHostGroup   `json:"hostGroupInfo"`
+	LunGroupInfo    []*HuaweiLunGroup    `json:"lunGroupInfo"`
+	MappingViewInfo []*HuaweiMappingView `json:"mappingViewInfo"`
+
+	VStoreInfo []*HuaweiVStore `json:"vstoreInfo"`
+
+	FilesystemInfo []*HuaweiFilesystem `json:"filesystemInfo"`
+	ShareInfo      []*HuaweiShare      `json:"shareInfo"`
+	QuotaTreeInfo  []*HuaweiQuotaTree  `json:"quotaTreeInfo"`
+
+	ReplicationInfo []*HuaweiReplication `json:"replicationInfo"`
+
+	HardwareInfo []*HuaweiComponent `json:"hardwareInfo"`
+	DiskInfo     []*HuaweiDisk      `json:"diskInfo"`
+
+	AlarmInfo  []*HuaweiAlarm   `json:"alarmInfo"`
+	AlarmCount map[string]int64 `json:"alarmCount"` // 告警级别 -> 数量
+
+	StatisticInfo []*HuaweiStatistic `json:"statisticInfo"`
+}
+
+type HuaweiStatistic struct {
+	Object string           `json:"object"` // 对象类型(fc_port, disk, diskpool, lun, filesystem, vstore)
+	Uuid   string           `json:"uuid"`   // 对象类型ID:对象ID
+	VStore string           `json:"vstore"` // 所属租户
+	Data   map[string]int64 `json:"data"`   // 指标ID -> 指标值
 }
 
 func (h *HuaweiCrawlerData) PrintFile(path string) {
@@ -69,6 +106,10 @@ type Huawei struct {
 	AuthFile   string
 	AuthCookie string
 
+	AlarmFile      string // 上次采集的告警, 用于判断告警产生和恢复
+	EventFile      string
+	TimeSeriesFile string // 历史性能数据
+
 	Host string
 
 	Username string
@@ -76,10 +117,24 @@ type Huawei struct {
 
 	DeviceId string
 
+	Config  HuaweiConfig
+	Limiter *RateLimiter
+	Sink    TimeSeriesSink
+
+	// 设备不支持批量查询性能指标时, 后续改为逐个对象查询
+	statisticBatchDisabled bool
+	// 性能指标对象UUID -> 所属租户名称
+	statisticVStore map[string]string
+	// 租户ID -> 租户名称
+	vstoreNames map[string]string
+	// 租户管理员登录, 无法查询租户列表, 所有对象属于登录的租户
+	vstoreLoginScope bool
+
+	mutex       sync.Mutex
 	CrawlerData *HuaweiCrawlerData
 }
 
-func NewHuaweiCrawler() (*Huawei, error) {
+func NewHuaweiCrawler(conf HuaweiConfig) (*Huawei, error) {
 	c := new(Huawei)
 
 	logger, err := NewLogger("huawei.log")
@@ -90,11 +145,19 @@ func NewHuaweiCrawler() (*Huawei, error) {
 
 	c.AuthFile = "cookie/huawei.cookie"
 
+	c.AlarmFile = "data/huawei_alarm.json"
+	c.EventFile = "data/huawei_event.json"
+	c.TimeSeriesFile = "data/huawei_timeseries.json"
+
 	c.Host = "https://7.3.20.34:8088"
 
 	c.Username = HuaweiAccount
 	c.Password = HuaweiPassword
 
+	c.Config = conf
+	c.Limiter = NewRateLimiter(conf.RequestRate)
+	c.Sink = NewFileSink(c.TimeSeriesFile)
+
 	c.CrawlerData = new(HuaweiCrawlerData)
 
 	return c, nil
@@ -107,38 +170,10 @@ func (c *Huawei) Debug() {
 func (c *Huawei) Start() {
 	c.Log.Debug("抓取华为存储设备信息")
 
-	// 验证授权信息
-	if isExist(c.AuthFile) {
-		c.Log.Debug("检查到授权信息文件")
-		if cookie, err := ioutil.ReadFile(c.AuthFile); err != nil {
-			c.Log.Errorf("读取授权信息文件失败, 需要重新登陆, error: %v", err)
-			if err := c.Login(); err != nil {
-				c.Log.Errorf("登陆失败, 请重试, error: %v", err)
-				return
-			}
-		} else {
-			// 需要判断授权是否过期
-			if len(cookie) > 0 {
-				c.AuthCookie = string(cookie)
-			} else {
-				c.Log.Debug("授权信息文件为空, 执行登陆操作")
-				if err := c.Login(); err != nil {
-					c.Log.Errorf("登陆失败, 请重试, error: %v", err)
-					return
-				}
-			}
-		}
-	} else {
-		c.Log.Debug("未检查到授权信息文件, 执行登陆操作")
-		if err := c.Login(); err != nil {
-			c.Log.Errorf("登陆失败, 请重试, error: %v", err)
-			return
-		}
+	if err := c.preStart(); err != nil {
+		return
 	}
 
-	// 数据初始化
-	c.CrawlerData.SectorSize = 512
-
 	// 服务状态
 	if err := c.GetServerStatus(); err != nil {
 		return
@@ -147,10 +182,16 @@ func (c *Huawei) Start() {
 	if err := c.GetSystemInfo(); err != nil {
 		return
 	}
+	// 硬盘域信息
+	if err := c.GetDiskPoolInfo(); err != nil {
+		return
+	}
 	// 存储池信息
 	if err := c.GetStoragePoolInfo(); err != nil {
 		return
 	}
+	// 系统容量
+	c.SumSystemCapacity()
 	// 风扇信息
 	if err := c.GetFanInfo(); err != nil {
 		return
@@ -163,13 +204,115 @@ func (c *Huawei) Start() {
 	if err := c.GetFcPortInfo(); err != nil {
 		return
 	}
-
-	c.CrawlerData.PrintFile("huawei_text.txt")
+	// 控制器, 框, BBU, 接口模块, 以太网端口, SAS端口信息
+	if err := c.GetHardwareInfo(); err != nil {
+		return
+	}
+	// 硬盘信息
+	if err := c.GetDiskInfo(); err != nil {
+		return
+	}
+	// 告警信息
+	if err := c.GetAlarmInfo(); err != nil {
+		return
+	}
+	// 租户信息
+	if err := c.GetVStoreInfo(); err != nil {
+		return
+	}
+	// LUN信息
+	if err := c.GetLunInfo(); err != nil {
+		return
+	}
+	// 主机和主机组信息
+	if err := c.GetHostInfo(); err != nil {
+		return
+	}
+	// LUN组信息
+	if err := c.GetLunGroupInfo(); err != nil {
+		return
+	}
+	// 映射视图信息
+	if err := c.GetMappingViewInfo(); err != nil {
+		return
+	}
+	// 文件系统和配额树信息
+	if err := c.GetFilesystemInfo(); err != nil {
+		return
+	}
+	// NFS和CIFS共享信息
+	if err := c.GetShareInfo(); err != nil {
+		return
+	}
+	// 双活, 远程复制和HyperCopy信息
+	if err := c.GetReplicationInfo(); err != nil {
+		return
+	}
 
 	// 当前系统各种参数的实时指标状态
 	if err := c.GetCurrentState(); err != nil {
 		return
 	}
+
+	c.CrawlerData.PrintFile("huawei_text.txt")
+
+	// 补录历史性能数据
+	if c.Config.BackfillOnStart {
+		if err := c.Backfill(c.Config.BackfillWindow.Duration); err != nil {
+			return
+		}
+	}
+}
+
+// StartBackfill 仅补录历史性能数据
+func (c *Huawei) StartBackfill(window time.Duration) {
+	c.Log.Debugf("补录华为存储设备历史性能数据, 时间范围: %v", window)
+
+	if err := c.preStart(); err != nil {
+		return
+	}
+	// 扇区大小和租户名称
+	if err := c.GetSystemInfo(); err != nil {
+		return
+	}
+	if err := c.GetVStoreInfo(); err != nil {
+		return
+	}
+	if err := c.Backfill(window); err != nil {
+		return
+	}
+}
+
+func (c *Huawei) preStart() error {
+	// 验证授权信息
+	if isExist(c.AuthFile) {
+		c.Log.Debug("检查到授权信息文件")
+		if cookie, err := ioutil.ReadFile(c.AuthFile); err != nil {
+			c.Log.Errorf("读取授权信息文件失败, 需要重新登陆, error: %v", err)
+			if err := c.Login(); err != nil {
+				c.Log.Errorf("登陆失败, 请重试, error: %v", err)
+				return err
+			}
+		} else {
+			// 需要判断授权是否过期
+			if len(cookie) > 0 {
+				c.AuthCookie = string(cookie)
+			} else {
+				c.Log.Debug("授权信息文件为空, 执行登陆操作")
+				if err := c.Login(); err != nil {
+					c.Log.Errorf("登陆失败, 请重试, error: %v", err)
+					return err
+				}
+			}
+		}
+	} else {
+		c.Log.Debug("未检查到授权信息文件, 执行登陆操作")
+		if err := c.Login(); err != nil {
+			c.Log.Errorf("登陆失败, 请重试, error: %v", err)
+			return err
+		}
+	}
+	return nil
 }
 
 func (c *Huawei) Login() error {
@@ -179,19 +322,23 @@ func (c *Huawei) Login() error {
 
 	// 登录请求参数
 	params := map[string]interface{}{
-		"scope":     0,
+		"scope":     c.Config.LoginScope,
 		"username":  c.Username,
 		"password":  c.Password,
 		"isEncrypt": true,
 		"loginMode": 3,
 	}
+	// 租户管理员登录
+	if len(c.Config.VStoreName) > 0 {
+		params["vstorename"] = c.Config.VStoreName
+	}
 	paramsJson, err := json.Marshal(params)
 	if err != nil {
 		c.Log.Errorf("JSON序列化出错, %v, error: %v", params, err)
 		return err
 	}
 
-	// 构造登录请求
+	// 构造登录请求, 登录前无法获取设备ID, 使用占位符
 	loginUrl := c.Host + "/deviceManager/rest/xxxxx/login"
 	request, _ := http.NewRequest("POST", loginUrl, bytes.NewReader(paramsJson))
 	request.Header.Set("Content-Type", "application/json;charset=UTF-8")
@@ -227,7 +374,30 @@ func (c *Huawei) Login() error {
 	}
 }
 
+const (
+	// HuaweiErrorUnauthorized 会话过期或未登录
+	HuaweiErrorUnauthorized = "-401"
+	// HuaweiErrorInvalidParam 参数错误, 不支持批量查询性能指标的版本对多个UUID返回该错误码
+	HuaweiErrorInvalidParam = "50331651"
+)
+
+// HuaweiError 接口返回的业务错误码
+type HuaweiError struct {
+	Code        string
+	Description string
+}
+
+func (e *HuaweiError) Error() string {
+	if e.Code == HuaweiErrorUnauthorized {
+		return "权限验证失败"
+	}
+	return fmt.Sprintf("请求失败, 错误码: %s, 错误信息: %s", e.Code, e.Description)
+}
+
 func (c *Huawei) RequestJson(method, url string, params io.Reader) (string, error) {
+	// 限制请求频率
+	c.Limiter.Wait()
+
 	// 构造请求客户端
 	client := &http.Client{Transport: &http.Transport{
 		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
@@ -261,15 +431,14 @@ func (c *Huawei) RequestJson(method, url string, params io.Reader) (string, erro
 			// 判断业务状态码
 			errorCode := gjson.Get(string(body), "error.code").String()
 			if errorCode != "0" {
-				if errorCode == "-401" {
+				description := gjson.Get(string(body), "error.description").String()
+				if errorCode == HuaweiErrorUnauthorized {
 					_ = os.Remove(c.AuthFile)
 					c.Log.Errorf("权限验证失败, 移除cookie文件, 请重新运行")
-					return "", errors.New("权限验证失败")
 				} else {
-					c.Log.Errorf("请求失败, url: %s, 错误码: %s, 错误信息: %s",
-						url, errorCode, gjson.Get(string(body), "error.description").String())
-					return "", errors.New("请求失败")
+					c.Log.Errorf("请求失败, url: %s, 错误码: %s, 错误信息: %s", url, errorCode, description)
 				}
+				return "", &HuaweiError{Code: errorCode, Description: description}
 			} else {
 				return string(body), nil
 			}
@@ -277,6 +446,41 @@ func (c *Huawei) RequestJson(method, url string, params io.Reader) (string, erro
 	}
 }
 
+// RequestList 分页查询对象列表, path为deviceManager/rest/<DeviceId>/之后的部分
+func (c *Huawei) RequestList(path string) ([]gjson.Result, error) {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+
+	pageSize := 100
+	results := make([]gjson.Result, 0)
+	for start := 0; ; start += pageSize {
+		requestUrl := fmt.Sprintf("%s/deviceManager/rest/%s/%s%srange=[%d-%d]&t=%d",
+			c.Host, c.DeviceId, path, sep, start, start+pageSize, time.Now().UnixNano()/1e6)
+		data, err := c.RequestJson("GET", requestUrl, nil)
+		if err != nil {
+			return nil, err
+		}
+		page := gjson.Get(data, "data").Array()
+		results = append(results, page...)
+		if len(page) < pageSize {
+			return results, nil
+		}
+	}
+}
+
+// HuaweiEnumName 将枚举值转换为huawei_enum.json中定义的名称
+func HuaweiEnumName(enum, value string) string {
+	enumMap := gjson.Get(HuaweiEnumDefine, enum).Map()
+	for k, v := range enumMap {
+		if v.String() == value {
+			return k
+		}
+	}
+	return value
+}
+
 func (c *Huawei) GetServerStatus() error {
 	c.Log.Debug("[REST]服务状态")
 
@@ -306,155 +510,65 @@ func (c *Huawei) GetSystemInfo() error {
 		return err
 	} else {
 		// 设备型号
-		productMode := gjson.Get(data, "data.PRODUCTMODE").String()
-		productModeEnum := gjson.Get(HuaweiEnumDefine, "PRODUCT_MODE_E").Map()
-		for k, v := range productModeEnum {
-			if v.String() == productMode {
-				c.CrawlerData.ProductMode = k
-			}
-		}
+		c.CrawlerData.ProductMode = HuaweiEnumName("PRODUCT_MODE_E", gjson.Get(data, "data.PRODUCTMODE").String())
 
-		// 硬盘域信息
-		c.GetDiskPoolForSystem()
-		usableDiskpoolCapacityData := c.CrawlerData.UsableDiskpoolCapacityData
-		// 扇区大小
+		// 扇区大小, 容量字段均以扇区为单位
 		sectorSize := gjson.Get(data, "data.SECTORSIZE").Int()
+		if sectorSize <= 0 {
+			c.Log.Warnf("[REST]系统信息中未获取到扇区大小, 使用默认值%d", HuaweiDefaultSectorSize)
+			sectorSize = HuaweiDefaultSectorSize
+		}
 		c.CrawlerData.SectorSize = sectorSize
-		usedRawCapacity := gjson.Get(data, "data.MEMBERDISKSCAPACITY").Int()
-		unusedRawCapacity := gjson.Get(data, "data.FREEDISKSCAPACITY").Int()
-		usedCapacity := usedRawCapacity - usableDiskpoolCapacityData
-		unusedCapacity := unusedRawCapacity + usableDiskpoolCapacityData
-
-		// 系统容量（Byte）
-		c.CrawlerData.SystemCapacity = (usedCapacity + unusedCapacity) * sectorSize
-
-		// 系统已使用容量（Byte）
-		c.CrawlerData.SystemUsedCapacity = usedCapacity * sectorSize
-
-		// 存储池信息
-		c.GetStoragePoolForSystem()
-		// LUN
-		lunCapacity := c.CrawlerData.LunCapacity
-		c.CrawlerData.LunCapacity = lunCapacity * sectorSize
-
-		// 文件系统
-		filesystemCapacity := c.CrawlerData.FilesystemCapacity
-		c.CrawlerData.FilesystemCapacity = filesystemCapacity * sectorSize
-
-		// 数据保护
-		dataProtectCapacity := c.CrawlerData.DataProtectCapacity
-		c.CrawlerData.DataProtectCapacity = dataProtectCapacity * sectorSize
-
-		// 空闲容量
-		freePoolCapacity := c.CrawlerData.FreePoolCapacity
-		c.CrawlerData.FreePoolCapacity = freePoolCapacity * sectorSize
 
-		// 总可用容量
-		usableCapacity := lunCapacity + filesystemCapacity + dataProtectCapacity + freePoolCapacity
-		c.CrawlerData.UsableCapacity = usableCapacity * sectorSize
-
-		// 总订阅容量
-		// c.CrawlerData.TotalCapacity
+		// 硬盘容量（Byte）
+		c.CrawlerData.MemberDiskCapacity = gjson.Get(data, "data.MEMBERDISKSCAPACITY").Int() * sectorSize
+		c.CrawlerData.FreeDiskCapacity = gjson.Get(data, "data.FREEDISKSCAPACITY").Int() * sectorSize
 
 		return nil
 	}
 }
 
-func (c *Huawei) GetDiskPoolForSystem() {
-	c.Log.Debug("[REST]硬盘域信息")
+// SumSystemCapacity 根据硬盘域和存储池信息汇总系统容量
+func (c *Huawei) SumSystemCapacity() {
+	data := c.CrawlerData
 
-	requestUrl := fmt.Sprintf("%s/deviceManager/rest/%s/diskpool?t=%d", c.Host, c.DeviceId, time.Now().UnixNano()/1e6)
-	if data, err := c.RequestJson("GET", requestUrl, nil); err != nil {
-		c.Log.Errorf("[REST]请求硬盘域信息失败, error: %v", err)
-	} else {
-		// 解析数据
-		_, _ = jsonparser.ArrayEach([]byte(data), func(value []byte, valueType jsonparser.ValueType, offset int, err error) {
-			// 保存数据
-			c.CrawlerData.UsableDiskpoolCapacityData = c.CrawlerData.UsableDiskpoolCapacityData + gjson.Get(string(value), "FREECAPACITY").Int()
-		}, "data")
+	// 硬盘域空闲容量
+	data.UsableDiskpoolCapacityData = 0
+	for i := 0; i < len(data.DiskPoolInfo); i++ {
+		data.UsableDiskpoolCapacityData += data.DiskPoolInfo[i].FreeCapacity
 	}
-}
-
-func (c *Huawei) GetStoragePoolForSystem() {
-	c.Log.Debug("[REST]存储池信息(For 系统信息)")
-
-	requestUrl := fmt.Sprintf("%s/deviceManager/rest/%s/storagepool?t=%d", c.Host, c.DeviceId, time.Now().UnixNano()/1e6)
-	if data, err := c.RequestJson("GET", requestUrl, nil); err != nil {
-		c.Log.Errorf("[REST]请求存储池信息(For 系统信息)失败, error: %v", err)
-	} else {
-		// 解析数据
-		_, _ = jsonparser.ArrayEach([]byte(data), func(value []byte, valueType jsonparser.ValueType, offset int, err error) {
-			// 用户消耗容量
-			userConsumedCapacity := gjson.Get(string(value), "USERCONSUMEDCAPACITY").Int()
-			usageType := gjson.Get(string(value), "USAGETYPE").String()
-			if usageType == "1" {
-				// LUN
-				c.CrawlerData.LunCapacity = c.CrawlerData.LunCapacity + userConsumedCapacity
-				// 总订阅容量
-				c.CrawlerData.TotalCapacity = c.CrawlerData.TotalCapacity + gjson.Get(string(value), "LUNCONFIGEDCAPACITY").Int()
-			} else if usageType == "2" {
-				// 文件系统
-				c.CrawlerData.FilesystemCapacity = c.CrawlerData.FilesystemCapacity + userConsumedCapacity
-				// 总订阅容量
-				c.CrawlerData.TotalCapacity = c.CrawlerData.TotalCapacity + gjson.Get(string(value), "TOTALFSCAPACITY").Int()
-			}
-
-			// 数据保护
-			c.CrawlerData.DataProtectCapacity = c.CrawlerData.DataProtectCapacity + gjson.Get(string(value), "REPLICATIONCAPACITY").Int()
-
-			// 空闲容量
-			c.CrawlerData.FreePoolCapacity = c.CrawlerData.FreePoolCapacity + gjson.Get(string(value), "USERFREECAPACITY").Int()
-		}, "data")
+	usedCapacity := data.MemberDiskCapacity - data.UsableDiskpoolCapacityData
+	unusedCapacity := data.FreeDiskCapacity + data.UsableDiskpoolCapacityData
+
+	// 系统容量（Byte）
+	data.SystemCapacity = usedCapacity + unusedCapacity
+
+	// 系统已使用容量（Byte）
+	data.SystemUsedCapacity = usedCapacity
+
+	data.LunCapacity = 0
+	data.FilesystemCapacity = 0
+	data.DataProtectCapacity = 0
+	data.FreePoolCapacity = 0
+	data.TotalCapacity = 0
+	for i := 0; i < len(data.StoragePoolInfo); i++ {
+		pool := data.StoragePoolInfo[i]
+		switch pool.UsageType {
+		case "LUN":
+			data.LunCapacity += pool.ConsumedCapacity
+		case "FILESYSTEM":
+			data.FilesystemCapacity += pool.ConsumedCapacity
+		}
+		// 数据保护
+		data.DataProtectCapacity += pool.ProtectionCapacity
+		// 空闲容量
+		data.FreePoolCapacity += pool.FreeCapacity
+		// 总订阅容量
+		data.TotalCapacity += pool.SubscribedCapacity
 	}
-}
-
-func (c *Huawei) GetStoragePoolInfo() error {
-	c.Log.Debug("[REST]存储池信息")
-
-	requestUrl := fmt.Sprintf("%s/deviceManager/rest/%s/storagepool?t=%d", c.Host, c.DeviceId, time.Now().UnixNano()/1e6)
-	if data, err := c.RequestJson("GET", requestUrl, nil); err != nil {
-		c.Log.Errorf("[REST]请求存储池信息失败, error: %v", err)
-		return err
-	} else {
-		// 解析数据
-		_, _ = jsonparser.ArrayEach([]byte(data), func(value []byte, valueType jsonparser.ValueType, offset int, err error) {
-			// 存储池信息
-			storagePoolInfo := make(map[string]interface{})
-
-			storagePoolInfo["id"] = gjson.Get(string(value), "ID").String()
-			storagePoolInfo["name"] = gjson.Get(string(value), "NAME").String()
-
-			// 健康状态
-			healthStatus := gjson.Get(string(value), "HEALTHSTATUS").String()
-			healthStatusEnum := gjson.Get(HuaweiEnumDefine, "HEALTH_STATUS_E").Map()
-			for k, v := range healthStatusEnum {
-				if v.String() == healthStatus {
-					storagePoolInfo["healthStatus"] = k
-				}
-			}
-
-			// 运行状态
-			runningStatus := gjson.Get(string(value), "RUNNINGSTATUS").String()
-			runningStatusEnum := gjson.Get(HuaweiEnumDefine, "RUNNING_STATUS_E").Map()
-			for k, v := range runningStatusEnum {
-				if v.String() == runningStatus {
-					storagePoolInfo["runningStatus"] = k
-				}
-			}
-
-			// 空闲容量
-			userFreeCapacity := gjson.Get(string(value), "USERFREECAPACITY").Int()
-			storagePoolInfo["userFreeCapacity"] = userFreeCapacity * c.CrawlerData.SectorSize
 
-			// 总容量
-			userTotalCapacity := gjson.Get(string(value), "USERTOTALCAPACITY").Int()
-			storagePoolInfo["userTotalCapacity"] = userTotalCapacity * c.CrawlerData.SectorSize
-
-			c.CrawlerData.StoragePoolInfo = append(c.CrawlerData.StoragePoolInfo, storagePoolInfo)
-		}, "data")
-
-		return nil
-	}
+	// 总可用容量
+	data.UsableCapacity = data.LunCapacity + data.FilesystemCapacity + data.DataProtectCapacity + data.FreePoolCapacity
 }
 
 func (c *Huawei) GetFanInfo() error {
@@ -579,52 +693,197 @@ func (c *Huawei) GetFcPortInfo() error {
 	}
 }
 
-func (c *Huawei) GetCurrentState() error {
-	// 采集指标
-	indexList := []string{"fc_port", "disk", "diskpool", "lun"}
+// 采集性能指标的对象类型
+var huaweiStatisticIndexList = []string{"fc_port", "disk", "diskpool", "lun", "filesystem", "vstore"}
 
+// huaweiStatisticDataIdList 对象类型对应的指标ID列表
+func huaweiStatisticDataIdList(index string) string {
 	// 总IOPS,读IOPS,写IOPS,最大IOPS,读带宽,写带宽
 	baseDataIdList := "22,25,28,307,23,26"
-	baseUrl := fmt.Sprintf("%s/deviceManager/rest/%s/performace_statistic/cur_statistic_data", c.Host, c.DeviceId)
-	for i := 0; i < len(indexList); i++ {
-		index := indexList[i]
+	if index == "diskpool" || index == "filesystem" || index == "vstore" {
+		return strings.Replace(baseDataIdList, "307,", "", 1)
+	}
+	return baseDataIdList
+}
+
+func (c *Huawei) GetCurrentState() error {
+	c.statisticVStore = make(map[string]string)
+	for i := 0; i < len(huaweiStatisticIndexList); i++ {
+		index := huaweiStatisticIndexList[i]
+		if index == "vstore" && c.vstoreLoginScope {
+			// 租户管理员无法查询租户列表, 租户的性能为其下对象的性能
+			continue
+		}
 
 		// 查询列表
-		uuidList := make([]string, 0)
-		listUrl := fmt.Sprintf("%s/deviceManager/rest/%s/%s?t=%d", c.Host, c.DeviceId, index, time.Now().UnixNano()/1e6)
-		if data, err := c.RequestJson("GET", listUrl, nil); err != nil {
-			c.Log.Errorf("[REST]请求[%s]列表数据失败, error: %v", index, err)
+		uuidList, err := c.listStatisticObject(index)
+		if err != nil {
 			return err
-		} else {
-			_, _ = jsonparser.ArrayEach([]byte(data), func(value []byte, valueType jsonparser.ValueType, offset int, err error) {
-				dataType := gjson.Get(string(value), "TYPE").String()
-				dataId := gjson.Get(string(value), "ID").String()
-
-				uuidList = append(uuidList, dataType+":"+dataId)
-			}, "data")
 		}
 
 		// 指标信息
-		for i := 0; i < len(uuidList); i++ {
-			var (
-				uuid       = uuidList[i]
-				dataIdList = baseDataIdList
-			)
-
-			if index == "diskpool" {
-				dataIdList = strings.Replace(baseDataIdList, "307,", "", 1)
+		if err := c.GetStatistic(index, uuidList, huaweiStatisticDataIdList(index)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// listStatisticObject 查询对象列表, 返回对象UUID并记录对象所属租户
+func (c *Huawei) listStatisticObject(index string) ([]string, error) {
+	uuidList := make([]string, 0)
+	items, err := c.RequestList(index)
+	if err != nil {
+		c.Log.Errorf("[REST]请求[%s]列表数据失败, error: %v", index, err)
+		return nil, err
+	}
+	for j := 0; j < len(items); j++ {
+		dataType := items[j].Get("TYPE").String()
+		dataId := items[j].Get("ID").String()
+
+		uuid := dataType + ":" + dataId
+		uuidList = append(uuidList, uuid)
+
+		// 对象所属租户
+		if index == "vstore" {
+			c.statisticVStore[uuid] = items[j].Get("NAME").String()
+		} else {
+			c.statisticVStore[uuid] = c.VStoreName(items[j].Get("vstoreId").String())
+		}
+	}
+	return uuidList, nil
+}
+
+// GetStatistic 按批次并发查询对象的实时性能指标
+func (c *Huawei) GetStatistic(index string, uuidList []string, dataIdList string) error {
+	return c.batchStatistic(index, uuidList, func(batch []string) error {
+		return c.requestStatistic(index, batch, dataIdList)
+	})
+}
+
+// batchStatistic 按批次并发执行性能查询, request查询一个批次的对象
+func (c *Huawei) batchStatistic(index string, uuidList []string, request func(batch []string) error) error {
+	batchSize := c.Config.StatisticBatchSize
+	if batchSize <= 0 {
+		batchSize = 1
+	}
+	parallel := c.Config.StatisticParallel
+	if parallel <= 0 {
+		parallel = 1
+	}
+
+	var (
+		wg       sync.WaitGroup
+		errMutex sync.Mutex
+		firstErr error
+	)
+	sem := make(chan struct{}, parallel)
+	for start := 0; start < len(uuidList); start += batchSize {
+		end := start + batchSize
+		if end > len(uuidList) {
+			end = len(uuidList)
+		}
+		batch := uuidList[start:end]
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := c.getStatisticBatch(index, batch, request); err != nil {
+				errMutex.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				errMutex.Unlock()
 			}
+		}()
+	}
+	wg.Wait()
 
-			collectUrl := fmt.Sprintf("%s?CMO_STATISTIC_UUID=%s&CMO_STATISTIC_DATA_ID_LIST=%s&timeConversion=1", baseUrl, uuid, dataIdList)
+	return firstErr
+}
+
+// getStatisticBatch 查询一个批次, 设备拒绝批量查询或批次中有对象失败时逐个对象查询, 跳过失败的对象
+func (c *Huawei) getStatisticBatch(index string, batch []string, request func(batch []string) error) error {
+	if len(batch) > 1 && !c.isStatisticBatchDisabled() {
+		err := request(batch)
+		if err == nil {
+			return nil
+		}
+		var huaweiErr *HuaweiError
+		if !errors.As(err, &huaweiErr) || huaweiErr.Code == HuaweiErrorUnauthorized {
+			// 网络错误或会话过期, 逐个查询同样会失败
+			return err
+		}
+		if huaweiErr.Code == HuaweiErrorInvalidParam {
+			// 设备拒绝批量查询, 后续改为逐个对象查询
+			c.Log.Warnf("[REST]设备不支持批量请求[%s]指标信息, 改为逐个对象查询", index)
+			c.mutex.Lock()
+			c.statisticBatchDisabled = true
+			c.mutex.Unlock()
+		} else {
+			// 批次中有对象查询失败, 仅该批次逐个查询
+			c.Log.Warnf("[REST]批量请求[%s]指标信息失败, 该批次改为逐个对象查询, error: %v", index, err)
+		}
+	}
 
-			if data, err := c.RequestJson("GET", collectUrl, nil); err != nil {
-				c.Log.Errorf("[REST]请求[%s]指标信息失败, error: %v", index, err)
+	for i := 0; i < len(batch); i++ {
+		if err := request(batch[i : i+1]); err != nil {
+			var huaweiErr *HuaweiError
+			if !errors.As(err, &huaweiErr) || huaweiErr.Code == HuaweiErrorUnauthorized {
 				return err
-			} else {
-				c.Log.Debugf("[REST]UUID[%s]的[%s]的指标项数据, %s", index, uuid, gjson.Get(data, "data.0"))
 			}
+			// 单个对象失败不影响其他对象
+			c.Log.Warnf("[REST]跳过[%s]对象[%s]的指标信息, error: %v", index, batch[i], err)
 		}
 	}
+	return nil
+}
+
+func (c *Huawei) isStatisticBatchDisabled() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.statisticBatchDisabled
+}
+
+func (c *Huawei) requestStatistic(index string, uuidList []string, dataIdList string) error {
+	baseUrl := fmt.Sprintf("%s/deviceManager/rest/%s/performace_statistic/cur_statistic_data", c.Host, c.DeviceId)
+	collectUrl := fmt.Sprintf("%s?CMO_STATISTIC_UUID=%s&CMO_STATISTIC_DATA_ID_LIST=%s&timeConversion=1",
+		baseUrl, strings.Join(uuidList, ","), dataIdList)
+
+	data, err := c.RequestJson("GET", collectUrl, nil)
+	if err != nil {
+		c.Log.Errorf("[REST]请求[%s]指标信息失败, uuid: %v, error: %v", index, uuidList, err)
+		return err
+	}
 
+	dataIds := strings.Split(dataIdList, ",")
+	results := gjson.Get(data, "data").Array()
+	for i := 0; i < len(results); i++ {
+		statistic := new(HuaweiStatistic)
+		statistic.Object = index
+		statistic.Uuid = results[i].Get("CMO_STATISTIC_UUID").String()
+		if len(statistic.Uuid) == 0 && len(uuidList) == 1 {
+			statistic.Uuid = uuidList[0]
+		}
+		statistic.VStore = c.statisticVStore[statistic.Uuid]
+		statistic.Data = make(map[string]int64)
+
+		// 指标值与指标ID列表顺序一致
+		values := strings.Split(results[i].Get("CMO_STATISTIC_DATA_LIST").String(), ",")
+		for j := 0; j < len(values) && j < len(dataIds); j++ {
+			value, _ := strconv.ParseInt(values[j], 10, 64)
+			statistic.Data[dataIds[j]] = value
+		}
+		c.Log.Debugf("[REST]UUID[%s]的[%s]的指标项数据, %v", index, statistic.Uuid, statistic.Data)
+
+		c.mutex.Lock()
+		c.CrawlerData.StatisticInfo = append(c.CrawlerData.StatisticInfo, statistic)
+		c.mutex.Unlock()
+	}
 	return nil
 }
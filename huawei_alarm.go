@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+type HuaweiAlarm struct {
+	Sequence    string `json:"sequence"` // 告警流水号
+	AlarmId     string `json:"alarmId"`  // 告警ID
+	Name        string `json:"name"`
+	Level       string `json:"level"`  // 级别(INFO, WARNING, MAJOR, CRITICAL)
+	Type        string `json:"type"`   // 类型(EVENT, ALARM)
+	Status      string `json:"status"` // 状态(UNRECOVERED, CLEARED, RECOVERED)
+	Description string `json:"description"`
+	Location    string `json:"location"`
+	StartTime   int64  `json:"startTime"` // 产生时间(秒)
+}
+
+// GetAlarmInfo 读取当前告警, 与上次保存的告警状态比较生成告警产生和恢复事件.
+// 没有告警状态文件(首次采集)时只保存当前告警, 不生成告警产生事件, 避免把已存在的告警当作新告警
+func (c *Huawei) GetAlarmInfo() error {
+	c.Log.Debug("[REST]当前告警信息")
+
+	items, err := c.RequestList("alarm/currentalarm")
+	if err != nil {
+		c.Log.Errorf("[REST]请求当前告警信息失败, error: %v", err)
+		return err
+	}
+
+	current := make(map[string]*HuaweiAlarm)
+	c.CrawlerData.AlarmCount = make(map[string]int64)
+	for i := 0; i < len(items); i++ {
+		item := items[i]
+
+		alarm := new(HuaweiAlarm)
+		alarm.Sequence = item.Get("sequence").String()
+		alarm.AlarmId = item.Get("eventID").String()
+		alarm.Name = item.Get("name").String()
+		alarm.Level = HuaweiEnumName("EVENT_LEVEL_E", item.Get("level").String())
+		alarm.Type = HuaweiEnumName("CMO_ALARM_TYPE", item.Get("eventType").String())
+		alarm.Status = HuaweiEnumName("ALARM_STATUS_E", item.Get("alarmStatus").String())
+		alarm.Description = item.Get("description").String()
+		alarm.Location = item.Get("location").String()
+		alarm.StartTime = item.Get("startTime").Int()
+
+		current[alarm.Sequence] = alarm
+		c.CrawlerData.AlarmInfo = append(c.CrawlerData.AlarmInfo, alarm)
+		c.CrawlerData.AlarmCount[alarm.Level]++
+	}
+
+	// 与上次采集的告警比较, 生成告警产生和恢复事件
+	previous := make(map[string]*HuaweiAlarm)
+	baseline := false
+	if data, err := ioutil.ReadFile(c.AlarmFile); err == nil {
+		baseline = true
+		alarms := make([]*HuaweiAlarm, 0)
+		if err := json.Unmarshal(data, &alarms); err != nil {
+			c.Log.Errorf("解析告警状态文件失败, error: %v", err)
+		}
+		for i := 0; i < len(alarms); i++ {
+			previous[alarms[i].Sequence] = alarms[i]
+		}
+	}
+
+	if !baseline {
+		c.Log.Infof("没有告警状态文件, 保存当前的%d条告警作为基准, 不生成告警产生事件", len(current))
+	}
+
+	events := make([]*Event, 0)
+	for seq, alarm := range current {
+		if _, ok := previous[seq]; !ok && baseline {
+			events = append(events, alarm.toEvent(EventAlarmRaised, time.Unix(alarm.StartTime, 0)))
+		}
+	}
+	for seq, alarm := range previous {
+		if _, ok := current[seq]; !ok {
+			events = append(events, alarm.toEvent(EventAlarmCleared, time.Now()))
+		}
+	}
+	for i := 0; i < len(events); i++ {
+		c.Log.Warnf("告警事件[%s], ID: %s, 级别: %s, 位置: %s, 描述: %s",
+			events[i].Type, events[i].Id, events[i].Level, events[i].Location, events[i].Message)
+	}
+	if err := AppendEvents(c.EventFile, events); err != nil {
+		c.Log.Errorf("写入告警事件失败, error: %v", err)
+		return err
+	}
+
+	// 保存本次告警状态
+	data, _ := json.Marshal(c.CrawlerData.AlarmInfo)
+	if err := ioutil.WriteFile(c.AlarmFile, data, os.ModePerm); err != nil {
+		c.Log.Errorf("写入告警状态文件失败, error: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (a *HuaweiAlarm) toEvent(eventType string, t time.Time) *Event {
+	return &Event{
+		Time:     t.Format("2006-01-02 15:04:05"),
+		Source:   "huawei",
+		Type:     eventType,
+		Id:       a.AlarmId,
+		Level:    a.Level,
+		Location: a.Location,
+		Message:  a.Description,
+	}
+}
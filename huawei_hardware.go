@@ -0,0 +1,118 @@
+package main
+
+import (
+	"strings"
+)
+
+type HuaweiComponent struct {
+	Type            string `json:"type"` // 部件类型(controller, enclosure, backup_power, intf_module, eth_port, sas_port)
+	Id              string `json:"id"`
+	Name            string `json:"name"`
+	Location        string `json:"location"`
+	Enclosure       string `json:"enclosure"` // 所在框, 由位置信息解析
+	Slot            string `json:"slot"`      // 所在槽位, 由位置信息解析
+	HealthStatus    string `json:"healthStatus"`
+	RunningStatus   string `json:"runningStatus"`
+	Temperature     int64  `json:"temperature"`     // 温度(℃)
+	FirmwareVersion string `json:"firmwareVersion"` // 固件版本
+}
+
+type HuaweiDisk struct {
+	Id              string `json:"id"`
+	Location        string `json:"location"`
+	Enclosure       string `json:"enclosure"`
+	Slot            string `json:"slot"`
+	DiskType        string `json:"diskType"`
+	Model           string `json:"model"`
+	SerialNumber    string `json:"serialNumber"`
+	Capacity        int64  `json:"capacity"` // 容量(Byte)
+	HealthStatus    string `json:"healthStatus"`
+	RunningStatus   string `json:"runningStatus"`
+	Temperature     int64  `json:"temperature"`     // 温度(℃)
+	FirmwareVersion string `json:"firmwareVersion"` // 固件版本
+	WearLevel       int64  `json:"wearLevel"`       // 磨损度(%), 仅SSD
+	RemainLife      int64  `json:"remainLife"`      // 剩余寿命(天)
+}
+
+// 硬件部件查询路径 -> 固件版本字段
+var huaweiComponentIndex = [][]string{
+	{"controller", "SOFTVER"},
+	{"enclosure", ""},
+	{"backup_power", "FIRMWAREVER"},
+	{"intf_module", "FIRMWAREVER"},
+	{"eth_port", ""},
+	{"sas_port", ""},
+}
+
+func (c *Huawei) GetHardwareInfo() error {
+	for i := 0; i < len(huaweiComponentIndex); i++ {
+		index := huaweiComponentIndex[i][0]
+		firmwareKey := huaweiComponentIndex[i][1]
+
+		c.Log.Debugf("[REST]硬件信息[%s]", index)
+		items, err := c.RequestList(index)
+		if err != nil {
+			c.Log.Errorf("[REST]请求硬件信息[%s]失败, error: %v", index, err)
+			return err
+		}
+		for j := 0; j < len(items); j++ {
+			item := items[j]
+
+			component := new(HuaweiComponent)
+			component.Type = index
+			component.Id = item.Get("ID").String()
+			component.Name = item.Get("NAME").String()
+			component.Location = item.Get("LOCATION").String()
+			component.Enclosure, component.Slot = parseHuaweiLocation(component.Location)
+			component.HealthStatus = HuaweiEnumName("HEALTH_STATUS_E", item.Get("HEALTHSTATUS").String())
+			component.RunningStatus = HuaweiEnumName("RUNNING_STATUS_E", item.Get("RUNNINGSTATUS").String())
+			component.Temperature = item.Get("TEMPERATURE").Int()
+			if len(firmwareKey) > 0 {
+				component.FirmwareVersion = item.Get(firmwareKey).String()
+			}
+
+			c.CrawlerData.HardwareInfo = append(c.CrawlerData.HardwareInfo, component)
+		}
+	}
+	return nil
+}
+
+func (c *Huawei) GetDiskInfo() error {
+	c.Log.Debug("[REST]硬盘信息")
+
+	items, err := c.RequestList("disk")
+	if err != nil {
+		c.Log.Errorf("[REST]请求硬盘信息失败, error: %v", err)
+		return err
+	}
+	for i := 0; i < len(items); i++ {
+		item := items[i]
+
+		disk := new(HuaweiDisk)
+		disk.Id = item.Get("ID").String()
+		disk.Location = item.Get("LOCATION").String()
+		disk.Enclosure, disk.Slot = parseHuaweiLocation(disk.Location)
+		disk.DiskType = HuaweiEnumName("DISK_TYPE_E", item.Get("DISKTYPE").String())
+		disk.Model = item.Get("MODEL").String()
+		disk.SerialNumber = item.Get("SERIALNUMBER").String()
+		disk.Capacity = item.Get("SECTORS").Int() * item.Get("SECTORSIZE").Int()
+		disk.HealthStatus = HuaweiEnumName("HEALTH_STATUS_E", item.Get("HEALTHSTATUS").String())
+		disk.RunningStatus = HuaweiEnumName("RUNNING_STATUS_E", item.Get("RUNNINGSTATUS").String())
+		disk.Temperature = item.Get("TEMPERATURE").Int()
+		disk.FirmwareVersion = item.Get("FIRMWAREVER").String()
+		disk.WearLevel = item.Get("ABRASIONRATE").Int()
+		disk.RemainLife = item.Get("REMAINLIFE").Int()
+
+		c.CrawlerData.DiskInfo = append(c.CrawlerData.DiskInfo, disk)
+	}
+	return nil
+}
+
+// parseHuaweiLocation 解析位置信息, 如 CTE0.A -> (CTE0, A), DAE000.5 -> (DAE000, 5)
+func parseHuaweiLocation(location string) (string, string) {
+	i := strings.Index(location, ".")
+	if i < 0 {
+		return location, ""
+	}
+	return location[:i], location[i+1:]
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// huaweiStatisticDataNames 性能指标ID -> 时序数据中的指标名称
+//
+// 不能通过STATISTIC_DATA_ID反查, 枚举中有多个名称对应同一个ID
+var huaweiStatisticDataNames = map[string]string{
+	"22":  "totalIOPS",
+	"23":  "readBandwidth",
+	"25":  "readIOPS",
+	"26":  "writeBandwidth",
+	"28":  "writeIOPS",
+	"307": "maxIOPS",
+}
+
+// huaweiStatisticDataName 指标ID对应的指标名称, 未定义的指标使用 data_<指标ID>
+func huaweiStatisticDataName(dataId string) string {
+	if name, ok := huaweiStatisticDataNames[dataId]; ok {
+		return name
+	}
+	return "data_" + dataId
+}
+
+// Backfill 补录指定时间范围内各对象的历史性能数据, 使用数据原始的采样时间写入时序数据
+//
+// 与实时性能查询一样按批次并发查询, 查询失败的对象类型和对象跳过, 已写入的采样点不重复写入
+func (c *Huawei) Backfill(window time.Duration) error {
+	end := time.Now()
+	start := end.Add(-window)
+	c.Log.Debugf("[REST]补录历史性能数据, 开始时间: %v, 结束时间: %v", start, end)
+
+	written := make(map[string]bool)
+	if reader, ok := c.Sink.(TimeSeriesKeyReader); ok {
+		keys, err := reader.Keys(start.Unix(), end.Unix())
+		if err != nil {
+			c.Log.Errorf("读取已写入的时序数据失败, error: %v", err)
+			return err
+		}
+		written = keys
+	}
+
+	var firstErr error
+	c.statisticVStore = make(map[string]string)
+	for i := 0; i < len(huaweiStatisticIndexList); i++ {
+		index := huaweiStatisticIndexList[i]
+		if index == "vstore" && c.vstoreLoginScope {
+			continue
+		}
+		dataIdList := huaweiStatisticDataIdList(index)
+
+		uuidList, err := c.listStatisticObject(index)
+		if err != nil {
+			c.Log.Errorf("[REST]跳过[%s]的历史性能数据, error: %v", index, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		var mutex sync.Mutex
+		samples := make([]*Sample, 0)
+		err = c.batchStatistic(index, uuidList, func(batch []string) error {
+			data, err := c.requestHistoryStatistic(batch, dataIdList, start, end)
+			if err != nil {
+				c.Log.Errorf("[REST]请求[%s]历史指标信息失败, uuid: %v, error: %v", index, batch, err)
+				return err
+			}
+			mutex.Lock()
+			samples = append(samples, parseHuaweiHistory(index, batch, c.statisticVStore, dataIdList, data)...)
+			mutex.Unlock()
+			return nil
+		})
+		if err != nil {
+			c.Log.Errorf("[REST]请求[%s]历史指标信息失败, error: %v", index, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+
+		// 去掉已写入的采样点
+		unwritten := make([]*Sample, 0, len(samples))
+		for j := 0; j < len(samples); j++ {
+			key := samples[j].Key()
+			if !written[key] {
+				written[key] = true
+				unwritten = append(unwritten, samples[j])
+			}
+		}
+		if err := c.Sink.Write(unwritten); err != nil {
+			c.Log.Errorf("写入[%s]历史性能数据失败, error: %v", index, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		c.Log.Debugf("[REST]对象类型[%s]补录历史性能数据%d条, 跳过已写入的%d条", index, len(unwritten), len(samples)-len(unwritten))
+	}
+	return firstErr
+}
+
+func (c *Huawei) requestHistoryStatistic(uuidList []string, dataIdList string, start, end time.Time) (string, error) {
+	baseUrl := fmt.Sprintf("%s/deviceManager/rest/%s/performace_statistic/history_statistic_data", c.Host, c.DeviceId)
+	collectUrl := fmt.Sprintf("%s?CMO_STATISTIC_UUID=%s&CMO_STATISTIC_DATA_ID_LIST=%s&CMO_STATISTIC_START_TIME=%d&CMO_STATISTIC_END_TIME=%d&timeConversion=1",
+		baseUrl, strings.Join(uuidList, ","), dataIdList, start.Unix(), end.Unix())
+	return c.RequestJson("GET", collectUrl, nil)
+}
+
+// parseHuaweiHistory 将历史性能数据转换为时序数据, 每个采样点的指标值与指标ID列表顺序一致
+//
+// 批量查询时采样点中的CMO_STATISTIC_UUID为所属对象, 单个对象查询时可能不返回
+func parseHuaweiHistory(index string, uuidList []string, vstores map[string]string, dataIdList, data string) []*Sample {
+	dataIds := strings.Split(dataIdList, ",")
+	samples := make([]*Sample, 0)
+
+	points := gjson.Get(data, "data").Array()
+	for i := 0; i < len(points); i++ {
+		uuid := points[i].Get("CMO_STATISTIC_UUID").String()
+		if len(uuid) == 0 && len(uuidList) == 1 {
+			uuid = uuidList[0]
+		}
+		timestamp := points[i].Get("CMO_STATISTIC_TIMESTAMP").Int()
+		values := strings.Split(points[i].Get("CMO_STATISTIC_DATA_LIST").String(), ",")
+		for j := 0; j < len(values) && j < len(dataIds); j++ {
+			value, err := strconv.ParseFloat(values[j], 64)
+			if err != nil {
+				continue
+			}
+			samples = append(samples, &Sample{
+				Metric: "huawei_" + index + "_" + huaweiStatisticDataName(dataIds[j]),
+				Labels: map[string]string{
+					"uuid":   uuid,
+					"vstore": vstores[uuid],
+				},
+				Value:     value,
+				Timestamp: timestamp,
+			})
+		}
+	}
+	return samples
+}
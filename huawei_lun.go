@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/tidwall/gjson"
+)
+
+type HuaweiLun struct {
+	Id            string `json:"id"`
+	Name          string `json:"name"`
+	VStore        string `json:"vstore"` // 所属租户
+	Wwn           string `json:"wwn"`
+	PoolId        string `json:"poolId"`        // 所属存储池ID
+	PoolName      string `json:"poolName"`      // 所属存储池名称
+	AllocType     string `json:"allocType"`     // 分配类型(FAT, THIN)
+	Capacity      int64  `json:"capacity"`      // 容量(Byte)
+	AllocCapacity int64  `json:"allocCapacity"` // 已分配容量(Byte)
+	HealthStatus  string `json:"healthStatus"`
+	RunningStatus string `json:"runningStatus"`
+}
+
+type HuaweiHost struct {
+	Id            string `json:"id"`
+	Name          string `json:"name"`
+	VStore        string `json:"vstore"` // 所属租户
+	Ip            string `json:"ip"`
+	OsType        string `json:"osType"`
+	HealthStatus  string `json:"healthStatus"`
+	RunningStatus string `json:"runningStatus"`
+}
+
+type HuaweiHostGroup struct {
+	Id      string   `json:"id"`
+	Name    string   `json:"name"`
+	HostIds []string `json:"hostIds"`
+}
+
+type HuaweiLunGroup struct {
+	Id       string   `json:"id"`
+	Name     string   `json:"name"`
+	LunIds   []string `json:"lunIds"`
+	Capacity int64    `json:"capacity"` // 组内LUN总容量(Byte)
+}
+
+type HuaweiMappingView struct {
+	Id           string   `json:"id"`
+	Name         string   `json:"name"`
+	HostGroupIds []string `json:"hostGroupIds"`
+	LunGroupIds  []string `json:"lunGroupIds"`
+}
+
+func (c *Huawei) GetLunInfo() error {
+	c.Log.Debug("[REST]LUN信息")
+
+	items, err := c.RequestList("lun")
+	if err != nil {
+		c.Log.Errorf("[REST]请求LUN信息失败, error: %v", err)
+		return err
+	}
+	for i := 0; i < len(items); i++ {
+		item := items[i]
+
+		lun := new(HuaweiLun)
+		lun.Id = item.Get("ID").String()
+		lun.Name = item.Get("NAME").String()
+		lun.VStore = c.VStoreName(item.Get("vstoreId").String())
+		lun.Wwn = item.Get("WWN").String()
+		lun.PoolId = item.Get("PARENTID").String()
+		lun.PoolName = item.Get("PARENTNAME").String()
+		lun.AllocType = HuaweiEnumName("LUN_ALLOC_TYPE_E", item.Get("ALLOCTYPE").String())
+		lun.Capacity = item.Get("CAPACITY").Int() * c.CrawlerData.SectorSize
+		lun.AllocCapacity = item.Get("ALLOCCAPACITY").Int() * c.CrawlerData.SectorSize
+		lun.HealthStatus = HuaweiEnumName("HEALTH_STATUS_E", item.Get("HEALTHSTATUS").String())
+		lun.RunningStatus = HuaweiEnumName("RUNNING_STATUS_E", item.Get("RUNNINGSTATUS").String())
+
+		c.CrawlerData.LunInfo = append(c.CrawlerData.LunInfo, lun)
+	}
+	return nil
+}
+
+func (c *Huawei) GetHostInfo() error {
+	c.Log.Debug("[REST]主机信息")
+
+	items, err := c.RequestList("host")
+	if err != nil {
+		c.Log.Errorf("[REST]请求主机信息失败, error: %v", err)
+		return err
+	}
+	for i := 0; i < len(items); i++ {
+		item := items[i]
+
+		host := new(HuaweiHost)
+		host.Id = item.Get("ID").String()
+		host.Name = item.Get("NAME").String()
+		host.VStore = c.VStoreName(item.Get("vstoreId").String())
+		host.Ip = item.Get("IP").String()
+		host.OsType = HuaweiEnumName("OS_TYPE_E", item.Get("OPERATIONSYSTEM").String())
+		host.HealthStatus = HuaweiEnumName("HEALTH_STATUS_E", item.Get("HEALTHSTATUS").String())
+		host.RunningStatus = HuaweiEnumName("RUNNING_STATUS_E", item.Get("RUNNINGSTATUS").String())
+
+		c.CrawlerData.HostInfo = append(c.CrawlerData.HostInfo, host)
+	}
+
+	c.Log.Debug("[REST]主机组信息")
+
+	groups, err := c.RequestList("hostgroup")
+	if err != nil {
+		c.Log.Errorf("[REST]请求主机组信息失败, error: %v", err)
+		return err
+	}
+	for i := 0; i < len(groups); i++ {
+		group := new(HuaweiHostGroup)
+		group.Id = groups[i].Get("ID").String()
+		group.Name = groups[i].Get("NAME").String()
+
+		// 主机组内的主机
+		hosts, err := c.requestAssociate("host", "HOSTGROUP", group.Id)
+		if err != nil {
+			c.Log.Errorf("[REST]请求主机组[%s]的主机失败, error: %v", group.Name, err)
+			return err
+		}
+		for j := 0; j < len(hosts); j++ {
+			group.HostIds = append(group.HostIds, hosts[j].Get("ID").String())
+		}
+
+		c.CrawlerData.HostGroupInfo = append(c.CrawlerData.HostGroupInfo, group)
+	}
+	return nil
+}
+
+func (c *Huawei) GetLunGroupInfo() error {
+	c.Log.Debug("[REST]LUN组信息")
+
+	groups, err := c.RequestList("lungroup")
+	if err != nil {
+		c.Log.Errorf("[REST]请求LUN组信息失败, error: %v", err)
+		return err
+	}
+	for i := 0; i < len(groups); i++ {
+		group := new(HuaweiLunGroup)
+		group.Id = groups[i].Get("ID").String()
+		group.Name = groups[i].Get("NAME").String()
+
+		// LUN组内的LUN
+		luns, err := c.requestAssociate("lun", "LUNGroup", group.Id)
+		if err != nil {
+			c.Log.Errorf("[REST]请求LUN组[%s]的LUN失败, error: %v", group.Name, err)
+			return err
+		}
+		for j := 0; j < len(luns); j++ {
+			group.LunIds = append(group.LunIds, luns[j].Get("ID").String())
+			group.Capacity += luns[j].Get("CAPACITY").Int() * c.CrawlerData.SectorSize
+		}
+
+		c.CrawlerData.LunGroupInfo = append(c.CrawlerData.LunGroupInfo, group)
+	}
+	return nil
+}
+
+func (c *Huawei) GetMappingViewInfo() error {
+	c.Log.Debug("[REST]映射视图信息")
+
+	views, err := c.RequestList("mappingview")
+	if err != nil {
+		c.Log.Errorf("[REST]请求映射视图信息失败, error: %v", err)
+		return err
+	}
+	for i := 0; i < len(views); i++ {
+		view := new(HuaweiMappingView)
+		view.Id = views[i].Get("ID").String()
+		view.Name = views[i].Get("NAME").String()
+
+		// 映射视图关联的主机组
+		hostGroups, err := c.requestAssociate("hostgroup", "MAPPINGVIEW", view.Id)
+		if err != nil {
+			c.Log.Errorf("[REST]请求映射视图[%s]的主机组失败, error: %v", view.Name, err)
+			return err
+		}
+		for j := 0; j < len(hostGroups); j++ {
+			view.HostGroupIds = append(view.HostGroupIds, hostGroups[j].Get("ID").String())
+		}
+
+		// 映射视图关联的LUN组
+		lunGroups, err := c.requestAssociate("lungroup", "MAPPINGVIEW", view.Id)
+		if err != nil {
+			c.Log.Errorf("[REST]请求映射视图[%s]的LUN组失败, error: %v", view.Name, err)
+			return err
+		}
+		for j := 0; j < len(lunGroups); j++ {
+			view.LunGroupIds = append(view.LunGroupIds, lunGroups[j].Get("ID").String())
+		}
+
+		c.CrawlerData.MappingViewInfo = append(c.CrawlerData.MappingViewInfo, view)
+	}
+	return nil
+}
+
+// requestAssociate 查询与指定对象关联的对象列表, objType为MOTYPE中定义的名称
+func (c *Huawei) requestAssociate(index, objType, objId string) ([]gjson.Result, error) {
+	typeId := gjson.Get(HuaweiEnumDefine, "MOTYPE."+objType).String()
+	return c.RequestList(fmt.Sprintf("%s/associate?ASSOCIATEOBJTYPE=%s&ASSOCIATEOBJID=%s", index, typeId, objId))
+}
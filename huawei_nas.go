@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+
+	"github.com/tidwall/gjson"
+)
+
+type HuaweiFilesystem struct {
+	Id            string `json:"id"`
+	Name          string `json:"name"`
+	VStore        string `json:"vstore"`   // 所属租户
+	PoolId        string `json:"poolId"`   // 所属存储池ID
+	PoolName      string `json:"poolName"` // 所属存储池名称
+	AllocType     string `json:"allocType"`
+	HealthStatus  string `json:"healthStatus"`
+	RunningStatus string `json:"runningStatus"`
+
+	Capacity             int64 `json:"capacity"`             // 总容量(Byte)
+	UsedCapacity         int64 `json:"usedCapacity"`         // 已使用容量(Byte)
+	AvailableCapacity    int64 `json:"availableCapacity"`    // 可用容量(Byte)
+	SnapshotReservePer   int64 `json:"snapshotReservePer"`   // 快照预留空间比例(%)
+	SnapshotUsedCapacity int64 `json:"snapshotUsedCapacity"` // 快照已使用容量(Byte)
+}
+
+type HuaweiShare struct {
+	Protocol     string `json:"protocol"` // 共享协议(NFS, CIFS)
+	Id           string `json:"id"`
+	Name         string `json:"name"`
+	VStore       string `json:"vstore"`
+	SharePath    string `json:"sharePath"`
+	FilesystemId string `json:"filesystemId"`
+}
+
+type HuaweiQuotaTree struct {
+	Id           string `json:"id"`
+	Name         string `json:"name"`
+	VStore       string `json:"vstore"`
+	FilesystemId string `json:"filesystemId"`
+
+	SpaceUsed      int64 `json:"spaceUsed"`      // 已使用空间(Byte)
+	SpaceSoftQuota int64 `json:"spaceSoftQuota"` // 空间软配额(Byte), 0表示未设置
+	SpaceHardQuota int64 `json:"spaceHardQuota"` // 空间硬配额(Byte), 0表示未设置
+	FileUsed       int64 `json:"fileUsed"`       // 已使用文件数
+	FileSoftQuota  int64 `json:"fileSoftQuota"`  // 文件数软配额, 0表示未设置
+	FileHardQuota  int64 `json:"fileHardQuota"`  // 文件数硬配额, 0表示未设置
+}
+
+func (c *Huawei) GetFilesystemInfo() error {
+	c.Log.Debug("[REST]文件系统信息")
+
+	items, err := c.RequestList("filesystem")
+	if err != nil {
+		c.Log.Errorf("[REST]请求文件系统信息失败, error: %v", err)
+		return err
+	}
+	sectorSize := c.CrawlerData.SectorSize
+	for i := 0; i < len(items); i++ {
+		item := items[i]
+
+		fs := new(HuaweiFilesystem)
+		fs.Id = item.Get("ID").String()
+		fs.Name = item.Get("NAME").String()
+		fs.VStore = c.VStoreName(item.Get("vstoreId").String())
+		fs.PoolId = item.Get("PARENTID").String()
+		fs.PoolName = item.Get("PARENTNAME").String()
+		fs.AllocType = HuaweiEnumName("LUN_ALLOC_TYPE_E", item.Get("ALLOCTYPE").String())
+		fs.HealthStatus = HuaweiEnumName("HEALTH_STATUS_E", item.Get("HEALTHSTATUS").String())
+		fs.RunningStatus = HuaweiEnumName("RUNNING_STATUS_E", item.Get("RUNNINGSTATUS").String())
+
+		fs.Capacity = item.Get("CAPACITY").Int() * sectorSize
+		// 设备接口字段名称即为CAPCITY
+		fs.AvailableCapacity = item.Get("AVAILABLECAPCITY").Int() * sectorSize
+		fs.UsedCapacity = fs.Capacity - fs.AvailableCapacity
+		fs.SnapshotReservePer = item.Get("SNAPSHOTRESERVEPER").Int()
+		fs.SnapshotUsedCapacity = item.Get("SNAPSHOTUSECAPACITY").Int() * sectorSize
+
+		c.CrawlerData.FilesystemInfo = append(c.CrawlerData.FilesystemInfo, fs)
+
+		// 文件系统下的配额树
+		if err := c.GetQuotaTreeInfo(fs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Huawei) GetShareInfo() error {
+	// 共享查询路径 -> 共享协议
+	shareIndex := [][]string{
+		{"NFSHARE", "NFS"},
+		{"CIFSHARE", "CIFS"},
+	}
+	for i := 0; i < len(shareIndex); i++ {
+		index, protocol := shareIndex[i][0], shareIndex[i][1]
+
+		c.Log.Debugf("[REST]%s共享信息", protocol)
+		items, err := c.RequestList(index)
+		if err != nil {
+			c.Log.Errorf("[REST]请求%s共享信息失败, error: %v", protocol, err)
+			return err
+		}
+		for j := 0; j < len(items); j++ {
+			item := items[j]
+
+			share := new(HuaweiShare)
+			share.Protocol = protocol
+			share.Id = item.Get("ID").String()
+			share.Name = item.Get("NAME").String()
+			share.VStore = c.VStoreName(item.Get("vstoreId").String())
+			share.SharePath = item.Get("SHAREPATH").String()
+			share.FilesystemId = item.Get("FSID").String()
+
+			c.CrawlerData.ShareInfo = append(c.CrawlerData.ShareInfo, share)
+		}
+	}
+	return nil
+}
+
+func (c *Huawei) GetQuotaTreeInfo(fs *HuaweiFilesystem) error {
+	items, err := c.RequestList("quotatree?PARENTID=" + url.QueryEscape(fs.Id))
+	if err != nil {
+		c.Log.Errorf("[REST]请求文件系统[%s]的配额树信息失败, error: %v", fs.Name, err)
+		return err
+	}
+	quotaTypeId := gjson.Get(HuaweiEnumDefine, "MOTYPE.QUOTATREE").String()
+	for i := 0; i < len(items); i++ {
+		tree := new(HuaweiQuotaTree)
+		tree.Id = items[i].Get("ID").String()
+		tree.Name = items[i].Get("NAME").String()
+		tree.VStore = fs.VStore
+		tree.FilesystemId = fs.Id
+
+		// 配额树的配额, 一个配额树下可能有多条配额规则, 取目录配额
+		quotas, err := c.RequestList(fmt.Sprintf("FS_QUOTA?PARENTTYPE=%s&PARENTID=%s", quotaTypeId, url.QueryEscape(tree.Id)))
+		if err != nil {
+			c.Log.Errorf("[REST]请求配额树[%s]的配额信息失败, error: %v", tree.Name, err)
+			return err
+		}
+		for j := 0; j < len(quotas); j++ {
+			quota := quotas[j]
+			if HuaweiEnumName("QUOTA_TYPE_E", quota.Get("QUOTATYPE").String()) != "FS_QUOTA_TREE" {
+				continue
+			}
+			unit := huaweiQuotaUnit(quota.Get("SPACEUNITTYPE").String())
+			tree.SpaceUsed = huaweiQuotaValue(quota.Get("SPACEUSED").String()) * unit
+			tree.SpaceSoftQuota = huaweiQuotaValue(quota.Get("SPACESOFTQUOTA").String()) * unit
+			tree.SpaceHardQuota = huaweiQuotaValue(quota.Get("SPACEHARDQUOTA").String()) * unit
+			tree.FileUsed = huaweiQuotaValue(quota.Get("FILEUSED").String())
+			tree.FileSoftQuota = huaweiQuotaValue(quota.Get("FILESOFTQUOTA").String())
+			tree.FileHardQuota = huaweiQuotaValue(quota.Get("FILEHARDQUOTA").String())
+		}
+
+		c.CrawlerData.QuotaTreeInfo = append(c.CrawlerData.QuotaTreeInfo, tree)
+	}
+	return nil
+}
+
+// huaweiQuotaUnit 配额空间单位(UNIT_E)对应的字节数
+//
+// 配额的空间值不是以扇区为单位, 而是以SPACEUNITTYPE为单位, 未返回单位时为KB
+func huaweiQuotaUnit(unitType string) int64 {
+	switch HuaweiEnumName("UNIT_E", unitType) {
+	case "UNIT_BYTE":
+		return 1
+	case "UNIT_MB", "UNIT_MIB":
+		return 1 << 20
+	case "UNIT_GB", "UNIT_GIB":
+		return 1 << 30
+	case "UNIT_TB", "UNIT_TIB":
+		return 1 << 40
+	case "UNIT_PB", "UNIT_PIB":
+		return 1 << 50
+	default:
+		return 1 << 10
+	}
+}
+
+// huaweiQuotaValue 解析配额值, 未设置配额时设备返回18446744073709551615, 转换为0
+func huaweiQuotaValue(value string) int64 {
+	number, err := strconv.ParseUint(value, 10, 64)
+	if err != nil || number > math.MaxInt64 {
+		return 0
+	}
+	return int64(number)
+}
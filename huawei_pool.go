@@ -0,0 +1,125 @@
+package main
+
+type HuaweiTierCapacity struct {
+	Tier0Capacity int64 `json:"tier0Capacity"` // 高性能层(SSD)
+	Tier1Capacity int64 `json:"tier1Capacity"` // 性能层(SAS)
+	Tier2Capacity int64 `json:"tier2Capacity"` // 容量层(NL-SAS)
+}
+
+type HuaweiDiskPool struct {
+	Id            string `json:"id"`
+	Name          string `json:"name"`
+	HealthStatus  string `json:"healthStatus"`
+	RunningStatus string `json:"runningStatus"`
+
+	TotalCapacity     int64 `json:"totalCapacity"`     // 总容量(Byte)
+	UsedCapacity      int64 `json:"usedCapacity"`      // 已使用容量(Byte)
+	FreeCapacity      int64 `json:"freeCapacity"`      // 空闲容量(Byte)
+	SpareCapacity     int64 `json:"spareCapacity"`     // 热备容量(Byte)
+	UsedSpareCapacity int64 `json:"usedSpareCapacity"` // 已使用热备容量(Byte)
+
+	HuaweiTierCapacity
+}
+
+type HuaweiStoragePool struct {
+	Id            string `json:"id"`
+	Name          string `json:"name"`
+	DiskPoolId    string `json:"diskPoolId"` // 所属硬盘域ID
+	UsageType     string `json:"usageType"`  // 用途(LUN, FILESYSTEM)
+	HealthStatus  string `json:"healthStatus"`
+	RunningStatus string `json:"runningStatus"`
+
+	TotalCapacity      int64 `json:"totalCapacity"`      // 总容量(Byte)
+	FreeCapacity       int64 `json:"freeCapacity"`       // 空闲容量(Byte)
+	ConsumedCapacity   int64 `json:"consumedCapacity"`   // 已分配容量(Byte)
+	SubscribedCapacity int64 `json:"subscribedCapacity"` // 订阅容量(Byte)
+	ProtectionCapacity int64 `json:"protectionCapacity"` // 数据保护容量(Byte)
+
+	HuaweiTierCapacity
+
+	OvercommitRatio    float64 `json:"overcommitRatio"`    // 超分比例, 订阅容量/总容量
+	DataReductionRatio float64 `json:"dataReductionRatio"` // 数据缩减率, 缩减前容量/缩减后容量
+}
+
+func (c *Huawei) GetDiskPoolInfo() error {
+	c.Log.Debug("[REST]硬盘域信息")
+
+	items, err := c.RequestList("diskpool")
+	if err != nil {
+		c.Log.Errorf("[REST]请求硬盘域信息失败, error: %v", err)
+		return err
+	}
+	sectorSize := c.CrawlerData.SectorSize
+	for i := 0; i < len(items); i++ {
+		item := items[i]
+
+		pool := new(HuaweiDiskPool)
+		pool.Id = item.Get("ID").String()
+		pool.Name = item.Get("NAME").String()
+		pool.HealthStatus = HuaweiEnumName("HEALTH_STATUS_E", item.Get("HEALTHSTATUS").String())
+		pool.RunningStatus = HuaweiEnumName("RUNNING_STATUS_E", item.Get("RUNNINGSTATUS").String())
+
+		pool.TotalCapacity = item.Get("TOTALCAPACITY").Int() * sectorSize
+		pool.UsedCapacity = item.Get("USEDCAPACITY").Int() * sectorSize
+		pool.FreeCapacity = item.Get("FREECAPACITY").Int() * sectorSize
+		pool.SpareCapacity = item.Get("SPARECAPACITY").Int() * sectorSize
+		pool.UsedSpareCapacity = item.Get("USEDSPARECAPACITY").Int() * sectorSize
+
+		pool.Tier0Capacity = item.Get("TIER0CAPACITY").Int() * sectorSize
+		pool.Tier1Capacity = item.Get("TIER1CAPACITY").Int() * sectorSize
+		pool.Tier2Capacity = item.Get("TIER2CAPACITY").Int() * sectorSize
+
+		c.CrawlerData.DiskPoolInfo = append(c.CrawlerData.DiskPoolInfo, pool)
+	}
+	return nil
+}
+
+func (c *Huawei) GetStoragePoolInfo() error {
+	c.Log.Debug("[REST]存储池信息")
+
+	items, err := c.RequestList("storagepool")
+	if err != nil {
+		c.Log.Errorf("[REST]请求存储池信息失败, error: %v", err)
+		return err
+	}
+	sectorSize := c.CrawlerData.SectorSize
+	for i := 0; i < len(items); i++ {
+		item := items[i]
+
+		pool := new(HuaweiStoragePool)
+		pool.Id = item.Get("ID").String()
+		pool.Name = item.Get("NAME").String()
+		pool.DiskPoolId = item.Get("PARENTID").String()
+		pool.UsageType = HuaweiEnumName("STORAGEPOOL_USAGETYPE_E", item.Get("USAGETYPE").String())
+		pool.HealthStatus = HuaweiEnumName("HEALTH_STATUS_E", item.Get("HEALTHSTATUS").String())
+		pool.RunningStatus = HuaweiEnumName("RUNNING_STATUS_E", item.Get("RUNNINGSTATUS").String())
+
+		pool.TotalCapacity = item.Get("USERTOTALCAPACITY").Int() * sectorSize
+		pool.FreeCapacity = item.Get("USERFREECAPACITY").Int() * sectorSize
+		pool.ConsumedCapacity = item.Get("USERCONSUMEDCAPACITY").Int() * sectorSize
+		pool.ProtectionCapacity = item.Get("REPLICATIONCAPACITY").Int() * sectorSize
+		// LUN存储池的订阅容量为LUN配置容量, 文件系统存储池为文件系统总容量
+		switch pool.UsageType {
+		case "LUN":
+			pool.SubscribedCapacity = item.Get("LUNCONFIGEDCAPACITY").Int() * sectorSize
+		case "FILESYSTEM":
+			pool.SubscribedCapacity = item.Get("TOTALFSCAPACITY").Int() * sectorSize
+		}
+
+		pool.Tier0Capacity = item.Get("TIER0CAPACITY").Int() * sectorSize
+		pool.Tier1Capacity = item.Get("TIER1CAPACITY").Int() * sectorSize
+		pool.Tier2Capacity = item.Get("TIER2CAPACITY").Int() * sectorSize
+
+		if pool.TotalCapacity > 0 {
+			pool.OvercommitRatio = float64(pool.SubscribedCapacity) / float64(pool.TotalCapacity)
+		}
+		// 缩减前容量 = 已分配容量 + 重删节省容量 + 压缩节省容量
+		savedCapacity := (item.Get("DEDUPSAVEDCAPACITY").Int() + item.Get("COMPRESSIONSAVEDCAPACITY").Int()) * sectorSize
+		if pool.ConsumedCapacity > 0 {
+			pool.DataReductionRatio = float64(pool.ConsumedCapacity+savedCapacity) / float64(pool.ConsumedCapacity)
+		}
+
+		c.CrawlerData.StoragePoolInfo = append(c.CrawlerData.StoragePoolInfo, pool)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/tidwall/gjson"
+)
+
+type HuaweiReplication struct {
+	Type          string `json:"type"` // 类型(hypermetro_domain, hypermetro_pair, replication_pair, consistency_group, hypercopy_pair)
+	Id            string `json:"id"`
+	Name          string `json:"name"`
+	LocalObject   string `json:"localObject"`  // 本端对象
+	RemoteObject  string `json:"remoteObject"` // 远端对象
+	Group         string `json:"group"`        // 所属双活域或一致性组
+	Mode          string `json:"mode"`         // 复制模式(RM_MODEL_SYNC, RM_MODEL_ASYNC)
+	HealthStatus  string `json:"healthStatus"`
+	RunningStatus string `json:"runningStatus"`
+	LinkStatus    string `json:"linkStatus"`   // 链路状态(HyperCopy为HC_LINK_ON, HC_LINK_OFF, 其他为LINK_UP, LINK_DOWN)
+	SyncProgress  int64  `json:"syncProgress"` // 同步进度(%)
+	Broken        bool   `json:"broken"`       // 健康状态异常, 链路断开或同步中断
+}
+
+type huaweiReplicationIndex struct {
+	index       string // 查询路径
+	typ         string
+	healthEnum  string
+	runningEnum string
+	linkEnum    string
+}
+
+var huaweiReplicationIndexList = []huaweiReplicationIndex{
+	{"HyperMetroDomain", "hypermetro_domain", "HEALTH_STATUS_E", "RUNNING_STATUS_E", "RUNNING_STATUS_E"},
+	{"HyperMetroPair", "hypermetro_pair", "HEALTH_STATUS_E", "RUNNING_STATUS_E", "RUNNING_STATUS_E"},
+	{"REPLICATIONPAIR", "replication_pair", "HEALTH_STATUS_E", "RUNNING_STATUS_E", "RUNNING_STATUS_E"},
+	{"CONSISTENTGROUP", "consistency_group", "HEALTH_STATUS_E", "RUNNING_STATUS_E", "RUNNING_STATUS_E"},
+	{"HyperCopyPair", "hypercopy_pair", "HYPERCOPY_HEALTH_STATUS_E", "RUNNING_STATUS_E_HyperCopy", "HC_LINK_STATUS"},
+}
+
+func (c *Huawei) GetReplicationInfo() error {
+	for i := 0; i < len(huaweiReplicationIndexList); i++ {
+		index := huaweiReplicationIndexList[i]
+
+		c.Log.Debugf("[REST]复制特性信息[%s]", index.index)
+		items, err := c.RequestList(index.index)
+		if err != nil {
+			var huaweiErr *HuaweiError
+			if !errors.As(err, &huaweiErr) || huaweiErr.Code == HuaweiErrorUnauthorized {
+				// 网络错误或会话过期, 其他特性同样会失败
+				c.Log.Errorf("[REST]请求复制特性信息[%s]失败, error: %v", index.index, err)
+				return err
+			}
+			// 设备未开通(没有license)该特性时接口返回业务错误码, 不影响其他数据的采集
+			c.Log.Warnf("[REST]请求复制特性信息[%s]失败, 设备可能未开通该特性, error: %v", index.index, err)
+			continue
+		}
+		for j := 0; j < len(items); j++ {
+			item := items[j]
+
+			r := new(HuaweiReplication)
+			r.Type = index.typ
+			r.Id = item.Get("ID").String()
+			r.Name = item.Get("NAME").String()
+			r.LocalObject = firstHuaweiValue(item, "LOCALOBJNAME", "LOCALRESNAME")
+			r.RemoteObject = firstHuaweiValue(item, "REMOTEOBJNAME", "REMOTERESNAME")
+			r.Group = firstHuaweiValue(item, "DOMAINNAME", "CGNAME")
+			if mode := item.Get("REPLICATIONMODEL").String(); len(mode) > 0 {
+				r.Mode = HuaweiEnumName("RM_MODEL_E", mode)
+			}
+			health := item.Get("HEALTHSTATUS").String()
+			r.HealthStatus = HuaweiEnumName(index.healthEnum, health)
+			r.RunningStatus = HuaweiEnumName(index.runningEnum, item.Get("RUNNINGSTATUS").String())
+			if link := item.Get("LINKSTATUS").String(); len(link) > 0 {
+				r.LinkStatus = HuaweiEnumName(index.linkEnum, link)
+			}
+			r.SyncProgress = gjson.Parse(firstHuaweiValue(item, "SYNCPROGRESS", "REPLICATIONPROGRESS")).Int()
+
+			// 没有健康状态字段的对象只根据运行和链路状态判断
+			r.Broken = (len(health) > 0 && r.HealthStatus != "NORMAL") || r.LinkStatus == "HC_LINK_OFF" || r.LinkStatus == "LINK_DOWN" ||
+				r.RunningStatus == "INTERRUPTED" || r.RunningStatus == "INVALID"
+			if r.Broken {
+				c.Log.Warnf("[REST]复制特性状态异常[%s], ID: %s, 健康状态: %s, 运行状态: %s, 链路状态: %s",
+					r.Type, r.Id, r.HealthStatus, r.RunningStatus, r.LinkStatus)
+			}
+
+			c.CrawlerData.ReplicationInfo = append(c.CrawlerData.ReplicationInfo, r)
+		}
+	}
+	return nil
+}
+
+// firstHuaweiValue 不同对象表示相同含义的字段名称不同, 取第一个有值的字段
+func firstHuaweiValue(item gjson.Result, keys ...string) string {
+	for i := 0; i < len(keys); i++ {
+		if value := item.Get(keys[i]).String(); len(value) > 0 {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,461 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestHuaweiEnumName(t *testing.T) {
+	if name := HuaweiEnumName("LUN_ALLOC_TYPE_E", "1"); name != "THIN" {
+		t.Errorf("LUN分配类型转换错误, %s", name)
+	}
+	if name := HuaweiEnumName("HEALTH_STATUS_E", "999"); name != "999" {
+		t.Errorf("未定义的枚举值应保持原值, %s", name)
+	}
+}
+
+// newHuaweiTestCrawler 使用模拟接口创建采集任务
+func newHuaweiTestCrawler(t *testing.T, handler http.Handler) *Huawei {
+	server := httptest.NewTLSServer(handler)
+	t.Cleanup(server.Close)
+
+	c := new(Huawei)
+	c.Log = zap.NewNop().Sugar()
+	c.Host = server.URL
+	c.DeviceId = "2102351QLH9WK5800028"
+	c.AuthFile = t.TempDir() + "/huawei.cookie"
+	c.Limiter = NewRateLimiter(0)
+	c.CrawlerData = new(HuaweiCrawlerData)
+	c.CrawlerData.SectorSize = HuaweiDefaultSectorSize
+	return c
+}
+
+// huaweiRoutes 按请求路径(设备ID之后的部分)返回响应数据, 列表查询第二页及之后返回空列表
+func huaweiRoutes(routes map[string]string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path[strings.Index(r.URL.Path, "/rest/")+len("/rest/"):]
+		path = path[strings.Index(path, "/")+1:]
+		if strings.HasPrefix(r.URL.Query().Get("range"), "[0-") || len(r.URL.Query().Get("range")) == 0 {
+			if data, ok := routes[path]; ok {
+				_, _ = fmt.Fprint(w, data)
+				return
+			}
+		}
+		_, _ = fmt.Fprint(w, `{"data":[],"error":{"code":0}}`)
+	})
+}
+
+// startHuaweiStatisticServer 模拟性能指标接口, handle返回非0错误码时作为业务错误响应
+func startHuaweiStatisticServer(t *testing.T, handle func(uuidList []string) int64) (*Huawei, *[]string) {
+	var mutex sync.Mutex
+	requests := make([]string, 0)
+	c := newHuaweiTestCrawler(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uuidList := strings.Split(r.URL.Query().Get("CMO_STATISTIC_UUID"), ",")
+		mutex.Lock()
+		requests = append(requests, strings.Join(uuidList, ","))
+		mutex.Unlock()
+
+		if code := handle(uuidList); code != 0 {
+			_, _ = fmt.Fprintf(w, `{"data":{},"error":{"code":%d,"description":"error"}}`, code)
+			return
+		}
+		items := make([]string, len(uuidList))
+		for i := 0; i < len(uuidList); i++ {
+			items[i] = fmt.Sprintf(`{"CMO_STATISTIC_UUID":"%s","CMO_STATISTIC_DATA_LIST":"%d,10"}`, uuidList[i], i+1)
+		}
+		_, _ = fmt.Fprintf(w, `{"data":[%s],"error":{"code":0}}`, strings.Join(items, ","))
+	}))
+	c.Config.StatisticBatchSize = 2
+	c.Config.StatisticParallel = 2
+	c.statisticVStore = map[string]string{}
+	return c, &requests
+}
+
+func huaweiStatisticUuids(c *Huawei) []string {
+	uuids := make([]string, 0)
+	for i := 0; i < len(c.CrawlerData.StatisticInfo); i++ {
+		uuids = append(uuids, c.CrawlerData.StatisticInfo[i].Uuid)
+	}
+	sort.Strings(uuids)
+	return uuids
+}
+
+func TestHuawei_StatisticBatch(t *testing.T) {
+	c, requests := startHuaweiStatisticServer(t, func(uuidList []string) int64 { return 0 })
+	uuidList := []string{"11:1", "11:2", "11:3", "11:4", "11:5"}
+	if err := c.GetStatistic("lun", uuidList, "22,25"); err != nil {
+		t.Fatalf("查询指标失败, error: %v", err)
+	}
+	if len(*requests) != 3 || c.statisticBatchDisabled {
+		t.Errorf("批量查询请求错误, %v", *requests)
+	}
+	if uuids := huaweiStatisticUuids(c); strings.Join(uuids, ",") != strings.Join(uuidList, ",") {
+		t.Errorf("指标对象错误, %v", uuids)
+	}
+	for i := 0; i < len(c.CrawlerData.StatisticInfo); i++ {
+		if c.CrawlerData.StatisticInfo[i].Data["25"] != 10 {
+			t.Errorf("指标值错误, %+v", c.CrawlerData.StatisticInfo[i])
+		}
+	}
+}
+
+func TestHuawei_StatisticFallback(t *testing.T) {
+	// 设备拒绝批量查询, 后续全部逐个查询
+	c, requests := startHuaweiStatisticServer(t, func(uuidList []string) int64 {
+		if len(uuidList) > 1 {
+			return 50331651
+		}
+		return 0
+	})
+	c.Config.StatisticParallel = 1
+	if err := c.GetStatistic("lun", []string{"11:1", "11:2", "11:3", "11:4"}, "22,25"); err != nil {
+		t.Fatalf("查询指标失败, error: %v", err)
+	}
+	if !c.statisticBatchDisabled || len(*requests) != 5 || len(c.CrawlerData.StatisticInfo) != 4 {
+		t.Errorf("降级查询错误, %v, %d", *requests, len(c.CrawlerData.StatisticInfo))
+	}
+
+	// 批次中有对象不存在, 只对该批次逐个查询并跳过失败的对象
+	c, requests = startHuaweiStatisticServer(t, func(uuidList []string) int64 {
+		for i := 0; i < len(uuidList); i++ {
+			if uuidList[i] == "11:2" {
+				return 1077948996
+			}
+		}
+		return 0
+	})
+	if err := c.GetStatistic("lun", []string{"11:1", "11:2", "11:3", "11:4"}, "22,25"); err != nil {
+		t.Fatalf("查询指标失败, error: %v", err)
+	}
+	if c.statisticBatchDisabled || len(*requests) != 4 {
+		t.Errorf("单个对象失败不应禁用批量查询, %v", *requests)
+	}
+	if uuids := huaweiStatisticUuids(c); strings.Join(uuids, ",") != "11:1,11:3,11:4" {
+		t.Errorf("指标对象错误, %v", uuids)
+	}
+
+	// 会话过期时返回错误, 不禁用批量查询
+	c, _ = startHuaweiStatisticServer(t, func(uuidList []string) int64 { return -401 })
+	if err := c.GetStatistic("lun", []string{"11:1", "11:2"}, "22,25"); err == nil || c.statisticBatchDisabled {
+		t.Errorf("会话过期应返回错误, error: %v", err)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	start := time.Now()
+	l := NewRateLimiter(20)
+	for i := 0; i < 4; i++ {
+		l.Wait()
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("请求频率未限制, %v", elapsed)
+	}
+
+	// 不限制
+	start = time.Now()
+	var nilLimiter *RateLimiter
+	for i := 0; i < 100; i++ {
+		NewRateLimiter(0).Wait()
+		nilLimiter.Wait()
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("不限制时不应等待, %v", elapsed)
+	}
+}
+
+func TestHuawei_ParseLocation(t *testing.T) {
+	if enclosure, slot := parseHuaweiLocation("DAE000.5"); enclosure != "DAE000" || slot != "5" {
+		t.Errorf("位置信息解析错误, %s, %s", enclosure, slot)
+	}
+	if enclosure, slot := parseHuaweiLocation("CTE0"); enclosure != "CTE0" || slot != "" {
+		t.Errorf("位置信息解析错误, %s, %s", enclosure, slot)
+	}
+}
+
+func TestHuawei_GetAlarmInfo(t *testing.T) {
+	routes := map[string]string{
+		"alarm/currentalarm": `{"data":[{"sequence":"101","eventID":"0xF00170001","name":"Disk fault","level":"6",
+			"description":"disk fault","location":"CTE0.5","startTime":"1700000000"}],"error":{"code":0}}`,
+	}
+	c := newHuaweiTestCrawler(t, huaweiRoutes(routes))
+	dir := t.TempDir()
+	c.EventFile = dir + "/event.json"
+	c.AlarmFile = dir + "/alarm.json"
+
+	// 首次采集只保存告警状态
+	if err := c.GetAlarmInfo(); err != nil {
+		t.Fatal(err)
+	}
+	if c.CrawlerData.AlarmCount["CRITICAL"] != 1 {
+		t.Errorf("告警数量错误, %v", c.CrawlerData.AlarmCount)
+	}
+	if data, _ := ioutil.ReadFile(c.EventFile); len(data) != 0 {
+		t.Errorf("首次采集不应生成告警事件, %s", data)
+	}
+
+	// 新告警产生, 原告警恢复
+	routes["alarm/currentalarm"] = `{"data":[{"sequence":"102","eventID":"0xF00CF0001","name":"Link down","level":"5",
+		"description":"link down","location":"CTE0.A.IOM0.P0","startTime":"1700000600"}],"error":{"code":0}}`
+	c.CrawlerData = new(HuaweiCrawlerData)
+	if err := c.GetAlarmInfo(); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadFile(c.EventFile)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("告警事件数量错误, %s", data)
+	}
+	types := make(map[string]string)
+	for i := 0; i < len(lines); i++ {
+		event := new(Event)
+		if err := json.Unmarshal([]byte(lines[i]), event); err != nil {
+			t.Fatal(err)
+		}
+		types[event.Id] = event.Type
+	}
+	if types["0xF00CF0001"] != EventAlarmRaised || types["0xF00170001"] != EventAlarmCleared {
+		t.Errorf("告警事件类型错误, %v", types)
+	}
+
+	// 重启后使用告警状态文件, 告警未变化时不生成事件
+	restarted := newHuaweiTestCrawler(t, huaweiRoutes(routes))
+	restarted.EventFile = c.EventFile
+	restarted.AlarmFile = c.AlarmFile
+	if err := restarted.GetAlarmInfo(); err != nil {
+		t.Fatal(err)
+	}
+	if after, _ := ioutil.ReadFile(c.EventFile); len(after) != len(data) {
+		t.Errorf("重启后告警未变化不应生成事件, %s", after)
+	}
+}
+
+func TestHuawei_SumSystemCapacity(t *testing.T) {
+	c := new(Huawei)
+	c.CrawlerData = new(HuaweiCrawlerData)
+	c.CrawlerData.MemberDiskCapacity = 1000
+	c.CrawlerData.FreeDiskCapacity = 200
+	c.CrawlerData.DiskPoolInfo = []*HuaweiDiskPool{{FreeCapacity: 100}}
+	c.CrawlerData.StoragePoolInfo = []*HuaweiStoragePool{
+		{UsageType: "LUN", ConsumedCapacity: 300, FreeCapacity: 50, ProtectionCapacity: 10, SubscribedCapacity: 800},
+		{UsageType: "FILESYSTEM", ConsumedCapacity: 200, FreeCapacity: 40, SubscribedCapacity: 400},
+	}
+	c.SumSystemCapacity()
+
+	data := c.CrawlerData
+	if data.SystemCapacity != 1200 || data.SystemUsedCapacity != 900 {
+		t.Errorf("系统容量计算错误, %d, %d", data.SystemCapacity, data.SystemUsedCapacity)
+	}
+	if data.LunCapacity != 300 || data.FilesystemCapacity != 200 || data.TotalCapacity != 1200 {
+		t.Errorf("存储池容量汇总错误, %d, %d, %d", data.LunCapacity, data.FilesystemCapacity, data.TotalCapacity)
+	}
+	if data.UsableCapacity != 600 {
+		t.Errorf("总可用容量计算错误, %d", data.UsableCapacity)
+	}
+}
+
+func TestHuawei_VStoreLoginScope(t *testing.T) {
+	routes := map[string]string{
+		"vstore":           `{"data":{},"error":{"code":1077949058,"description":"no permission"}}`,
+		"lun/count":        `{"data":{"COUNT":"3"},"error":{"code":0}}`,
+		"filesystem/count": `{"data":{"COUNT":"2"},"error":{"code":0}}`,
+	}
+	c := newHuaweiTestCrawler(t, huaweiRoutes(routes))
+
+	// 系统管理员登录时返回错误
+	if err := c.GetVStoreInfo(); err == nil {
+		t.Errorf("查询租户列表失败时应返回错误")
+	}
+
+	// 租户管理员登录时使用登录的租户
+	c.Config.VStoreName = "vstore_finance"
+	c.CrawlerData.VStoreInfo = nil
+	if err := c.GetVStoreInfo(); err != nil {
+		t.Fatalf("查询租户信息失败, error: %v", err)
+	}
+	if len(c.CrawlerData.VStoreInfo) != 1 {
+		t.Fatalf("租户数量错误, %d", len(c.CrawlerData.VStoreInfo))
+	}
+	if v := c.CrawlerData.VStoreInfo[0]; v.Name != "vstore_finance" || v.LunCount != 3 || v.FilesystemCount != 2 {
+		t.Errorf("租户信息错误, %+v", v)
+	}
+	if name := c.VStoreName("1"); name != "vstore_finance" {
+		t.Errorf("对象所属租户错误, %s", name)
+	}
+}
+
+func TestHuawei_FilesystemAndQuota(t *testing.T) {
+	routes := map[string]string{
+		"filesystem": `{"data":[{"ID":"5","NAME":"fs_home","vstoreId":"","PARENTID":"0","PARENTNAME":"StoragePool001",
+			"ALLOCTYPE":"1","HEALTHSTATUS":"1","RUNNINGSTATUS":"27","CAPACITY":"2097152","AVAILABLECAPCITY":"1572864",
+			"SNAPSHOTRESERVEPER":"20","SNAPSHOTUSECAPACITY":"2048"}],"error":{"code":0}}`,
+		"quotatree": `{"data":[{"ID":"5@4097","NAME":"qt_dev","PARENTID":"5"}],"error":{"code":0}}`,
+		"FS_QUOTA": `{"data":[
+			{"ID":"5@2@4097","QUOTATYPE":"2","SPACEUSED":"1","SPACEHARDQUOTA":"1"},
+			{"ID":"5@1@4097","QUOTATYPE":"1","SPACEUSED":"262144","SPACESOFTQUOTA":"18446744073709551615",
+				"SPACEHARDQUOTA":"1048576","FILEUSED":"1200","FILESOFTQUOTA":"18446744073709551615","FILEHARDQUOTA":"10000"}
+		],"error":{"code":0}}`,
+		"NFSHARE":  `{"data":[{"ID":"1","NAME":"/fs_home/","SHAREPATH":"/fs_home/","FSID":"5","vstoreId":""}],"error":{"code":0}}`,
+		"CIFSHARE": `{"data":[{"ID":"2","NAME":"home","SHAREPATH":"/fs_home/","FSID":"5","vstoreId":""}],"error":{"code":0}}`,
+	}
+	c := newHuaweiTestCrawler(t, huaweiRoutes(routes))
+	if err := c.GetFilesystemInfo(); err != nil {
+		t.Fatalf("查询文件系统失败, error: %v", err)
+	}
+	if err := c.GetShareInfo(); err != nil {
+		t.Fatalf("查询共享失败, error: %v", err)
+	}
+
+	data := c.CrawlerData
+	if len(data.FilesystemInfo) != 1 {
+		t.Fatalf("文件系统数量错误, %d", len(data.FilesystemInfo))
+	}
+	// 文件系统容量以扇区为单位
+	if fs := data.FilesystemInfo[0]; fs.Capacity != 1<<30 || fs.UsedCapacity != 256<<20 || fs.SnapshotUsedCapacity != 1<<20 ||
+		fs.AllocType != "THIN" || fs.PoolName != "StoragePool001" {
+		t.Errorf("文件系统信息错误, %+v", fs)
+	}
+	// 配额以KB为单位, 只取目录配额
+	if len(data.QuotaTreeInfo) != 1 {
+		t.Fatalf("配额树数量错误, %d", len(data.QuotaTreeInfo))
+	}
+	if tree := data.QuotaTreeInfo[0]; tree.SpaceUsed != 256<<20 || tree.SpaceHardQuota != 1<<30 || tree.SpaceSoftQuota != 0 ||
+		tree.FileUsed != 1200 || tree.FileHardQuota != 10000 || tree.FileSoftQuota != 0 || tree.FilesystemId != "5" {
+		t.Errorf("配额树信息错误, %+v", tree)
+	}
+	if len(data.ShareInfo) != 2 || data.ShareInfo[0].Protocol != "NFS" || data.ShareInfo[1].Protocol != "CIFS" ||
+		data.ShareInfo[1].FilesystemId != "5" {
+		t.Errorf("共享信息错误, %+v", data.ShareInfo)
+	}
+
+	if unit := huaweiQuotaUnit("4"); unit != 1<<30 {
+		t.Errorf("配额单位转换错误, %d", unit)
+	}
+	if unit := huaweiQuotaUnit(""); unit != 1<<10 {
+		t.Errorf("配额默认单位错误, %d", unit)
+	}
+}
+
+func TestHuawei_ReplicationLinkStatus(t *testing.T) {
+	routes := map[string]string{
+		"HyperMetroPair":  `{"data":[{"ID":"1","HEALTHSTATUS":"1","RUNNINGSTATUS":"1","LINKSTATUS":"11","LOCALOBJNAME":"lun01"}],"error":{"code":0}}`,
+		"HyperCopyPair":   `{"data":[{"ID":"2","HEALTHSTATUS":"0","RUNNINGSTATUS":"2","LINKSTATUS":"1"}],"error":{"code":0}}`,
+		"CONSISTENTGROUP": `{"data":[{"ID":"3","RUNNINGSTATUS":"1"}],"error":{"code":0}}`,
+		// 未开通远程复制特性
+		"REPLICATIONPAIR": `{"data":{},"error":{"code":1077949058,"description":"not licensed"}}`,
+	}
+	c := newHuaweiTestCrawler(t, huaweiRoutes(routes))
+	if err := c.GetReplicationInfo(); err != nil {
+		t.Fatalf("查询复制特性失败, error: %v", err)
+	}
+	items := make(map[string]*HuaweiReplication)
+	for i := 0; i < len(c.CrawlerData.ReplicationInfo); i++ {
+		items[c.CrawlerData.ReplicationInfo[i].Type] = c.CrawlerData.ReplicationInfo[i]
+	}
+	if r := items["hypermetro_pair"]; r == nil || r.LinkStatus != "LINK_DOWN" || !r.Broken {
+		t.Errorf("双活链路状态错误, %+v", r)
+	}
+	if r := items["hypercopy_pair"]; r == nil || r.LinkStatus != "HC_LINK_ON" || r.HealthStatus != "NORMAL" || r.Broken {
+		t.Errorf("HyperCopy链路状态错误, %+v", r)
+	}
+	if r := items["consistency_group"]; r == nil || r.Broken {
+		t.Errorf("没有健康状态时不应判断为异常, %+v", r)
+	}
+
+	// 会话过期时返回错误
+	routes["REPLICATIONPAIR"] = `{"data":{},"error":{"code":-401}}`
+	c.CrawlerData = new(HuaweiCrawlerData)
+	if err := c.GetReplicationInfo(); err == nil {
+		t.Errorf("会话过期时应返回错误")
+	}
+}
+
+func TestHuawei_ParseHistory(t *testing.T) {
+	data := `{"data":[
+		{"CMO_STATISTIC_TIMESTAMP":1631808000,"CMO_STATISTIC_DATA_LIST":"100,60"},
+		{"CMO_STATISTIC_TIMESTAMP":1631808300,"CMO_STATISTIC_DATA_LIST":"120,"}
+	],"error":{"code":0}}`
+
+	samples := parseHuaweiHistory("lun", []string{"11:1"}, map[string]string{"11:1": "vs1"}, "22,25", data)
+	if len(samples) != 3 {
+		t.Fatalf("采样点数量错误, %d", len(samples))
+	}
+	if s := samples[0]; s.Metric != "huawei_lun_totalIOPS" || s.Value != 100 || s.Timestamp != 1631808000 {
+		t.Errorf("采样点解析错误, %+v", s)
+	}
+	if s := samples[2]; s.Labels["vstore"] != "vs1" || s.Timestamp != 1631808300 {
+		t.Errorf("采样点解析错误, %+v", s)
+	}
+	if name := huaweiStatisticDataName("90001"); name != "data_90001" {
+		t.Errorf("未定义的指标名称错误, %s", name)
+	}
+}
+
+func TestHuawei_Backfill(t *testing.T) {
+	timestamp := time.Now().Unix() - 600
+	var mutex sync.Mutex
+	historyRequests := 0
+	routes := huaweiRoutes(map[string]string{
+		"lun":  `{"data":[{"TYPE":"11","ID":"1"},{"TYPE":"11","ID":"2"},{"TYPE":"11","ID":"3"}],"error":{"code":0}}`,
+		"disk": `{"data":{},"error":{"code":1077948996,"description":"error"}}`,
+	})
+	c := newHuaweiTestCrawler(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/history_statistic_data") {
+			routes.ServeHTTP(w, r)
+			return
+		}
+		mutex.Lock()
+		historyRequests++
+		mutex.Unlock()
+		uuidList := strings.Split(r.URL.Query().Get("CMO_STATISTIC_UUID"), ",")
+		points := make([]string, 0)
+		for i := 0; i < len(uuidList); i++ {
+			if uuidList[i] == "11:2" {
+				// 批次中有对象失败时逐个查询并跳过该对象
+				_, _ = fmt.Fprint(w, `{"data":{},"error":{"code":1077948996,"description":"error"}}`)
+				return
+			}
+			for j := int64(0); j < 2; j++ {
+				points = append(points, fmt.Sprintf(`{"CMO_STATISTIC_UUID":"%s","CMO_STATISTIC_TIMESTAMP":%d,"CMO_STATISTIC_DATA_LIST":"%d,1,1,1,1,1"}`,
+					uuidList[i], timestamp+j*300, i))
+			}
+		}
+		_, _ = fmt.Fprintf(w, `{"data":[%s],"error":{"code":0}}`, strings.Join(points, ","))
+	}))
+	c.Config.StatisticBatchSize = 20
+	c.Config.StatisticParallel = 2
+	c.Sink = NewFileSink(t.TempDir() + "/timeseries.json")
+
+	// 硬盘列表查询失败不影响其他对象类型
+	if err := c.Backfill(time.Hour); err == nil {
+		t.Errorf("对象类型查询失败时应返回错误")
+	}
+	// 批量查询1次, 逐个查询3次
+	if historyRequests != 4 {
+		t.Errorf("历史性能数据请求次数错误, %d", historyRequests)
+	}
+	lines, _ := ioutil.ReadFile(c.Sink.(*FileSink).Path)
+	if count := strings.Count(string(lines), "\n"); count != 2*2*6 {
+		t.Errorf("补录采样点数量错误, %d", count)
+	}
+	if !strings.Contains(string(lines), `"metric":"huawei_lun_maxIOPS","labels":{"uuid":"11:3","vstore":""}`) ||
+		strings.Contains(string(lines), `"uuid":"11:2"`) {
+		t.Errorf("补录数据错误, %s", lines)
+	}
+
+	// 重复补录不写入已有的采样点
+	_ = c.Backfill(time.Hour)
+	again, _ := ioutil.ReadFile(c.Sink.(*FileSink).Path)
+	if len(again) != len(lines) {
+		t.Errorf("重复补录写入了重复的采样点, %d, %d", len(lines), len(again))
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+type HuaweiVStore struct {
+	Id            string `json:"id"`
+	Name          string `json:"name"`
+	RunningStatus string `json:"runningStatus"`
+
+	SanCapacityQuota int64 `json:"sanCapacityQuota"` // SAN容量配额(Byte)
+	SanFreeCapacity  int64 `json:"sanFreeCapacity"`  // SAN剩余配额(Byte)
+	NasCapacityQuota int64 `json:"nasCapacityQuota"` // NAS容量配额(Byte)
+	NasFreeCapacity  int64 `json:"nasFreeCapacity"`  // NAS剩余配额(Byte)
+
+	LunCount        int64 `json:"lunCount"`
+	FilesystemCount int64 `json:"filesystemCount"`
+}
+
+func (c *Huawei) GetVStoreInfo() error {
+	c.Log.Debug("[REST]租户信息")
+
+	c.vstoreNames = make(map[string]string)
+	c.vstoreLoginScope = false
+	items, err := c.RequestList("vstore")
+	if err != nil {
+		var huaweiErr *HuaweiError
+		if len(c.Config.VStoreName) > 0 && errors.As(err, &huaweiErr) && huaweiErr.Code != HuaweiErrorUnauthorized {
+			// 租户管理员没有查询租户列表的权限, 登录的租户即为唯一的租户
+			c.Log.Warnf("[REST]租户管理员无法查询租户列表, 使用登录的租户[%s], error: %v", c.Config.VStoreName, err)
+			return c.getLoginVStoreInfo()
+		}
+		c.Log.Errorf("[REST]请求租户信息失败, error: %v", err)
+		return err
+	}
+	sectorSize := c.CrawlerData.SectorSize
+	for i := 0; i < len(items); i++ {
+		item := items[i]
+
+		vstore := new(HuaweiVStore)
+		vstore.Id = item.Get("ID").String()
+		vstore.Name = item.Get("NAME").String()
+		vstore.RunningStatus = HuaweiEnumName("RUNNING_STATUS_E", item.Get("RUNNINGSTATUS").String())
+		// 设备接口字段名称即为QUTOA
+		vstore.SanCapacityQuota = item.Get("SANCAPACITYQUTOA").Int() * sectorSize
+		vstore.SanFreeCapacity = item.Get("SANFREECAPACITYQUTOA").Int() * sectorSize
+		vstore.NasCapacityQuota = item.Get("NASCAPACITYQUOTA").Int() * sectorSize
+		vstore.NasFreeCapacity = item.Get("NASFREECAPACITYQUOTA").Int() * sectorSize
+
+		// LUN和文件系统数量
+		if vstore.LunCount, err = c.requestVStoreCount("lun", vstore.Id); err != nil {
+			c.Log.Errorf("[REST]请求租户[%s]的LUN数量失败, error: %v", vstore.Name, err)
+			return err
+		}
+		if vstore.FilesystemCount, err = c.requestVStoreCount("filesystem", vstore.Id); err != nil {
+			c.Log.Errorf("[REST]请求租户[%s]的文件系统数量失败, error: %v", vstore.Name, err)
+			return err
+		}
+
+		c.vstoreNames[vstore.Id] = vstore.Name
+		c.CrawlerData.VStoreInfo = append(c.CrawlerData.VStoreInfo, vstore)
+	}
+	return nil
+}
+
+// getLoginVStoreInfo 租户管理员登录时, 只统计登录租户的LUN和文件系统数量, 容量配额无法查询
+func (c *Huawei) getLoginVStoreInfo() error {
+	c.vstoreLoginScope = true
+
+	var err error
+	vstore := new(HuaweiVStore)
+	vstore.Name = c.Config.VStoreName
+	if vstore.LunCount, err = c.requestVStoreCount("lun", ""); err != nil {
+		c.Log.Errorf("[REST]请求租户[%s]的LUN数量失败, error: %v", vstore.Name, err)
+		return err
+	}
+	if vstore.FilesystemCount, err = c.requestVStoreCount("filesystem", ""); err != nil {
+		c.Log.Errorf("[REST]请求租户[%s]的文件系统数量失败, error: %v", vstore.Name, err)
+		return err
+	}
+	c.CrawlerData.VStoreInfo = append(c.CrawlerData.VStoreInfo, vstore)
+	return nil
+}
+
+// VStoreName 根据租户ID获取租户名称, 未指定租户时为系统租户, 租户管理员登录时均为登录的租户
+func (c *Huawei) VStoreName(id string) string {
+	if c.vstoreLoginScope {
+		return c.Config.VStoreName
+	}
+	if len(id) == 0 {
+		return ""
+	}
+	if name, ok := c.vstoreNames[id]; ok {
+		return name
+	}
+	return id
+}
+
+// requestVStoreCount 查询租户的对象数量, vstoreId为空时为登录范围内的数量
+func (c *Huawei) requestVStoreCount(index, vstoreId string) (int64, error) {
+	requestUrl := fmt.Sprintf("%s/deviceManager/rest/%s/%s/count?t=%d",
+		c.Host, c.DeviceId, index, time.Now().UnixNano()/1e6)
+	if len(vstoreId) > 0 {
+		requestUrl += "&vstoreId=" + vstoreId
+	}
+	data, err := c.RequestJson("GET", requestUrl, nil)
+	if err != nil {
+		return 0, err
+	}
+	return gjson.Get(data, "data.COUNT").Int(), nil
+}
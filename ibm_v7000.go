@@ -13,6 +13,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -27,6 +28,32 @@ var (
 )
 
 type IbmV7000CrawlerData struct {
+	System       *IbmClusterSystem     `json:"system"`
+	Pools        []*IbmPool            `json:"pools"`
+	ClusterStats []*IbmStat            `json:"clusterStats"` // 系统实时性能
+	NodeStats    map[string][]*IbmStat `json:"nodeStats"`    // 节点实时性能, 按节点ID
+	Hosts        []*IbmHost            `json:"hosts"`
+	DriveClasses []*IbmDriveClass      `json:"driveClasses"`
+	Drives       []*IbmDrive           `json:"drives"`
+	Volumes      []*IbmVolume          `json:"volumes"`
+	Health       *IbmSystemHealth      `json:"health"`
+	Events       []*IbmEvent           `json:"events"` // 未修复的告警
+
+	// 复制服务和主机映射, 只有CLI命令(REST接口或SSH)采集时才有, 复制服务需要开启copy_services
+	FlashCopyMappings []*IbmFlashCopyMapping `json:"flashCopyMappings"`
+	FlashCopyGroups   []*IbmFlashCopyGroup   `json:"flashCopyGroups"`
+	RemoteCopies      []*IbmRemoteCopy       `json:"remoteCopies"`
+	RemoteCopyGroups  []*IbmRemoteCopyGroup  `json:"remoteCopyGroups"`
+	HostMappings      []*IbmHostMapping      `json:"hostMappings"`
+
+	HostStatusCount  map[string]int64 `json:"hostStatusCount"`  // 各状态的主机数量
+	DriveStatusCount map[string]int64 `json:"driveStatusCount"` // 各状态的磁盘数量
+
+	FlashCopyStatusCount map[string]int64 `json:"flashCopyStatusCount"` // 各状态的FlashCopy映射数量
+	RemoteCopyStateCount map[string]int64 `json:"remoteCopyStateCount"` // 各状态的远程复制关系数量
+
+	ComponentStatusCount map[string]map[string]int64 `json:"componentStatusCount"` // 各类组件各状态的数量
+	UnfixedEventCount    int64                       `json:"unfixedEventCount"`
 }
 
 func (h *IbmV7000CrawlerData) PrintFile(path string) {
@@ -46,15 +73,28 @@ type IbmV7000 struct {
 	AuthFile   string
 	AuthCookie string
 
+	AlarmFile      string // 上次采集的未修复告警
+	EventFile      string
+	TimeSeriesFile string
+
 	Host string
 
 	Username string
 	Password string
 
+	Sink TimeSeriesSink
+
+	Commander    IbmCommander // 使用REST接口或SSH采集时不为空
+	CopyServices bool         // 采集复制服务
+
 	CrawlerData *IbmV7000CrawlerData
 }
 
-func NewIbmV7000Crawler() (*IbmV7000, error) {
+func NewIbmV7000Crawler(conf IbmConfig) (*IbmV7000, error) {
+	if conf.CopyServices && conf.Transport != "rest" && conf.Transport != "ssh" {
+		return nil, fmt.Errorf("采集方式[%s]不支持采集复制服务, copy_services需要使用rest或ssh方式", conf.Transport)
+	}
+
 	c := new(IbmV7000)
 
 	logger, err := NewLogger("ibm_v7000.log")
@@ -65,11 +105,35 @@ func NewIbmV7000Crawler() (*IbmV7000, error) {
 
 	c.AuthFile = "cookie/ibm_v7000.cookie"
 
+	c.AlarmFile = "data/ibm_v7000_alarm.json"
+	c.EventFile = "data/ibm_v7000_event.json"
+	c.TimeSeriesFile = "data/ibm_v7000_timeseries.json"
+
 	c.Host = "https://7.3.20.15"
 
 	c.Username = IbmAccount
 	c.Password = IbmPassword
 
+	c.Sink = NewFileSink(c.TimeSeriesFile)
+
+	switch conf.Transport {
+	case "rest":
+		address := conf.RestAddress
+		if len(address) == 0 {
+			address = c.Host + ":7443"
+		}
+		rest := NewIbmRestClient(address, c.Username, c.Password)
+		rest.TokenFile = "cookie/ibm_v7000_rest.token"
+		c.Commander = rest
+	case "ssh":
+		address := conf.SshAddress
+		if len(address) == 0 {
+			address = strings.TrimPrefix(c.Host, "https://") + ":22"
+		}
+		c.Commander = NewIbmShell(address, c.Username, c.Password)
+	}
+	c.CopyServices = conf.CopyServices
+
 	c.CrawlerData = new(IbmV7000CrawlerData)
 
 	return c, nil
@@ -82,6 +146,24 @@ func (c *IbmV7000) Debug() {
 func (c *IbmV7000) Start() {
 	c.Log.Debug("抓取IBM存储设备信息")
 
+	if c.Commander != nil {
+		if err := c.StartCommand(); err != nil {
+			return
+		}
+	} else {
+		if err := c.StartRPC(); err != nil {
+			return
+		}
+	}
+
+	c.CrawlerData.CountStatus()
+	c.CrawlerData.CountHealthStatus()
+	c.CrawlerData.CountCopyStatus()
+	c.CrawlerData.PrintFile("ibm_v7000_text.txt")
+}
+
+// StartRPC 通过管理界面的RPC接口采集
+func (c *IbmV7000) StartRPC() error {
 	// 验证授权信息
 	if isExist(c.AuthFile) {
 		c.Log.Debug("检查到授权信息文件")
@@ -89,7 +171,7 @@ func (c *IbmV7000) Start() {
 			c.Log.Errorf("读取授权信息文件失败, 需要重新登陆, error: %v", err)
 			if err := c.Login(); err != nil {
 				c.Log.Errorf("登陆失败, 请重试, error: %v", err)
-				return
+				return err
 			}
 		} else {
 			// 需要判断授权是否过期
@@ -104,38 +186,51 @@ func (c *IbmV7000) Start() {
 		c.Log.Debug("未检查到授权信息文件, 需要执行登陆操作")
 		if err := c.Login(); err != nil {
 			c.Log.Errorf("登陆失败, 请重试, error: %v", err)
-			return
+			return err
 		}
 	}
 
 	// 获取物理池状态
 	if err := c.GetMonitorSystem(); err != nil {
-		return
+		return err
 	}
 	// 获取物理池状态
 	if err := c.GetPhysicalPools(); err != nil {
-		return
+		return err
+	}
+	// 获取系统健康数据, 节点性能按其中的节点查询
+	if err := c.GetSystemHealth(); err != nil {
+		return err
 	}
 	// 获取系统状态（实时）
 	if err := c.GetClusterStates(); err != nil {
-		return
+		return err
 	}
 	// 获取节点状态（实时）
 	if err := c.GetNodeStates(); err != nil {
-		return
+		return err
+	}
+	// 写入系统和节点的性能数据
+	if err := c.WriteStatSamples(); err != nil {
+		return err
 	}
 	// 获取主机集群状态
 	if err := c.GetHosts(); err != nil {
-		return
+		return err
 	}
 	// 获取内部存储器（磁盘）状态
 	if err := c.GetPhysicalInternal(); err != nil {
-		return
+		return err
 	}
 	// 获取卷状态
 	if err := c.GetVolumes(); err != nil {
-		return
+		return err
 	}
+	// 获取机柜, 电源和电池状态, 失败时跳过
+	c.GetEnclosureHealth()
+	// 获取未修复的告警, 失败时不影响已采集的数据
+	_ = c.GetEventLog()
+	return nil
 }
 
 func (c *IbmV7000) Login() error {
@@ -235,6 +330,29 @@ func (c *IbmV7000) PostRPC(params io.Reader) (string, error) {
 	}
 }
 
+// RequestRPC 调用RPC方法并解析结果, methodClazz如 com.ibm.svc.gui.logic.PhysicalRPC
+func (c *IbmV7000) RequestRPC(methodClazz, methodName string, methodArgs []interface{}, result interface{}) error {
+	if methodArgs == nil {
+		methodArgs = []interface{}{}
+	}
+	params := map[string]interface{}{
+		"clazz":       "com.ibm.evo.rpc.RPCRequest",
+		"methodArgs":  methodArgs,
+		"methodClazz": methodClazz,
+		"methodName":  methodName,
+	}
+	paramsJson, err := json.Marshal(params)
+	if err != nil {
+		c.Log.Errorf("JSON序列化出错, %v, error: %v", params, err)
+		return err
+	}
+	data, err := c.PostRPC(bytes.NewReader(paramsJson))
+	if err != nil {
+		return err
+	}
+	return ParseIbmRPCResult(data, result)
+}
+
 func (c *IbmV7000) GetMonitorSystem() error {
 	c.Log.Debug("[RPC]获取系统状态")
 
@@ -255,7 +373,12 @@ func (c *IbmV7000) GetMonitorSystem() error {
 		c.Log.Errorf("[RPC]获取系统状态信息失败, error: %v", err)
 		return err
 	} else {
-		c.Log.Debugf("[RPC]获取系统状态信息结果, %s", data)
+		system := new(IbmClusterSystem)
+		if err := ParseIbmRPCResult(data, system); err != nil {
+			c.Log.Errorf("[RPC]解析系统状态信息失败, error: %v", err)
+			return err
+		}
+		c.CrawlerData.System = system
 		return nil
 	}
 }
@@ -280,7 +403,10 @@ func (c *IbmV7000) GetPhysicalPools() error {
 		c.Log.Errorf("[RPC]获取物理池状态失败, error: %v", err)
 		return err
 	} else {
-		c.Log.Debugf("[RPC]获取物理池状态结果, %s", data)
+		if err := ParseIbmRPCResult(data, &c.CrawlerData.Pools); err != nil {
+			c.Log.Errorf("[RPC]解析物理池状态失败, error: %v", err)
+			return err
+		}
 		return nil
 	}
 }
@@ -305,7 +431,10 @@ func (c *IbmV7000) GetClusterStates() error {
 		c.Log.Errorf("[RPC]获取系统状态失败, error: %v", err)
 		return err
 	} else {
-		c.Log.Debugf("[RPC]获取系统状态结果, %s", data)
+		if err := ParseIbmRPCResult(data, &c.CrawlerData.ClusterStats); err != nil {
+			c.Log.Errorf("[RPC]解析系统状态失败, error: %v", err)
+			return err
+		}
 		return nil
 	}
 }
@@ -313,27 +442,45 @@ func (c *IbmV7000) GetClusterStates() error {
 func (c *IbmV7000) GetNodeStates() error {
 	c.Log.Debug("[RPC]获取节点状态")
 
-	// TODO: 获取节点数
-	// 请求参数
-	params := map[string]interface{}{
-		"clazz":       "com.ibm.evo.rpc.RPCRequest",
-		"methodArgs":  []int{1},
-		"methodClazz": "com.ibm.svc.gui.logic.ClusterRPC",
-		"methodName":  "getNodeStats",
-	}
-	paramsJson, err := json.Marshal(params)
-	if err != nil {
-		c.Log.Errorf("JSON序列化出错, %v, error: %v", params, err)
-		return err
-	}
+	// 按系统健康数据中的节点查询, 节点更换后ID可能不连续
+	if c.CrawlerData.Health == nil || len(c.CrawlerData.Health.NodeHardware) == 0 {
+		c.Log.Errorf("[RPC]获取节点状态失败, 系统健康数据中没有节点")
+		return errors.New("系统健康数据中没有节点")
+	}
+	nodes := c.CrawlerData.Health.NodeHardware
+	c.CrawlerData.NodeStats = make(map[string][]*IbmStat)
+	for i := 0; i < len(nodes); i++ {
+		nodeId := nodes[i].Id
+		// 请求参数
+		params := map[string]interface{}{
+			"clazz":       "com.ibm.evo.rpc.RPCRequest",
+			"methodArgs":  []int64{nodeId},
+			"methodClazz": "com.ibm.svc.gui.logic.ClusterRPC",
+			"methodName":  "getNodeStats",
+		}
+		paramsJson, err := json.Marshal(params)
+		if err != nil {
+			c.Log.Errorf("JSON序列化出错, %v, error: %v", params, err)
+			return err
+		}
 
-	if data, err := c.PostRPC(bytes.NewReader(paramsJson)); err != nil {
-		c.Log.Errorf("[RPC]获取节点状态失败, error: %v", err)
-		return err
-	} else {
-		c.Log.Debugf("[RPC]获取节点状态结果, %s", data)
-		return nil
+		data, err := c.PostRPC(bytes.NewReader(paramsJson))
+		if err != nil {
+			c.Log.Errorf("[RPC]获取节点[%d]状态失败, error: %v", nodeId, err)
+			return err
+		}
+		stats := make([]*IbmStat, 0)
+		if err := ParseIbmRPCResult(data, &stats); err != nil || len(stats) == 0 {
+			c.Log.Debugf("[RPC]节点[%d]没有性能数据, error: %v", nodeId, err)
+			continue
+		}
+		c.CrawlerData.NodeStats[strconv.FormatInt(nodeId, 10)] = stats
+	}
+	if len(c.CrawlerData.NodeStats) == 0 {
+		c.Log.Errorf("[RPC]获取节点状态失败, 没有节点返回性能数据")
+		return errors.New("没有节点返回性能数据")
 	}
+	return nil
 }
 
 func (c *IbmV7000) GetHosts() error {
@@ -356,7 +503,10 @@ func (c *IbmV7000) GetHosts() error {
 		c.Log.Errorf("[RPC]获取主机集群状态失败, error: %v", err)
 		return err
 	} else {
-		c.Log.Debugf("[RPC]获取主机集群状态结果, %s", data)
+		if err := ParseIbmRPCResult(data, &c.CrawlerData.Hosts); err != nil {
+			c.Log.Errorf("[RPC]解析主机集群状态失败, error: %v", err)
+			return err
+		}
 		return nil
 	}
 }
@@ -391,7 +541,43 @@ func (c *IbmV7000) GetPhysicalInternal() error {
 		c.Log.Errorf("[RPC]获取内部存储器（磁盘）状态失败, error: %v", err)
 		return err
 	} else {
-		c.Log.Debugf("[RPC]获取内部存储器（磁盘）状态结果, %s", data)
+		internal := new(IbmPhysicalInternal)
+		if err := ParseIbmRPCResult(data, internal); err != nil {
+			c.Log.Errorf("[RPC]解析内部存储器（磁盘）状态失败, error: %v", err)
+			return err
+		}
+		c.CrawlerData.DriveClasses = internal.Classes
+		c.CrawlerData.Drives = internal.Drives
+		return nil
+	}
+}
+
+func (c *IbmV7000) GetSystemHealth() error {
+	c.Log.Debug("[RPC]获取系统健康数据")
+
+	// 请求参数
+	params := map[string]interface{}{
+		"clazz":       "com.ibm.evo.rpc.RPCRequest",
+		"methodArgs":  []interface{}{},
+		"methodClazz": "com.ibm.svc.gui.logic.HomeRPC",
+		"methodName":  "getSystemHealthData",
+	}
+	paramsJson, err := json.Marshal(params)
+	if err != nil {
+		c.Log.Errorf("JSON序列化出错, %v, error: %v", params, err)
+		return err
+	}
+
+	if data, err := c.PostRPC(bytes.NewReader(paramsJson)); err != nil {
+		c.Log.Errorf("[RPC]获取系统健康数据失败, error: %v", err)
+		return err
+	} else {
+		health := new(IbmSystemHealth)
+		if err := ParseIbmRPCResult(data, health); err != nil {
+			c.Log.Errorf("[RPC]解析系统健康数据失败, error: %v", err)
+			return err
+		}
+		c.CrawlerData.Health = health
 		return nil
 	}
 }
@@ -399,6 +585,39 @@ func (c *IbmV7000) GetPhysicalInternal() error {
 func (c *IbmV7000) GetVolumes() error {
 	c.Log.Debug("[POST]获取卷状态")
 
+	// panelKey是页面打开时生成的时间戳, 服务端按panelKey缓存表格数据, 同一次查询的所有分页使用相同的panelKey
+	panelKey := strconv.FormatInt(time.Now().UnixNano()/1e6, 10)
+
+	seen := make(map[string]bool)
+	var start, numRows int64
+	for {
+		store, err := c.GetVolumePage(panelKey, start, IbmVolumePageSize)
+		if err != nil {
+			return err
+		}
+		numRows = store.NumRows
+		for i := 0; i < len(store.Items); i++ {
+			// 分页之间数据发生变化时可能返回重复的行
+			if seen[store.Items[i].Idty] {
+				continue
+			}
+			seen[store.Items[i].Idty] = true
+			c.CrawlerData.Volumes = append(c.CrawlerData.Volumes, store.Items[i])
+		}
+
+		start += int64(len(store.Items))
+		if len(store.Items) == 0 || start >= numRows {
+			break
+		}
+	}
+	if int64(len(c.CrawlerData.Volumes)) != numRows {
+		c.Log.Warnf("[POST]卷数量与总行数不一致, 卷数量: %d, 总行数: %d", len(c.CrawlerData.Volumes), numRows)
+	}
+	return nil
+}
+
+// GetVolumePage 分页查询卷, start为起始行, count为每页行数
+func (c *IbmV7000) GetVolumePage(panelKey string, start, count int64) (*IbmQueryReadStore, error) {
 	tr := &http.Transport{
 		TLSClientConfig: &tls.Config{
 			InsecureSkipVerify: true,
@@ -406,29 +625,34 @@ func (c *IbmV7000) GetVolumes() error {
 	}
 	client := &http.Client{Transport: tr}
 
-	// TODO: 需要分页查询
 	form := url.Values{
-		"panelKey":          []string{"1631805210113"},
+		"panelKey":          []string{panelKey},
 		"extendedMDiskInfo": []string{"false"},
 		"password":          []string{"0"},
 		"tzoffset":          []string{"40"},
+		"start":             []string{strconv.FormatInt(start, 10)},
+		"count":             []string{strconv.FormatInt(count, 10)},
 	}
 	request, _ := http.NewRequest("POST", c.Host+"/VDiskGridDataHandler", strings.NewReader(form.Encode()))
 	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	request.Header.Set("Cookie", c.AuthCookie)
 	if resp, err := client.Do(request); err != nil {
 		c.Log.Errorf("获取请求数据失败, params: %v, error: %v", form, err)
-		return err
+		return nil, err
 	} else {
 		defer func() {
 			_ = resp.Body.Close()
 		}()
 		if body, err := ioutil.ReadAll(resp.Body); err != nil {
 			c.Log.Errorf("读取请求体数据失败, params: VDiskGridDataHandler, error: %v", err)
-			return err
+			return nil, err
 		} else {
-			fmt.Println(string(body))
-			return nil
+			store := new(IbmQueryReadStore)
+			if err := json.Unmarshal(body, store); err != nil {
+				c.Log.Errorf("解析卷状态失败, start: %d, error: %v", start, err)
+				return nil, err
+			}
+			return store, nil
 		}
 	}
 }
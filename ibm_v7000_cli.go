@@ -0,0 +1,625 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IbmCommander 执行CLI命令, 由REST接口(IbmRestClient)或SSH(IbmShell)实现
+//
+// command为命令名称, 查询单个对象的详细视图时为 命令/对象ID, 如 lsdrive/5.
+// params为命令参数, 如 {"bytes": true} 对应 -bytes, {"fixed": "no"} 对应 -fixed no.
+// result为*IbmRow(详细视图, 如lssystem)或*[]IbmRow(列表视图)
+type IbmCommander interface {
+	Command(command string, params map[string]interface{}, result interface{}) error
+	Close() error
+}
+
+// IbmRow CLI命令输出的一行, 列名为键, REST接口返回的JSON对象字段与CLI的列名相同, 值均为字符串
+type IbmRow map[string]string
+
+func (row IbmRow) Int(name string) int64 {
+	value, _ := strconv.ParseInt(row[name], 10, 64)
+	return value
+}
+
+// Size 容量, 使用-bytes参数时为字节数, 否则带单位, 如 16.35TB
+func (row IbmRow) Size(name string) int64 {
+	return parseIbmSize(row[name])
+}
+
+// ibmSizeUnits 容量单位, 按1024换算
+var ibmSizeUnits = []string{"PB", "TB", "GB", "MB", "KB", "B"}
+
+func parseIbmSize(value string) int64 {
+	value = strings.TrimSpace(value)
+	scale := 1.0
+	for i := 0; i < len(ibmSizeUnits); i++ {
+		if strings.HasSuffix(value, ibmSizeUnits[i]) {
+			value = strings.TrimSpace(strings.TrimSuffix(value, ibmSizeUnits[i]))
+			for j := i; j < len(ibmSizeUnits)-1; j++ {
+				scale *= 1024
+			}
+			break
+		}
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0
+	}
+	return int64(number * scale)
+}
+
+// ToSystem lssystem
+func (row IbmRow) ToSystem() *IbmClusterSystem {
+	return &IbmClusterSystem{
+		Id:                     row["id"],
+		Name:                   row["name"],
+		ProductName:            row["product_name"],
+		CodeLevel:              row["code_level"],
+		ConsoleIp:              row["console_IP"],
+		TotalMdiskCapacity:     row.Size("total_mdisk_capacity"),
+		SpaceInMdiskGrps:       row.Size("space_in_mdisk_grps"),
+		SpaceAllocatedToVdisks: row.Size("space_allocated_to_vdisks"),
+		TotalFreeSpace:         row.Size("total_free_space"),
+		TotalUsedCapacity:      row.Size("total_used_capacity"),
+		TotalVdiskCapacity:     row.Size("total_vdisk_capacity"),
+		TotalVdiskcopyCapacity: row.Size("total_vdiskcopy_capacity"),
+		TotalOverallocation:    row.Int("total_overallocation"),
+		TotalDriveRawCapacity:  row.Size("total_drive_raw_capacity"),
+		StatisticsFrequency:    row.Int("statistics_frequency"),
+		StatisticsStatus:       row["statistics_status"],
+		TimeZone:               row["time_zone"],
+	}
+}
+
+// ToPool lsmdiskgrp
+func (row IbmRow) ToPool() *IbmPool {
+	return &IbmPool{
+		Id:              row.Int("id"),
+		Name:            row["name"],
+		Status:          row["status"],
+		Type:            row["type"],
+		MdiskCount:      row.Int("mdisk_count"),
+		VdiskCount:      row.Int("vdisk_count"),
+		Capacity:        row.Size("capacity"),
+		FreeCapacity:    row.Size("free_capacity"),
+		UsedCapacity:    row.Size("used_capacity"),
+		RealCapacity:    row.Size("real_capacity"),
+		VirtualCapacity: row.Size("virtual_capacity"),
+		ExtentSize:      row.Int("extent_size"),
+		Overallocation:  row.Int("overallocation"),
+		Warning:         row.Int("warning"),
+		EasyTier:        row["easy_tier"],
+		EasyTierStatus:  row["easy_tier_status"],
+	}
+}
+
+// ToVolume lsvdisk, 列表中每个卷一行, 不包含副本的已使用容量
+func (row IbmRow) ToVolume() *IbmVolume {
+	return &IbmVolume{
+		Idty:         row["id"],
+		Id:           row.Int("id"),
+		Name:         row["name"],
+		VdiskUid:     row["vdisk_UID"],
+		Status:       row["status"],
+		IsPrimary:    true,
+		MdiskGrpId:   row["mdisk_grp_id"],
+		MdiskGrpName: row["mdisk_grp_name"],
+		IoGroupName:  row["IO_group_name"],
+		Capacity:     row.Size("capacity"),
+		IsThin:       row.Int("se_copy_count") > 0,
+		IsCompressed: row.Int("compressed_copy_count") > 0,
+		CopyCount:    row.Int("copy_count"),
+	}
+}
+
+// ToVolumeCopy lsvdiskcopy, 每个副本一行, 完全分配的副本已使用和实际分配容量与虚拟容量相同,
+// 精简和压缩副本的容量使用lssevdiskcopy的输出设置(SetSpaceEfficient)
+func (row IbmRow) ToVolumeCopy() *IbmVolume {
+	capacity := row.Size("capacity")
+	return &IbmVolume{
+		Idty:         row["vdisk_id"] + "-" + row["copy_id"],
+		Id:           row.Int("vdisk_id"),
+		Name:         row["vdisk_name"],
+		CopyStatus:   row["status"],
+		CopyId:       row.Int("copy_id"),
+		IsPrimary:    row["primary"] == "yes",
+		MdiskGrpId:   row["mdisk_grp_id"],
+		MdiskGrpName: row["mdisk_grp_name"],
+		Capacity:     capacity,
+		UsedCapacity: capacity,
+		RealCapacity: capacity,
+		IsThin:       row["se_copy"] == "yes",
+		IsCompressed: row["compressed_copy"] == "yes",
+	}
+}
+
+// SetSpaceEfficient lssevdiskcopy, 精简和压缩副本的已使用和实际分配容量
+func (v *IbmVolume) SetSpaceEfficient(row IbmRow) {
+	v.UsedCapacity = row.Size("used_capacity")
+	v.RealCapacity = row.Size("real_capacity")
+}
+
+// ToHost lshost
+func (row IbmRow) ToHost() *IbmHost {
+	return &IbmHost{
+		Id:              row.Int("id"),
+		Name:            row["name"],
+		Status:          row["status"],
+		PortCount:       row.Int("port_count"),
+		IogrpCount:      row.Int("iogrp_count"),
+		HostClusterId:   row.Int("host_cluster_id"),
+		HostClusterName: row["host_cluster_name"],
+	}
+}
+
+// ToDrive lsdrive
+func (row IbmRow) ToDrive() *IbmDrive {
+	return &IbmDrive{
+		Id:           row.Int("id"),
+		EnclosureId:  row.Int("enclosure_id"),
+		SlotId:       row.Int("slot_id"),
+		Status:       row["status"],
+		Use:          row["use"],
+		TechType:     row["tech_type"],
+		Capacity:     row.Size("capacity"),
+		MdiskName:    row["mdisk_name"],
+		DriveClassId: row["drive_class_id"],
+	}
+}
+
+// SetDetail lsdrive <id>, 列表视图中没有转速, 厂商和固件版本
+func (d *IbmDrive) SetDetail(row IbmRow) {
+	d.Rpm = row.Int("RPM")
+	d.VendorId = row["vendor_id"]
+	d.ProductId = row["product_id"]
+	d.FirmwareLevel = row["firmware_level"]
+	d.Port1Status = row["port_1_status"]
+	d.Port2Status = row["port_2_status"]
+}
+
+// ToDriveClass lsdriveclass, capacity为单个磁盘的容量
+func (row IbmRow) ToDriveClass() *IbmDriveClass {
+	capacity := row.Size("capacity")
+	return &IbmDriveClass{
+		Id:            row["id"],
+		IoGrp:         row["IO_group_name"],
+		TechType:      row["tech_type"],
+		Rpm:           row.Int("RPM"),
+		BlockSize:     row.Int("block_size"),
+		Capacity:      capacity,
+		TotalCapacity: capacity * row.Int("total_count"),
+	}
+}
+
+// ToNodeCanister lsnodecanister
+func (row IbmRow) ToNodeCanister() *IbmNodeHardware {
+	return &IbmNodeHardware{
+		Id:          row.Int("id"),
+		Name:        row["name"],
+		IoGroupName: row["IO_group_name"],
+		Status:      row["status"],
+	}
+}
+
+// SetHardware lsnodehw <id>, 节点的内存, CPU和实际硬件与配置是否一致
+func (n *IbmNodeHardware) SetHardware(row IbmRow) {
+	n.MemoryActual = row.Int("memory_actual")
+	n.CpuCount = row.Int("cpu_count")
+	n.ActualValid = row["actual_valid"] == "yes"
+}
+
+// ToEnclosure lsenclosure
+func (row IbmRow) ToEnclosure() *IbmEnclosure {
+	return &IbmEnclosure{
+		Id:              row.Int("id"),
+		Status:          row["status"],
+		Type:            row["type"],
+		ProductMTM:      row["product_MTM"],
+		SerialNumber:    row["serial_number"],
+		TotalCanisters:  row.Int("total_canisters"),
+		OnlineCanisters: row.Int("online_canisters"),
+		TotalPSUs:       row.Int("total_PSUs"),
+		OnlinePSUs:      row.Int("online_PSUs"),
+		DriveSlots:      row.Int("drive_slots"),
+	}
+}
+
+// ToEnclosureCanister lsenclosurecanister
+func (row IbmRow) ToEnclosureCanister() *IbmEnclosureCanister {
+	return &IbmEnclosureCanister{
+		EnclosureId: row.Int("enclosure_id"),
+		CanisterId:  row.Int("canister_id"),
+		NodeId:      row.Int("node_id"),
+		NodeName:    row["node_name"],
+		Type:        row["type"],
+		Status:      row["status"],
+	}
+}
+
+// SetDetail lsenclosurecanister -canister <canister_id> <enclosure_id>, 控制器的温度, 故障灯和固件版本
+func (e *IbmEnclosureCanister) SetDetail(row IbmRow) {
+	e.SesStatus = row["SES_status"]
+	e.FaultLed = row["fault_LED"]
+	e.Temperature = row.Int("temperature")
+	e.FirmwareLevel = row["firmware_level"]
+}
+
+// ToEnclosurePSU lsenclosurepsu
+func (row IbmRow) ToEnclosurePSU() *IbmEnclosurePSU {
+	return &IbmEnclosurePSU{
+		EnclosureId: row.Int("enclosure_id"),
+		PsuId:       row.Int("PSU_id"),
+		Status:      row["status"],
+		InputPower:  row["input_power"],
+	}
+}
+
+// ToEnclosureBattery lsenclosurebattery
+func (row IbmRow) ToEnclosureBattery() *IbmEnclosureBattery {
+	return &IbmEnclosureBattery{
+		EnclosureId:       row.Int("enclosure_id"),
+		BatteryId:         row.Int("battery_id"),
+		Status:            row["status"],
+		ChargingStatus:    row["charging_status"],
+		ReconditionNeeded: row["recondition_needed"],
+		PercentCharged:    row.Int("percent_charged"),
+		EndOfLifeWarning:  row["end_of_life_warning"],
+	}
+}
+
+// ToFCPort lsportfc
+func (row IbmRow) ToFCPort() *IbmIOPort {
+	return &IbmIOPort{
+		Id:         row["id"],
+		NodeId:     row.Int("node_id"),
+		NodeName:   row["node_name"],
+		PortId:     row.Int("port_id"),
+		PortType:   "fc",
+		Status:     row["status"],
+		PortSpeed:  row["port_speed"],
+		Wwpn:       row["WWPN"],
+		ClusterUse: row["cluster_use"],
+	}
+}
+
+// ToStat lssystemstats, lsnodestats, 命令输出中没有采样时间, 使用采集时间,
+// stat_peak_time为设备时区的本地时间, sampleTime需要使用设备的时区
+func (row IbmRow) ToStat(sampleTime time.Time) *IbmStat {
+	return &IbmStat{
+		StatName:      row["stat_name"],
+		SampleEpoch:   sampleTime.Unix(),
+		StatCurrent:   row.Int("stat_current"),
+		StatPeak:      row.Int("stat_peak"),
+		StatPeakTime:  row["stat_peak_time"],
+		StatPeakEpoch: parseIbmTimestamp(row["stat_peak_time"], sampleTime.Location()).Unix(),
+	}
+}
+
+// ToEvent lseventlog
+func (row IbmRow) ToEvent() *IbmEvent {
+	return &IbmEvent{
+		SequenceNumber: row.Int("sequence_number"),
+		LastTimestamp:  row["last_timestamp"],
+		ObjectType:     row["object_type"],
+		ObjectId:       row["object_id"],
+		ObjectName:     row["object_name"],
+		Status:         row["status"],
+		Fixed:          row["fixed"],
+		EventId:        row["event_id"],
+		ErrorCode:      row["error_code"],
+		Description:    row["description"],
+	}
+}
+
+// ibmRowList 执行列表视图的命令
+func ibmRowList(cli IbmCommander, command string, params map[string]interface{}) ([]IbmRow, error) {
+	rows := make([]IbmRow, 0)
+	if err := cli.Command(command, params, &rows); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// StartCommand 通过CLI命令采集(REST接口或SSH), 采集的数据与RPC接口相同
+func (c *IbmV7000) StartCommand() error {
+	defer func() {
+		_ = c.Commander.Close()
+	}()
+	bytesParam := map[string]interface{}{"bytes": true}
+
+	c.Log.Debug("[CLI]获取系统信息")
+	system := IbmRow{}
+	if err := c.Commander.Command("lssystem", nil, &system); err != nil {
+		c.Log.Errorf("[CLI]获取系统信息失败, error: %v", err)
+		return err
+	}
+	c.CrawlerData.System = system.ToSystem()
+
+	c.Log.Debug("[CLI]获取存储池状态")
+	pools, err := ibmRowList(c.Commander, "lsmdiskgrp", bytesParam)
+	if err != nil {
+		c.Log.Errorf("[CLI]获取存储池状态失败, error: %v", err)
+		return err
+	}
+	c.CrawlerData.Pools = make([]*IbmPool, len(pools))
+	for i := 0; i < len(pools); i++ {
+		c.CrawlerData.Pools[i] = pools[i].ToPool()
+	}
+
+	c.Log.Debug("[CLI]获取系统和节点状态（实时）")
+	now := time.Now().In(c.SystemLocation())
+	stats, err := ibmRowList(c.Commander, "lssystemstats", nil)
+	if err != nil {
+		c.Log.Errorf("[CLI]获取系统状态失败, error: %v", err)
+		return err
+	}
+	c.CrawlerData.ClusterStats = make([]*IbmStat, len(stats))
+	for i := 0; i < len(stats); i++ {
+		c.CrawlerData.ClusterStats[i] = stats[i].ToStat(now)
+	}
+	nodeStats, err := ibmRowList(c.Commander, "lsnodestats", nil)
+	if err != nil {
+		c.Log.Errorf("[CLI]获取节点状态失败, error: %v", err)
+		return err
+	}
+	c.CrawlerData.NodeStats = make(map[string][]*IbmStat)
+	for i := 0; i < len(nodeStats); i++ {
+		nodeId := nodeStats[i]["node_id"]
+		c.CrawlerData.NodeStats[nodeId] = append(c.CrawlerData.NodeStats[nodeId], nodeStats[i].ToStat(now))
+	}
+
+	c.Log.Debug("[CLI]获取主机状态")
+	hosts, err := ibmRowList(c.Commander, "lshost", nil)
+	if err != nil {
+		c.Log.Errorf("[CLI]获取主机状态失败, error: %v", err)
+		return err
+	}
+	c.CrawlerData.Hosts = make([]*IbmHost, len(hosts))
+	for i := 0; i < len(hosts); i++ {
+		c.CrawlerData.Hosts[i] = hosts[i].ToHost()
+	}
+
+	if err := c.GetCommandDrives(); err != nil {
+		return err
+	}
+	if err := c.GetCommandVolumes(); err != nil {
+		return err
+	}
+	if err := c.GetCommandHealth(); err != nil {
+		return err
+	}
+	// 节点名称来自健康数据中的节点
+	if err := c.WriteStatSamples(); err != nil {
+		return err
+	}
+	if err := c.GetHostMappings(); err != nil {
+		return err
+	}
+	if c.CopyServices {
+		if err := c.GetCopyServices(); err != nil {
+			return err
+		}
+	}
+
+	c.Log.Debug("[CLI]获取事件日志")
+	events, err := ibmRowList(c.Commander, "lseventlog", map[string]interface{}{"fixed": "no"})
+	if err != nil {
+		c.Log.Errorf("[CLI]获取事件日志失败, error: %v", err)
+		return err
+	}
+	items := make([]*IbmEvent, len(events))
+	for i := 0; i < len(events); i++ {
+		items[i] = events[i].ToEvent()
+	}
+	return c.SetEvents(items)
+}
+
+// GetCommandDrives 获取磁盘和磁盘类别, 列表视图中没有的转速, 厂商和固件版本逐个查询详细视图
+func (c *IbmV7000) GetCommandDrives() error {
+	c.Log.Debug("[CLI]获取内部存储器（磁盘）状态")
+	bytesParam := map[string]interface{}{"bytes": true}
+
+	drives, err := ibmRowList(c.Commander, "lsdrive", bytesParam)
+	if err != nil {
+		c.Log.Errorf("[CLI]获取磁盘状态失败, error: %v", err)
+		return err
+	}
+	c.CrawlerData.Drives = make([]*IbmDrive, len(drives))
+	for i := 0; i < len(drives); i++ {
+		drive := drives[i].ToDrive()
+		detail := IbmRow{}
+		// 详细视图获取失败时保留列表视图的数据
+		if err := c.Commander.Command("lsdrive/"+drives[i]["id"], bytesParam, &detail); err != nil {
+			c.Log.Warnf("[CLI]获取磁盘[%s]详细信息失败, 跳过, error: %v", drives[i]["id"], err)
+		} else {
+			drive.SetDetail(detail)
+		}
+		c.CrawlerData.Drives[i] = drive
+	}
+
+	// 7.6之前的版本没有lsdriveclass命令, 不影响其他数据
+	classes, err := ibmRowList(c.Commander, "lsdriveclass", bytesParam)
+	if err != nil {
+		c.Log.Warnf("[CLI]获取磁盘类别失败, error: %v", err)
+		return nil
+	}
+	c.CrawlerData.DriveClasses = make([]*IbmDriveClass, len(classes))
+	for i := 0; i < len(classes); i++ {
+		c.CrawlerData.DriveClasses[i] = classes[i].ToDriveClass()
+	}
+	c.CrawlerData.SetDriveClassCapacity()
+	return nil
+}
+
+// GetCommandVolumes 获取卷和卷副本, lsvdisk的列表视图中没有已使用和实际分配容量
+func (c *IbmV7000) GetCommandVolumes() error {
+	c.Log.Debug("[CLI]获取卷状态")
+	bytesParam := map[string]interface{}{"bytes": true}
+
+	volumes, err := ibmRowList(c.Commander, "lsvdisk", bytesParam)
+	if err != nil {
+		c.Log.Errorf("[CLI]获取卷状态失败, error: %v", err)
+		return err
+	}
+	c.CrawlerData.Volumes = make([]*IbmVolume, len(volumes))
+	for i := 0; i < len(volumes); i++ {
+		c.CrawlerData.Volumes[i] = volumes[i].ToVolume()
+	}
+
+	copyRows, err := ibmRowList(c.Commander, "lsvdiskcopy", bytesParam)
+	if err != nil {
+		c.Log.Errorf("[CLI]获取卷副本失败, error: %v", err)
+		return err
+	}
+	seRows, err := ibmRowList(c.Commander, "lssevdiskcopy", bytesParam)
+	if err != nil {
+		c.Log.Errorf("[CLI]获取精简卷副本失败, error: %v", err)
+		return err
+	}
+	seCopies := make(map[string]IbmRow)
+	for i := 0; i < len(seRows); i++ {
+		seCopies[seRows[i]["vdisk_id"]+"-"+seRows[i]["copy_id"]] = seRows[i]
+	}
+	copies := make([]*IbmVolume, len(copyRows))
+	for i := 0; i < len(copyRows); i++ {
+		copies[i] = copyRows[i].ToVolumeCopy()
+		if row, ok := seCopies[copies[i].Idty]; ok {
+			copies[i].SetSpaceEfficient(row)
+		}
+	}
+	c.CrawlerData.SetVolumeCopies(copies)
+	return nil
+}
+
+// SetVolumeCopies 主副本的状态和容量设置到卷上, 镜像卷的其他副本放在children中, 与RPC接口的数据相同
+func (h *IbmV7000CrawlerData) SetVolumeCopies(copies []*IbmVolume) {
+	volumes := make(map[int64]*IbmVolume)
+	for i := 0; i < len(h.Volumes); i++ {
+		volumes[h.Volumes[i].Id] = h.Volumes[i]
+	}
+	for i := 0; i < len(copies); i++ {
+		cp := copies[i]
+		v, ok := volumes[cp.Id]
+		if !ok {
+			continue
+		}
+		if cp.IsPrimary {
+			v.Idty = cp.Idty
+			v.CopyId = cp.CopyId
+			v.CopyStatus = cp.CopyStatus
+			v.MdiskGrpId = cp.MdiskGrpId
+			v.MdiskGrpName = cp.MdiskGrpName
+			v.UsedCapacity = cp.UsedCapacity
+			v.RealCapacity = cp.RealCapacity
+			v.IsThin = cp.IsThin
+			v.IsCompressed = cp.IsCompressed
+			continue
+		}
+		cp.Status = v.Status
+		cp.VdiskUid = v.VdiskUid
+		cp.IoGroupName = v.IoGroupName
+		cp.CopyCount = v.CopyCount
+		v.Children = append(v.Children, cp)
+	}
+}
+
+// SetDriveClassCapacity 按磁盘的用途统计各类别的成员和热备容量
+func (h *IbmV7000CrawlerData) SetDriveClassCapacity() {
+	classes := make(map[string]*IbmDriveClass)
+	for i := 0; i < len(h.DriveClasses); i++ {
+		classes[h.DriveClasses[i].Id] = h.DriveClasses[i]
+	}
+	for i := 0; i < len(h.Drives); i++ {
+		class, ok := classes[h.Drives[i].DriveClassId]
+		if !ok {
+			continue
+		}
+		switch h.Drives[i].Use {
+		case "member":
+			class.MemberCapacity += h.Drives[i].Capacity
+		case "spare":
+			class.SpareCapacity += h.Drives[i].Capacity
+		}
+	}
+}
+
+// GetCommandHealth 获取端口, 节点, 机柜及其控制器, 电源和电池的状态
+func (c *IbmV7000) GetCommandHealth() error {
+	c.Log.Debug("[CLI]获取系统健康数据")
+	health := new(IbmSystemHealth)
+
+	ports, err := ibmRowList(c.Commander, "lsportfc", nil)
+	if err != nil {
+		c.Log.Errorf("[CLI]获取FC端口状态失败, error: %v", err)
+		return err
+	}
+	for i := 0; i < len(ports); i++ {
+		health.Ports = append(health.Ports, ports[i].ToFCPort())
+	}
+
+	nodes, err := ibmRowList(c.Commander, "lsnodecanister", nil)
+	if err != nil {
+		c.Log.Errorf("[CLI]获取节点状态失败, error: %v", err)
+		return err
+	}
+	for i := 0; i < len(nodes); i++ {
+		node := nodes[i].ToNodeCanister()
+		hardware := IbmRow{}
+		if err := c.Commander.Command("lsnodehw/"+nodes[i]["id"], nil, &hardware); err != nil {
+			c.Log.Warnf("[CLI]获取节点[%s]硬件信息失败, 跳过, error: %v", nodes[i]["name"], err)
+		} else {
+			node.SetHardware(hardware)
+		}
+		health.NodeHardware = append(health.NodeHardware, node)
+	}
+
+	enclosures, err := ibmRowList(c.Commander, "lsenclosure", nil)
+	if err != nil {
+		c.Log.Errorf("[CLI]获取机柜状态失败, error: %v", err)
+		return err
+	}
+	for i := 0; i < len(enclosures); i++ {
+		health.Enclosures = append(health.Enclosures, enclosures[i].ToEnclosure())
+	}
+
+	canisters, err := ibmRowList(c.Commander, "lsenclosurecanister", nil)
+	if err != nil {
+		c.Log.Errorf("[CLI]获取机柜控制器状态失败, error: %v", err)
+		return err
+	}
+	for i := 0; i < len(canisters); i++ {
+		canister := canisters[i].ToEnclosureCanister()
+		detail := IbmRow{}
+		params := map[string]interface{}{"canister": canisters[i]["canister_id"]}
+		if err := c.Commander.Command("lsenclosurecanister/"+canisters[i]["enclosure_id"], params, &detail); err != nil {
+			c.Log.Warnf("[CLI]获取机柜[%s]控制器[%s]详细信息失败, 跳过, error: %v", canisters[i]["enclosure_id"], canisters[i]["canister_id"], err)
+		} else {
+			canister.SetDetail(detail)
+		}
+		health.EnclosureCanisters = append(health.EnclosureCanisters, canister)
+	}
+
+	psus, err := ibmRowList(c.Commander, "lsenclosurepsu", nil)
+	if err != nil {
+		c.Log.Errorf("[CLI]获取机柜电源状态失败, error: %v", err)
+		return err
+	}
+	for i := 0; i < len(psus); i++ {
+		health.EnclosurePSUs = append(health.EnclosurePSUs, psus[i].ToEnclosurePSU())
+	}
+
+	batteries, err := ibmRowList(c.Commander, "lsenclosurebattery", nil)
+	if err != nil {
+		c.Log.Errorf("[CLI]获取机柜电池状态失败, error: %v", err)
+		return err
+	}
+	for i := 0; i < len(batteries); i++ {
+		health.EnclosureBatteries = append(health.EnclosureBatteries, batteries[i].ToEnclosureBattery())
+	}
+
+	c.CrawlerData.Health = health
+	return nil
+}
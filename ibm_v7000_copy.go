@@ -0,0 +1,361 @@
+package main
+
+import (
+	"time"
+)
+
+// IbmFlashCopyMapping FlashCopy映射(lsfcmap)
+type IbmFlashCopyMapping struct {
+	Id              int64  `json:"id"`
+	Name            string `json:"name"`
+	SourceVdiskName string `json:"sourceVdiskName"`
+	TargetVdiskName string `json:"targetVdiskName"`
+	GroupName       string `json:"groupName"`
+	Status          string `json:"status"`   // idle_or_copied, copying, prepared, stopped, suspended 等
+	Progress        int64  `json:"progress"` // 复制进度(%)
+	CopyRate        int64  `json:"copyRate"` // 后台复制速率, 0表示不复制(快照)
+	StartTime       string `json:"startTime"`
+}
+
+// IbmFlashCopyGroup FlashCopy一致性组(lsfcconsistgrp)
+type IbmFlashCopyGroup struct {
+	Id     int64  `json:"id"`
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+// IbmRemoteCopy 远程复制关系(lsrcrelationship), 包括Metro Mirror和Global Mirror
+type IbmRemoteCopy struct {
+	Id                   int64  `json:"id"`
+	Name                 string `json:"name"`
+	MasterClusterName    string `json:"masterClusterName"`
+	MasterVdiskName      string `json:"masterVdiskName"`
+	AuxClusterName       string `json:"auxClusterName"`
+	AuxVdiskName         string `json:"auxVdiskName"`
+	Primary              string `json:"primary"` // master, aux
+	ConsistencyGroupName string `json:"consistencyGroupName"`
+	State                string `json:"state"`    // consistent_synchronized, consistent_copying, inconsistent_copying, idling 等
+	Progress             int64  `json:"progress"` // 同步进度(%)
+	CopyType             string `json:"copyType"` // metro, global
+	CyclingMode          string `json:"cyclingMode"`
+	FreezeTime           string `json:"freezeTime"`    // 辅助卷上一致数据的时间, YYYY/MM/DD/HH/MM/SS
+	RpoLagSeconds        int64  `json:"rpoLagSeconds"` // 距离一致数据时间的秒数, -1表示辅助卷数据不一致
+}
+
+// IbmRemoteCopyGroup 远程复制一致性组(lsrcconsistgrp)
+type IbmRemoteCopyGroup struct {
+	Id                int64  `json:"id"`
+	Name              string `json:"name"`
+	MasterClusterName string `json:"masterClusterName"`
+	AuxClusterName    string `json:"auxClusterName"`
+	Primary           string `json:"primary"`
+	State             string `json:"state"`
+	RelationshipCount int64  `json:"relationshipCount"`
+	CopyType          string `json:"copyType"`
+	CyclingMode       string `json:"cyclingMode"`
+	FreezeTime        string `json:"freezeTime"`
+	RpoLagSeconds     int64  `json:"rpoLagSeconds"`
+}
+
+// IbmHostMapping 主机与卷的映射(lshostvdiskmap)
+type IbmHostMapping struct {
+	HostId      int64  `json:"hostId"`
+	HostName    string `json:"hostName"`
+	ScsiId      int64  `json:"scsiId"`
+	VdiskId     int64  `json:"vdiskId"`
+	VdiskName   string `json:"vdiskName"`
+	VdiskUid    string `json:"vdiskUid"`
+	IoGroupName string `json:"ioGroupName"`
+	MappingType string `json:"mappingType"` // private, shared
+}
+
+// ToFlashCopyMapping lsfcmap
+func (row IbmRow) ToFlashCopyMapping() *IbmFlashCopyMapping {
+	return &IbmFlashCopyMapping{
+		Id:              row.Int("id"),
+		Name:            row["name"],
+		SourceVdiskName: row["source_vdisk_name"],
+		TargetVdiskName: row["target_vdisk_name"],
+		GroupName:       row["group_name"],
+		Status:          row["status"],
+		Progress:        row.Int("progress"),
+		CopyRate:        row.Int("copy_rate"),
+		StartTime:       row["start_time"],
+	}
+}
+
+// ToFlashCopyGroup lsfcconsistgrp
+func (row IbmRow) ToFlashCopyGroup() *IbmFlashCopyGroup {
+	return &IbmFlashCopyGroup{
+		Id:     row.Int("id"),
+		Name:   row["name"],
+		Status: row["status"],
+	}
+}
+
+// ToRemoteCopy lsrcrelationship
+func (row IbmRow) ToRemoteCopy(now time.Time) *IbmRemoteCopy {
+	r := &IbmRemoteCopy{
+		Id:                   row.Int("id"),
+		Name:                 row["name"],
+		MasterClusterName:    row["master_cluster_name"],
+		MasterVdiskName:      row["master_vdisk_name"],
+		AuxClusterName:       row["aux_cluster_name"],
+		AuxVdiskName:         row["aux_vdisk_name"],
+		Primary:              row["primary"],
+		ConsistencyGroupName: row["consistency_group_name"],
+		State:                row["state"],
+		Progress:             row.Int("progress"),
+		CopyType:             row["copy_type"],
+		CyclingMode:          row["cycling_mode"],
+		FreezeTime:           row["freeze_time"],
+	}
+	if r.State == "consistent_synchronized" && len(row["progress"]) == 0 {
+		// 同步完成后不输出进度
+		r.Progress = 100
+	}
+	r.RpoLagSeconds = ibmRpoLag(r.State, r.FreezeTime, now)
+	return r
+}
+
+// ToRemoteCopyGroup lsrcconsistgrp
+func (row IbmRow) ToRemoteCopyGroup(now time.Time) *IbmRemoteCopyGroup {
+	g := &IbmRemoteCopyGroup{
+		Id:                row.Int("id"),
+		Name:              row["name"],
+		MasterClusterName: row["master_cluster_name"],
+		AuxClusterName:    row["aux_cluster_name"],
+		Primary:           row["primary"],
+		State:             row["state"],
+		RelationshipCount: row.Int("relationship_count"),
+		CopyType:          row["copy_type"],
+		CyclingMode:       row["cycling_mode"],
+		FreezeTime:        row["freeze_time"],
+	}
+	g.RpoLagSeconds = ibmRpoLag(g.State, g.FreezeTime, now)
+	return g
+}
+
+// ToHostMapping lshostvdiskmap
+func (row IbmRow) ToHostMapping() *IbmHostMapping {
+	return &IbmHostMapping{
+		HostId:      row.Int("id"),
+		HostName:    row["name"],
+		ScsiId:      row.Int("SCSI_id"),
+		VdiskId:     row.Int("vdisk_id"),
+		VdiskName:   row["vdisk_name"],
+		VdiskUid:    row["vdisk_UID"],
+		IoGroupName: row["IO_group_name"],
+		MappingType: row["mapping_type"],
+	}
+}
+
+// ibmRpoLag 计算RPO延迟(秒), freeze_time为设备时区的本地时间, now需要使用设备的时区
+//
+// 有freeze_time时(Global Mirror with Change Volumes, 或复制停止后)辅助卷数据停留在该时间点;
+// 没有freeze_time且为同步状态时辅助卷与主卷一致, 延迟为0; 其他状态辅助卷数据不一致, 返回-1
+func ibmRpoLag(state, freezeTime string, now time.Time) int64 {
+	if len(freezeTime) > 0 {
+		t, err := time.ParseInLocation("2006/01/02/15/04/05", freezeTime, now.Location())
+		if err == nil {
+			lag := int64(now.Sub(t).Seconds())
+			if lag < 0 {
+				lag = 0
+			}
+			return lag
+		}
+	}
+	if state == "consistent_synchronized" {
+		return 0
+	}
+	return -1
+}
+
+// GetCopyServices 获取FlashCopy和远程复制
+func (c *IbmV7000) GetCopyServices() error {
+	c.Log.Debug("[CLI]获取FlashCopy映射")
+	fcmaps, err := ibmRowList(c.Commander, "lsfcmap", nil)
+	if err != nil {
+		c.Log.Errorf("[CLI]获取FlashCopy映射失败, error: %v", err)
+		return err
+	}
+	c.CrawlerData.FlashCopyMappings = make([]*IbmFlashCopyMapping, len(fcmaps))
+	for i := 0; i < len(fcmaps); i++ {
+		c.CrawlerData.FlashCopyMappings[i] = fcmaps[i].ToFlashCopyMapping()
+	}
+	fcgroups, err := ibmRowList(c.Commander, "lsfcconsistgrp", nil)
+	if err != nil {
+		c.Log.Errorf("[CLI]获取FlashCopy一致性组失败, error: %v", err)
+		return err
+	}
+	c.CrawlerData.FlashCopyGroups = make([]*IbmFlashCopyGroup, len(fcgroups))
+	for i := 0; i < len(fcgroups); i++ {
+		c.CrawlerData.FlashCopyGroups[i] = fcgroups[i].ToFlashCopyGroup()
+	}
+
+	c.Log.Debug("[CLI]获取远程复制关系")
+	now := time.Now().In(c.SystemLocation())
+	relationships, err := ibmRowList(c.Commander, "lsrcrelationship", nil)
+	if err != nil {
+		c.Log.Errorf("[CLI]获取远程复制关系失败, error: %v", err)
+		return err
+	}
+	c.CrawlerData.RemoteCopies = make([]*IbmRemoteCopy, len(relationships))
+	for i := 0; i < len(relationships); i++ {
+		c.CrawlerData.RemoteCopies[i] = relationships[i].ToRemoteCopy(now)
+	}
+	rcgroups, err := ibmRowList(c.Commander, "lsrcconsistgrp", nil)
+	if err != nil {
+		c.Log.Errorf("[CLI]获取远程复制一致性组失败, error: %v", err)
+		return err
+	}
+	c.CrawlerData.RemoteCopyGroups = make([]*IbmRemoteCopyGroup, len(rcgroups))
+	for i := 0; i < len(rcgroups); i++ {
+		c.CrawlerData.RemoteCopyGroups[i] = rcgroups[i].ToRemoteCopyGroup(now)
+	}
+
+	return c.WriteCopySamples(now)
+}
+
+// GetHostMappings 获取主机映射
+func (c *IbmV7000) GetHostMappings() error {
+	c.Log.Debug("[CLI]获取主机映射")
+	maps, err := ibmRowList(c.Commander, "lshostvdiskmap", nil)
+	if err != nil {
+		c.Log.Errorf("[CLI]获取主机映射失败, error: %v", err)
+		return err
+	}
+	c.CrawlerData.HostMappings = make([]*IbmHostMapping, len(maps))
+	for i := 0; i < len(maps); i++ {
+		c.CrawlerData.HostMappings[i] = maps[i].ToHostMapping()
+	}
+	c.CrawlerData.SetVolumeMappings()
+	return nil
+}
+
+// SetVolumeMappings 根据主机映射设置卷的映射数量, lsvdisk的输出中没有映射信息
+func (h *IbmV7000CrawlerData) SetVolumeMappings() {
+	count := make(map[int64]int64)
+	for i := 0; i < len(h.HostMappings); i++ {
+		count[h.HostMappings[i].VdiskId]++
+	}
+	for i := 0; i < len(h.Volumes); i++ {
+		h.Volumes[i].HostMappings = count[h.Volumes[i].Id]
+		h.Volumes[i].IsMapped = h.Volumes[i].HostMappings > 0
+	}
+}
+
+// WriteCopySamples 写入FlashCopy进度, 远程复制进度和RPO延迟(辅助卷数据不一致时不写入),
+// 状态变化时序列不变, 状态单独写入值为1的_info样本
+func (c *IbmV7000) WriteCopySamples(now time.Time) error {
+	system := ""
+	if c.CrawlerData.System != nil {
+		system = c.CrawlerData.System.Name
+	}
+
+	samples := make([]*Sample, 0)
+	for i := 0; i < len(c.CrawlerData.FlashCopyMappings); i++ {
+		m := c.CrawlerData.FlashCopyMappings[i]
+		samples = append(samples, &Sample{
+			Metric: "ibm_v7000_flashcopy_progress_ratio",
+			Labels: map[string]string{
+				"system":  system,
+				"mapping": m.Name,
+				"group":   m.GroupName,
+			},
+			Value:     float64(m.Progress) * 0.01,
+			Timestamp: now.Unix(),
+		}, &Sample{
+			Metric: "ibm_v7000_flashcopy_info",
+			Labels: map[string]string{
+				"system":  system,
+				"mapping": m.Name,
+				"group":   m.GroupName,
+				"status":  m.Status,
+			},
+			Value:     1,
+			Timestamp: now.Unix(),
+		})
+	}
+	for i := 0; i < len(c.CrawlerData.RemoteCopies); i++ {
+		r := c.CrawlerData.RemoteCopies[i]
+		labels := map[string]string{
+			"system":       system,
+			"relationship": r.Name,
+			"group":        r.ConsistencyGroupName,
+			"copy_type":    r.CopyType,
+		}
+		samples = append(samples, &Sample{
+			Metric:    "ibm_v7000_remote_copy_progress_ratio",
+			Labels:    labels,
+			Value:     float64(r.Progress) * 0.01,
+			Timestamp: now.Unix(),
+		})
+		// 辅助卷数据不一致时没有RPO延迟, 不写入样本
+		if r.RpoLagSeconds >= 0 {
+			samples = append(samples, &Sample{
+				Metric:    "ibm_v7000_remote_copy_rpo_lag_seconds",
+				Labels:    labels,
+				Value:     float64(r.RpoLagSeconds),
+				Timestamp: now.Unix(),
+			})
+		}
+		samples = append(samples, &Sample{
+			Metric: "ibm_v7000_remote_copy_info",
+			Labels: map[string]string{
+				"system":       system,
+				"relationship": r.Name,
+				"group":        r.ConsistencyGroupName,
+				"copy_type":    r.CopyType,
+				"state":        r.State,
+				"primary":      r.Primary,
+			},
+			Value:     1,
+			Timestamp: now.Unix(),
+		})
+	}
+	for i := 0; i < len(c.CrawlerData.RemoteCopyGroups); i++ {
+		g := c.CrawlerData.RemoteCopyGroups[i]
+		if g.RpoLagSeconds >= 0 {
+			samples = append(samples, &Sample{
+				Metric: "ibm_v7000_remote_copy_group_rpo_lag_seconds",
+				Labels: map[string]string{
+					"system":    system,
+					"group":     g.Name,
+					"copy_type": g.CopyType,
+				},
+				Value:     float64(g.RpoLagSeconds),
+				Timestamp: now.Unix(),
+			})
+		}
+		samples = append(samples, &Sample{
+			Metric: "ibm_v7000_remote_copy_group_info",
+			Labels: map[string]string{
+				"system":    system,
+				"group":     g.Name,
+				"copy_type": g.CopyType,
+				"state":     g.State,
+				"primary":   g.Primary,
+			},
+			Value:     1,
+			Timestamp: now.Unix(),
+		})
+	}
+	if err := c.Sink.Write(samples); err != nil {
+		c.Log.Errorf("写入复制服务数据失败, error: %v", err)
+		return err
+	}
+	return nil
+}
+
+// CountCopyStatus 统计FlashCopy映射和远程复制关系的状态数量
+func (h *IbmV7000CrawlerData) CountCopyStatus() {
+	h.FlashCopyStatusCount = make(map[string]int64)
+	for i := 0; i < len(h.FlashCopyMappings); i++ {
+		h.FlashCopyStatusCount[h.FlashCopyMappings[i].Status]++
+	}
+	h.RemoteCopyStateCount = make(map[string]int64)
+	for i := 0; i < len(h.RemoteCopies); i++ {
+		h.RemoteCopyStateCount[h.RemoteCopies[i].State]++
+	}
+}
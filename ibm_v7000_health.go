@@ -0,0 +1,260 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// IbmSystemHealth 系统健康数据(HomeRPC.getSystemHealthData)
+type IbmSystemHealth struct {
+	Ports              []*IbmIOPort            `json:"ports"`
+	NodeAdapters       []*IbmNodeAdapter       `json:"nodeAdapters"`
+	NodeHardware       []*IbmNodeHardware      `json:"nodeHardware"`
+	EnclosureCanisters []*IbmEnclosureCanister `json:"enclosureCanisters"`
+
+	// 以下数据不在健康数据中, 单独查询(PhysicalRPC, 或lsenclosure等CLI命令)
+	Enclosures         []*IbmEnclosure        `json:"enclosures"`
+	EnclosurePSUs      []*IbmEnclosurePSU     `json:"enclosurePSUs"`
+	EnclosureBatteries []*IbmEnclosureBattery `json:"enclosureBatteries"`
+}
+
+// IbmIOPort 端口(IOPort)
+type IbmIOPort struct {
+	Id         string `json:"id"`
+	NodeId     int64  `json:"nodeId"`
+	NodeName   string `json:"nodeName"`
+	PortId     int64  `json:"portId"`
+	PortType   string `json:"portType"` // fc, iscsi, enclosure
+	Status     string `json:"status"`   // active, inactive_unconfigured, unconfigured, offline
+	PortSpeed  string `json:"portSpeed"`
+	Wwpn       string `json:"wwpn"`
+	ClusterUse string `json:"clusterUse"`
+}
+
+// IbmNodeAdapter 节点适配器, 配置与实际不一致时valid为no
+type IbmNodeAdapter struct {
+	Id         string `json:"id"`
+	NodeId     string `json:"nodeId"`
+	Configured string `json:"configured"`
+	Actual     string `json:"actual"`
+	Valid      string `json:"valid"`
+}
+
+// IbmNodeHardware 节点硬件(NodeHardwareBean)
+type IbmNodeHardware struct {
+	Id           int64  `json:"id"`
+	Name         string `json:"name"`
+	IoGroupName  string `json:"ioGroupName"`
+	Status       string `json:"status"`
+	MemoryActual int64  `json:"memoryActual"` // 内存(GB)
+	CpuCount     int64  `json:"cpuCount"`
+	ActualValid  bool   `json:"actualValid"` // 实际硬件与配置是否一致
+}
+
+// IbmEnclosureCanister 机柜控制器(EnclosureCanisterBeanExtended)
+type IbmEnclosureCanister struct {
+	EnclosureId   int64  `json:"enclosureId"`
+	CanisterId    int64  `json:"canisterId"`
+	NodeId        int64  `json:"nodeId"`
+	NodeName      string `json:"nodeName"`
+	Type          string `json:"type"`
+	Status        string `json:"status"`
+	SesStatus     string `json:"sesStatus"`
+	FaultLed      string `json:"faultLed"`
+	Temperature   int64  `json:"temperature"` // 温度(摄氏度)
+	FirmwareLevel string `json:"firmwareLevel"`
+}
+
+// IbmEnclosure 机柜(lsenclosure)
+type IbmEnclosure struct {
+	Id              int64  `json:"id"`
+	Status          string `json:"status"` // online, offline, degraded
+	Type            string `json:"type"`   // control, expansion
+	ProductMTM      string `json:"productMTM"`
+	SerialNumber    string `json:"serialNumber"`
+	TotalCanisters  int64  `json:"totalCanisters"`
+	OnlineCanisters int64  `json:"onlineCanisters"`
+	TotalPSUs       int64  `json:"totalPSUs"`
+	OnlinePSUs      int64  `json:"onlinePSUs"`
+	DriveSlots      int64  `json:"driveSlots"`
+}
+
+// IbmEnclosurePSU 机柜电源(lsenclosurepsu)
+type IbmEnclosurePSU struct {
+	EnclosureId int64  `json:"enclosureId"`
+	PsuId       int64  `json:"psuId"`
+	Status      string `json:"status"` // online, offline, degraded
+	InputPower  string `json:"inputPower"`
+}
+
+// IbmEnclosureBattery 机柜电池(lsenclosurebattery)
+type IbmEnclosureBattery struct {
+	EnclosureId       int64  `json:"enclosureId"`
+	BatteryId         int64  `json:"batteryId"`
+	Status            string `json:"status"` // online, offline, degraded
+	ChargingStatus    string `json:"chargingStatus"`
+	ReconditionNeeded string `json:"reconditionNeeded"`
+	PercentCharged    int64  `json:"percentCharged"`
+	EndOfLifeWarning  string `json:"endOfLifeWarning"`
+}
+
+// IbmEvent 事件日志(lseventlog)
+type IbmEvent struct {
+	SequenceNumber int64  `json:"sequenceNumber"`
+	LastTimestamp  string `json:"lastTimestamp"` // YYMMDDHHMMSS
+	ObjectType     string `json:"objectType"`
+	ObjectId       string `json:"objectId"`
+	ObjectName     string `json:"objectName"`
+	Status         string `json:"status"` // alert, message, monitoring, expired
+	Fixed          string `json:"fixed"`  // yes, no
+	EventId        string `json:"eventId"`
+	ErrorCode      string `json:"errorCode"` // 需要处理的告警才有错误码
+	Description    string `json:"description"`
+}
+
+// GetEnclosureHealth 通过RPC接口获取机柜, 电源和电池状态, 需要在系统健康数据之后获取
+//
+// 这几个RPC方法没有抓包数据确认, 不同版本可能不存在, 获取失败时跳过, 不影响其他数据
+func (c *IbmV7000) GetEnclosureHealth() {
+	if c.CrawlerData.Health == nil {
+		c.CrawlerData.Health = new(IbmSystemHealth)
+	}
+	health := c.CrawlerData.Health
+
+	c.Log.Debug("[RPC]获取机柜状态")
+	if err := c.RequestRPC("com.ibm.svc.gui.logic.PhysicalRPC", "getEnclosures", nil, &health.Enclosures); err != nil {
+		c.Log.Warnf("[RPC]获取机柜状态失败, 跳过, error: %v", err)
+	}
+	c.Log.Debug("[RPC]获取机柜电源状态")
+	if err := c.RequestRPC("com.ibm.svc.gui.logic.PhysicalRPC", "getEnclosurePSUs", nil, &health.EnclosurePSUs); err != nil {
+		c.Log.Warnf("[RPC]获取机柜电源状态失败, 跳过, error: %v", err)
+	}
+	c.Log.Debug("[RPC]获取机柜电池状态")
+	if err := c.RequestRPC("com.ibm.svc.gui.logic.PhysicalRPC", "getEnclosureBatteries", nil, &health.EnclosureBatteries); err != nil {
+		c.Log.Warnf("[RPC]获取机柜电池状态失败, 跳过, error: %v", err)
+	}
+}
+
+// GetEventLog 通过RPC接口获取未修复的事件, 与CLI的 lseventlog -fixed no 相同
+//
+// RPC方法没有抓包数据确认, 获取失败时不比较告警状态, 避免把上次的告警全部记录为恢复
+func (c *IbmV7000) GetEventLog() error {
+	c.Log.Debug("[RPC]获取事件日志")
+
+	items := make([]*IbmEvent, 0)
+	if err := c.RequestRPC("com.ibm.svc.gui.logic.EventsRPC", "getUnfixedEvents", nil, &items); err != nil {
+		c.Log.Warnf("[RPC]获取事件日志失败, 跳过, error: %v", err)
+		return err
+	}
+	return c.SetEvents(items)
+}
+
+// SetEvents 保存未修复的告警事件, 与上次采集的告警比较生成告警产生和恢复事件
+func (c *IbmV7000) SetEvents(items []*IbmEvent) error {
+	current := make(map[int64]*IbmEvent)
+	c.CrawlerData.Events = make([]*IbmEvent, 0)
+	for i := 0; i < len(items); i++ {
+		if items[i].Fixed == "yes" || items[i].Status != "alert" {
+			continue
+		}
+		current[items[i].SequenceNumber] = items[i]
+		c.CrawlerData.Events = append(c.CrawlerData.Events, items[i])
+	}
+
+	previous := make(map[int64]*IbmEvent)
+	if data, err := ioutil.ReadFile(c.AlarmFile); err == nil {
+		alarms := make([]*IbmEvent, 0)
+		if err := json.Unmarshal(data, &alarms); err != nil {
+			c.Log.Errorf("解析告警状态文件失败, error: %v", err)
+		}
+		for i := 0; i < len(alarms); i++ {
+			previous[alarms[i].SequenceNumber] = alarms[i]
+		}
+	}
+
+	loc := c.SystemLocation()
+	events := make([]*Event, 0)
+	for seq, event := range current {
+		if _, ok := previous[seq]; !ok {
+			events = append(events, event.toEvent(EventAlarmRaised, parseIbmTimestamp(event.LastTimestamp, loc).Local()))
+		}
+	}
+	for seq, event := range previous {
+		if _, ok := current[seq]; !ok {
+			events = append(events, event.toEvent(EventAlarmCleared, time.Now()))
+		}
+	}
+	for i := 0; i < len(events); i++ {
+		c.Log.Warnf("告警事件[%s], 错误码: %s, 位置: %s, 描述: %s",
+			events[i].Type, events[i].Id, events[i].Location, events[i].Message)
+	}
+	if err := AppendEvents(c.EventFile, events); err != nil {
+		c.Log.Errorf("写入告警事件失败, error: %v", err)
+		return err
+	}
+
+	data, _ := json.Marshal(c.CrawlerData.Events)
+	if err := ioutil.WriteFile(c.AlarmFile, data, os.ModePerm); err != nil {
+		c.Log.Errorf("写入告警状态文件失败, error: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (e *IbmEvent) toEvent(eventType string, t time.Time) *Event {
+	return &Event{
+		Time:     t.Format("2006-01-02 15:04:05"),
+		Source:   "ibm",
+		Type:     eventType,
+		Id:       e.ErrorCode,
+		Level:    e.Status,
+		Location: e.ObjectType + ":" + e.ObjectName,
+		Message:  e.Description,
+	}
+}
+
+// parseIbmTimestamp 解析设备时间, 格式为YYMMDDHHMMSS, 如 210917001120, loc为设备的时区
+func parseIbmTimestamp(value string, loc *time.Location) time.Time {
+	t, err := time.ParseInLocation("060102150405", value, loc)
+	if err != nil {
+		return time.Now()
+	}
+	return t
+}
+
+// CountHealthStatus 统计各类组件的状态数量
+func (h *IbmV7000CrawlerData) CountHealthStatus() {
+	h.ComponentStatusCount = make(map[string]map[string]int64)
+	count := func(component, status string) {
+		if _, ok := h.ComponentStatusCount[component]; !ok {
+			h.ComponentStatusCount[component] = make(map[string]int64)
+		}
+		h.ComponentStatusCount[component][status]++
+	}
+	if h.Health != nil {
+		for i := 0; i < len(h.Health.Ports); i++ {
+			count("port_"+h.Health.Ports[i].PortType, h.Health.Ports[i].Status)
+		}
+		for i := 0; i < len(h.Health.NodeHardware); i++ {
+			count("node", h.Health.NodeHardware[i].Status)
+		}
+		for i := 0; i < len(h.Health.EnclosureCanisters); i++ {
+			count("canister", h.Health.EnclosureCanisters[i].Status)
+		}
+		for i := 0; i < len(h.Health.NodeAdapters); i++ {
+			count("adapter", "valid_"+h.Health.NodeAdapters[i].Valid)
+		}
+		for i := 0; i < len(h.Health.Enclosures); i++ {
+			count("enclosure", h.Health.Enclosures[i].Status)
+		}
+		for i := 0; i < len(h.Health.EnclosurePSUs); i++ {
+			count("psu", h.Health.EnclosurePSUs[i].Status)
+		}
+		for i := 0; i < len(h.Health.EnclosureBatteries); i++ {
+			count("battery", h.Health.EnclosureBatteries[i].Status)
+		}
+	}
+	h.UnfixedEventCount = int64(len(h.Events))
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// IbmRestClient Spectrum Virtualize的REST接口(8.1.3及以上版本), 每个CLI命令对应 POST /rest/<命令>
+//
+// 请求头X-Auth-Token为/rest/auth获取的令牌, 令牌空闲超时或被设备清理后返回403, 此时重新获取令牌后重试
+type IbmRestClient struct {
+	Address  string // https://host:7443
+	Username string
+	Password string
+
+	TokenFile string // 保存令牌, 设备限制了获取令牌的频率, 多次采集复用同一个令牌
+	Token     string
+
+	client *http.Client
+}
+
+func NewIbmRestClient(address, username, password string) *IbmRestClient {
+	r := new(IbmRestClient)
+	r.Address = address
+	r.Username = username
+	r.Password = password
+	r.client = &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+		Timeout: 60 * time.Second,
+	}
+	return r
+}
+
+// Auth 获取令牌
+func (r *IbmRestClient) Auth() error {
+	request, _ := http.NewRequest("POST", r.Address+"/rest/auth", nil)
+	request.Header.Set("X-Auth-Username", r.Username)
+	request.Header.Set("X-Auth-Password", r.Password)
+	resp, err := r.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("获取令牌失败, 错误码: %d, 错误信息: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	result := struct {
+		Token string `json:"token"`
+	}{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return err
+	}
+	if len(result.Token) == 0 {
+		return errors.New("响应数据中没有token")
+	}
+	r.Token = result.Token
+	if len(r.TokenFile) > 0 {
+		if err := ioutil.WriteFile(r.TokenFile, []byte(r.Token), 0600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Command 执行CLI命令并解析响应, params为命令参数, 如 {"bytes": true} 对应 -bytes
+//
+// 令牌无效时重新获取令牌并重试一次
+func (r *IbmRestClient) Command(command string, params map[string]interface{}, result interface{}) error {
+	if len(r.Token) == 0 && len(r.TokenFile) > 0 {
+		if token, err := ioutil.ReadFile(r.TokenFile); err == nil {
+			r.Token = strings.TrimSpace(string(token))
+		}
+	}
+	if len(r.Token) == 0 {
+		if err := r.Auth(); err != nil {
+			return err
+		}
+	}
+
+	status, body, err := r.post(command, params)
+	if err != nil {
+		return err
+	}
+	if status == http.StatusForbidden || status == http.StatusUnauthorized {
+		// 令牌过期
+		if err := r.Auth(); err != nil {
+			return err
+		}
+		if status, body, err = r.post(command, params); err != nil {
+			return err
+		}
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("命令[%s]执行失败, 错误码: %d, 错误信息: %s", command, status, strings.TrimSpace(string(body)))
+	}
+	return json.Unmarshal(body, result)
+}
+
+func (r *IbmRestClient) post(command string, params map[string]interface{}) (int, []byte, error) {
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	paramsJson, err := json.Marshal(params)
+	if err != nil {
+		return 0, nil, err
+	}
+	request, _ := http.NewRequest("POST", r.Address+"/rest/"+command, bytes.NewReader(paramsJson))
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("X-Auth-Token", r.Token)
+	resp, err := r.client.Do(request)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	body, err := ioutil.ReadAll(resp.Body)
+	return resp.StatusCode, body, err
+}
+
+func (r *IbmRestClient) Close() error {
+	return nil
+}
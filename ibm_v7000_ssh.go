@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// IbmShellDelim CLI输出的分隔符, 列表视图默认使用空格对齐, 值中包含空格时无法解析.
+// 不能使用冒号, iscsi_name(iqn.1986-03.com.ibm:2145...), IPv6地址和事件描述中都包含冒号, 会导致后面的列错位
+const IbmShellDelim = "!"
+
+// IbmShell 通过SSH执行CLI命令, 用于不支持REST接口的旧版本固件
+//
+// 每个命令使用单独的会话执行, 不需要交互式终端, 命令出错时在标准错误中输出CMMVC开头的错误信息
+type IbmShell struct {
+	Address  string // host:port
+	Username string
+	Password string
+	Timeout  time.Duration // 单个命令的超时时间
+
+	client *ssh.Client
+}
+
+func NewIbmShell(address, username, password string) *IbmShell {
+	s := new(IbmShell)
+	s.Address = address
+	s.Username = username
+	s.Password = password
+	s.Timeout = 60 * time.Second
+	return s
+}
+
+func (s *IbmShell) Connect() error {
+	auth := []ssh.AuthMethod{
+		ssh.Password(s.Password),
+		ssh.KeyboardInteractive(func(user, instruction string, questions []string, echos []bool) ([]string, error) {
+			answers := make([]string, len(questions))
+			for i := 0; i < len(questions); i++ {
+				answers[i] = s.Password
+			}
+			return answers, nil
+		}),
+	}
+	client, err := ssh.Dial("tcp", s.Address, &ssh.ClientConfig{
+		User:            s.Username,
+		Auth:            auth,
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         s.Timeout,
+	})
+	if err != nil {
+		return err
+	}
+	s.client = client
+	return nil
+}
+
+// Run 执行命令, 返回标准输出
+func (s *IbmShell) Run(command string) (string, error) {
+	if s.client == nil {
+		if err := s.Connect(); err != nil {
+			return "", err
+		}
+	}
+	session, err := s.client.NewSession()
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = session.Close()
+	}()
+	// 超时后关闭会话, Run返回错误
+	timer := time.AfterFunc(s.Timeout, func() {
+		_ = session.Close()
+	})
+	defer timer.Stop()
+
+	var stdout, stderr bytes.Buffer
+	session.Stdout = &stdout
+	session.Stderr = &stderr
+	if err := session.Run(command); err != nil {
+		if stderr.Len() > 0 {
+			return "", fmt.Errorf("命令[%s]执行失败, 错误信息: %s", command, strings.TrimSpace(stderr.String()))
+		}
+		return "", fmt.Errorf("命令[%s]执行失败, error: %v", command, err)
+	}
+	return stdout.String(), nil
+}
+
+// Command 将参数转换为命令行选项执行命令, 并解析为与REST接口相同的结果
+func (s *IbmShell) Command(command string, params map[string]interface{}, result interface{}) error {
+	// 命令/对象ID, 对象ID放在最后
+	name, object := command, ""
+	if idx := strings.Index(command, "/"); idx >= 0 {
+		name, object = command[:idx], command[idx+1:]
+	}
+	args := []string{name}
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for i := 0; i < len(keys); i++ {
+		switch value := params[keys[i]].(type) {
+		case bool:
+			if value {
+				args = append(args, "-"+keys[i])
+			}
+		default:
+			args = append(args, "-"+keys[i], fmt.Sprint(value))
+		}
+	}
+	args = append(args, "-delim", IbmShellDelim)
+	if len(object) > 0 {
+		args = append(args, object)
+	}
+
+	out, err := s.Run(strings.Join(args, " "))
+	if err != nil {
+		return err
+	}
+	switch r := result.(type) {
+	case *IbmRow:
+		*r = ParseIbmDetail(out)
+	case *[]IbmRow:
+		*r = ParseIbmList(out)
+	default:
+		return errors.New("解析目标必须是*IbmRow或*[]IbmRow")
+	}
+	return nil
+}
+
+func (s *IbmShell) Close() error {
+	if s.client != nil {
+		return s.client.Close()
+	}
+	return nil
+}
+
+func splitIbmLines(out string) []string {
+	lines := make([]string, 0)
+	all := strings.Split(strings.ReplaceAll(out, "\r\n", "\n"), "\n")
+	for i := 0; i < len(all); i++ {
+		if len(strings.TrimSpace(all[i])) > 0 {
+			lines = append(lines, all[i])
+		}
+	}
+	return lines
+}
+
+// ParseIbmList 解析列表视图的输出, 第一行为列名, 没有数据时不输出列名
+//
+// 命令使用 -delim ! 输出, iscsi_name等包含冒号的值不会被拆分. 最后一列(如lseventlog的description)
+// 中可能包含!, 超出列数的部分合并到最后一列
+func ParseIbmList(out string) []IbmRow {
+	rows := make([]IbmRow, 0)
+	lines := splitIbmLines(out)
+	if len(lines) == 0 {
+		return rows
+	}
+	header := strings.Split(lines[0], IbmShellDelim)
+	for i := 1; i < len(lines); i++ {
+		values := strings.SplitN(lines[i], IbmShellDelim, len(header))
+		row := IbmRow{}
+		for j := 0; j < len(values); j++ {
+			row[header[j]] = values[j]
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// ParseIbmDetail 解析详细视图的输出, 每行为 列名!值
+func ParseIbmDetail(out string) IbmRow {
+	row := IbmRow{}
+	lines := splitIbmLines(out)
+	for i := 0; i < len(lines); i++ {
+		parts := strings.SplitN(lines[i], IbmShellDelim, 2)
+		if len(parts) == 2 {
+			row[parts[0]] = parts[1]
+		}
+	}
+	return row
+}
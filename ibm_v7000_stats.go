@@ -0,0 +1,119 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type ibmStatUnit struct {
+	suffix string  // 指标名称后缀
+	scale  float64 // 换算为基本单位的系数
+}
+
+// ibmStatUnits statName最后一段为单位, mb: MB/s, io: IOPS, ms: 响应时间(毫秒), pc: 百分比
+var ibmStatUnits = map[string]ibmStatUnit{
+	"mb": {"bytes_per_second", 1024 * 1024},
+	"io": {"iops", 1},
+	"ms": {"latency_seconds", 0.001},
+	"pc": {"ratio", 0.01},
+}
+
+var ibmStatDirections = map[string]string{
+	"r": "read",
+	"w": "write",
+}
+
+// IbmStatMetric 将statName转换为指标名称和单位换算系数
+//
+// statName格式为 对象[_r|_w]_单位, 如 drive_w_mb -> ibm_v7000_drive_write_bytes_per_second,
+// write_cache_pc -> ibm_v7000_write_cache_ratio, 无法识别单位时保持原名称
+func IbmStatMetric(statName string) (string, float64) {
+	parts := strings.Split(statName, "_")
+	unit, ok := ibmStatUnits[parts[len(parts)-1]]
+	if !ok || len(parts) < 2 {
+		return "ibm_v7000_" + statName, 1
+	}
+	parts = parts[:len(parts)-1]
+	if direction, ok := ibmStatDirections[parts[len(parts)-1]]; ok && len(parts) > 1 {
+		parts[len(parts)-1] = direction
+	}
+	return "ibm_v7000_" + strings.Join(parts, "_") + "_" + unit.suffix, unit.scale
+}
+
+// IbmStatSamples 将实时性能转换为时序数据, 使用采样时间作为时间戳, 峰值使用_peak后缀和峰值时间单独输出
+func IbmStatSamples(stats []*IbmStat, labels map[string]string) []*Sample {
+	samples := make([]*Sample, 0, len(stats)*2)
+	for i := 0; i < len(stats); i++ {
+		stat := stats[i]
+		metric, scale := IbmStatMetric(stat.StatName)
+		samples = append(samples, &Sample{
+			Metric:    metric,
+			Labels:    labels,
+			Value:     float64(stat.StatCurrent) * scale,
+			Timestamp: stat.SampleEpoch,
+		}, &Sample{
+			Metric:    metric + "_peak",
+			Labels:    labels,
+			Value:     float64(stat.StatPeak) * scale,
+			Timestamp: stat.StatPeakEpoch,
+		})
+	}
+	return samples
+}
+
+// ibmNodeIds 节点ID按数值排序, 节点更换后ID可能不连续
+func ibmNodeIds(nodeStats map[string][]*IbmStat) []string {
+	ids := make([]string, 0, len(nodeStats))
+	for id := range nodeStats {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, _ := strconv.Atoi(ids[i])
+		b, _ := strconv.Atoi(ids[j])
+		if a != b {
+			return a < b
+		}
+		return ids[i] < ids[j]
+	})
+	return ids
+}
+
+// NodeNames 节点ID对应的节点名称, 来自系统健康数据中的节点硬件
+func (h *IbmV7000CrawlerData) NodeNames() map[string]string {
+	names := make(map[string]string)
+	if h.Health == nil {
+		return names
+	}
+	for i := 0; i < len(h.Health.NodeHardware); i++ {
+		node := h.Health.NodeHardware[i]
+		names[strconv.FormatInt(node.Id, 10)] = node.Name
+	}
+	return names
+}
+
+// WriteStatSamples 写入系统和各节点的实时性能
+func (c *IbmV7000) WriteStatSamples() error {
+	system := ""
+	if c.CrawlerData.System != nil {
+		system = c.CrawlerData.System.Name
+	}
+
+	samples := IbmStatSamples(c.CrawlerData.ClusterStats, map[string]string{
+		"system": system,
+	})
+	names := c.CrawlerData.NodeNames()
+	nodeIds := ibmNodeIds(c.CrawlerData.NodeStats)
+	for i := 0; i < len(nodeIds); i++ {
+		samples = append(samples, IbmStatSamples(c.CrawlerData.NodeStats[nodeIds[i]], map[string]string{
+			"system":    system,
+			"node_id":   nodeIds[i],
+			"node_name": names[nodeIds[i]],
+		})...)
+	}
+	if err := c.Sink.Write(samples); err != nil {
+		c.Log.Errorf("写入性能数据失败, error: %v", err)
+		return err
+	}
+	return nil
+}
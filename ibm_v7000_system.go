@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+	_ "time/tzdata" // Windows没有系统时区数据库, 内置时区数据用于加载设备时区
+)
+
+// IbmRPCResponse RPCAdapter接口的响应, result根据请求的方法为对象或数组
+type IbmRPCResponse struct {
+	Clazz    string          `json:"clazz"`
+	Messages interface{}     `json:"messages"`
+	Result   json.RawMessage `json:"result"`
+}
+
+// ParseIbmRPCResult 解析RPC响应中的result
+func ParseIbmRPCResult(data string, result interface{}) error {
+	resp := new(IbmRPCResponse)
+	if err := json.Unmarshal([]byte(data), resp); err != nil {
+		// 授权过期时返回登录页面
+		return err
+	}
+	if len(resp.Result) == 0 || string(resp.Result) == "null" {
+		return errors.New("响应数据中没有result")
+	}
+	return json.Unmarshal(resp.Result, result)
+}
+
+// IbmClusterSystem 系统信息(ClusterSystemBean), 容量单位为Byte
+type IbmClusterSystem struct {
+	Id                     string `json:"id"`
+	Name                   string `json:"name"`
+	ProductName            string `json:"productName"`
+	CodeLevel              string `json:"codeLevel"` // 软件版本
+	ConsoleIp              string `json:"consoleIp"`
+	TotalMdiskCapacity     int64  `json:"totalMdiskCapacity"`     // MDisk总容量
+	SpaceInMdiskGrps       int64  `json:"spaceInMdiskGrps"`       // 存储池容量
+	SpaceAllocatedToVdisks int64  `json:"spaceAllocatedToVdisks"` // 已分配给卷的容量
+	TotalFreeSpace         int64  `json:"totalFreeSpace"`
+	TotalUsedCapacity      int64  `json:"totalUsedCapacity"`
+	TotalVdiskCapacity     int64  `json:"totalVdiskCapacity"` // 卷的虚拟容量
+	TotalVdiskcopyCapacity int64  `json:"totalVdiskcopyCapacity"`
+	TotalOverallocation    int64  `json:"totalOverallocation"` // 超分配比例(%)
+	TotalDriveRawCapacity  int64  `json:"totalDriveRawCapacity"`
+	StatisticsFrequency    int64  `json:"statisticsFrequency"` // 性能统计间隔(分钟)
+	StatisticsStatus       string `json:"statisticsStatus"`
+	TimeZone               string `json:"timeZone"` // 设备时区, 如 522 UTC, 311 Asia/Shanghai
+}
+
+// Location 设备的时区, CLI输出中的时间(如freeze_time)为设备时区的本地时间
+func (s *IbmClusterSystem) Location() (*time.Location, error) {
+	fields := strings.Fields(s.TimeZone)
+	if len(fields) == 0 {
+		return nil, errors.New("没有时区信息")
+	}
+	return time.LoadLocation(fields[len(fields)-1])
+}
+
+// SystemLocation 设备的时区, 用于解析CLI输出中的时间, 无法识别时使用本机时区
+func (c *IbmV7000) SystemLocation() *time.Location {
+	if c.CrawlerData.System == nil {
+		return time.Local
+	}
+	loc, err := c.CrawlerData.System.Location()
+	if err != nil {
+		c.Log.Warnf("无法识别设备时区[%s], 使用本机时区, error: %v", c.CrawlerData.System.TimeZone, err)
+		return time.Local
+	}
+	return loc
+}
+
+// IbmPool 存储池(MDiskGroupBean)
+type IbmPool struct {
+	Id              int64  `json:"id"`
+	Name            string `json:"name"`
+	Status          string `json:"status"`
+	Type            string `json:"type"` // parent, child
+	MdiskCount      int64  `json:"mdiskCount"`
+	VdiskCount      int64  `json:"vdiskCount"`
+	Capacity        int64  `json:"capacity"`
+	FreeCapacity    int64  `json:"freeCapacity"`
+	UsedCapacity    int64  `json:"usedCapacity"`
+	RealCapacity    int64  `json:"realCapacity"`
+	VirtualCapacity int64  `json:"virtualCapacity"`
+	ExtentSize      int64  `json:"extentSize"`     // 区块大小(MB)
+	Overallocation  int64  `json:"overallocation"` // 超分配比例(%)
+	Warning         int64  `json:"warning"`        // 容量告警阈值(%)
+	EasyTier        string `json:"easyTier"`
+	EasyTierStatus  string `json:"easyTierStatus"`
+}
+
+// IbmStat 系统或节点的实时性能统计(ClusterStatsBean)
+type IbmStat struct {
+	StatName      string `json:"statName"`
+	SampleEpoch   int64  `json:"sampleEpoch"` // 采样时间(秒)
+	StatCurrent   int64  `json:"statCurrent"`
+	StatPeak      int64  `json:"statPeak"`
+	StatPeakTime  string `json:"statPeakTime"`
+	StatPeakEpoch int64  `json:"statPeakEpoch"`
+}
+
+// IbmHost 主机(HostBean)
+type IbmHost struct {
+	Id              int64  `json:"id"`
+	Name            string `json:"name"`
+	Type            string `json:"type"`
+	Status          string `json:"status"` // online, offline, degraded
+	PortCount       int64  `json:"portCount"`
+	IogrpCount      int64  `json:"iogrpCount"`
+	IsMapped        bool   `json:"isMapped"`
+	HostClusterId   int64  `json:"hostClusterId"`
+	HostClusterName string `json:"hostClusterName"`
+}
+
+// IbmDriveClass 磁盘类别(TBirdDriveClass), id为-1时表示所有磁盘的汇总
+type IbmDriveClass struct {
+	Id             string `json:"id"`
+	IoGrp          string `json:"ioGrp"`
+	TechType       string `json:"techType"`
+	Rpm            int64  `json:"rpm"`
+	BlockSize      int64  `json:"blockSize"`
+	Capacity       int64  `json:"capacity"` // 单个磁盘容量
+	TotalCapacity  int64  `json:"totalCapacity"`
+	SpareCapacity  int64  `json:"spareCapacity"`
+	MemberCapacity int64  `json:"memberCapacity"`
+}
+
+// IbmDrive 内部磁盘(TBirdDriveWithClassBean)
+type IbmDrive struct {
+	Id            int64  `json:"id"`
+	EnclosureId   int64  `json:"enclosureId"`
+	SlotId        int64  `json:"slotId"`
+	Status        string `json:"status"` // online, offline, degraded
+	Use           string `json:"use"`    // member, spare, candidate, failed, unused
+	TechType      string `json:"techType"`
+	Capacity      int64  `json:"capacity"`
+	Rpm           int64  `json:"rpm"`
+	VendorId      string `json:"vendorId"`
+	ProductId     string `json:"productId"`
+	FirmwareLevel string `json:"firmwareLevel"`
+	MdiskName     string `json:"mdiskName"`
+	DriveClassId  string `json:"driveClassId"`
+	Port1Status   string `json:"port1Status"`
+	Port2Status   string `json:"port2Status"`
+}
+
+// IbmPhysicalInternal 内部存储器信息(getInternalDriveInfo)
+type IbmPhysicalInternal struct {
+	Classes []*IbmDriveClass `json:"classes"`
+	Drives  []*IbmDrive      `json:"drives"`
+}
+
+// IbmVolumePageSize 分页查询卷时每页的行数, 与管理界面默认的分页大小相同
+const IbmVolumePageSize = 40
+
+// IbmVolume 卷(VDiskHierarchicalCopyBeanExtended), 每行为卷的一个副本, 镜像卷的其他副本在children中
+type IbmVolume struct {
+	Idty         string       `json:"idty"` // 行ID, 卷ID-副本ID
+	Id           int64        `json:"id"`
+	Name         string       `json:"name"`
+	VdiskUid     string       `json:"vdiskUid"`
+	Status       string       `json:"status"`
+	CopyStatus   string       `json:"copyStatus"`
+	CopyId       int64        `json:"copyId"`
+	IsPrimary    bool         `json:"isPrimary"`
+	MdiskGrpId   string       `json:"mdiskGrpId"`
+	MdiskGrpName string       `json:"mdiskGrpName"` // 所属存储池
+	IoGroupName  string       `json:"ioGroupName"`
+	Capacity     int64        `json:"capacity"`     // 虚拟容量(Byte)
+	UsedCapacity int64        `json:"usedCapacity"` // 已使用容量(Byte)
+	RealCapacity int64        `json:"realCapacity"` // 实际分配容量(Byte)
+	IsThin       bool         `json:"isThin"`
+	IsCompressed bool         `json:"isCompressed"`
+	IsMapped     bool         `json:"isMapped"`
+	HostMappings int64        `json:"hostMappings"`
+	CopyCount    int64        `json:"copyCount"`
+	Children     []*IbmVolume `json:"children"`
+}
+
+// IbmQueryReadStore 表格数据接口的响应, numRows和ids为所有行, items为当前分页的行
+type IbmQueryReadStore struct {
+	NumRows int64        `json:"numRows"`
+	Ids     []string     `json:"ids"`
+	Items   []*IbmVolume `json:"items"`
+}
+
+// CountStatus 统计主机和磁盘的状态数量
+func (h *IbmV7000CrawlerData) CountStatus() {
+	h.HostStatusCount = make(map[string]int64)
+	for i := 0; i < len(h.Hosts); i++ {
+		h.HostStatusCount[h.Hosts[i].Status]++
+	}
+	h.DriveStatusCount = make(map[string]int64)
+	for i := 0; i < len(h.Drives); i++ {
+		h.DriveStatusCount[h.Drives[i].Status]++
+	}
+}
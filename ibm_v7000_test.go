@@ -0,0 +1,719 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/crypto/ssh"
+)
+
+func readIbmExample(t *testing.T, name string) string {
+	data, err := ioutil.ReadFile("example/" + name)
+	if err != nil {
+		t.Fatalf("读取示例数据失败, error: %v", err)
+	}
+	return string(data)
+}
+
+func TestIbmV7000_ParseRPCResult(t *testing.T) {
+	h := new(IbmV7000CrawlerData)
+
+	h.System = new(IbmClusterSystem)
+	if err := ParseIbmRPCResult(readIbmExample(t, "monitor-system.txt"), h.System); err != nil {
+		t.Errorf("解析系统信息失败, error: %v", err)
+	}
+	if h.System.Name != "system-clouddesk" || h.System.SpaceInMdiskGrps != 17981954326528 || h.System.TotalFreeSpace != 2541546897408 {
+		t.Errorf("系统信息解析错误, %+v", h.System)
+	}
+
+	if err := ParseIbmRPCResult(readIbmExample(t, "physical-pools.txt"), &h.Pools); err != nil {
+		t.Errorf("解析存储池失败, error: %v", err)
+	}
+	if len(h.Pools) != 2 || h.Pools[0].Name != "vmpool1" || h.Pools[0].FreeCapacity != 827854946304 {
+		t.Errorf("存储池解析错误, %+v", h.Pools)
+	}
+
+	if err := ParseIbmRPCResult(readIbmExample(t, "cluster-states.txt"), &h.ClusterStats); err != nil {
+		t.Errorf("解析系统性能失败, error: %v", err)
+	}
+	if len(h.ClusterStats) == 0 || h.ClusterStats[0].StatName != "drive_w_mb" || h.ClusterStats[0].SampleEpoch != 1631808760 {
+		t.Errorf("系统性能解析错误, %+v", h.ClusterStats)
+	}
+
+	if err := ParseIbmRPCResult(readIbmExample(t, "hosts-all.txt"), &h.Hosts); err != nil {
+		t.Errorf("解析主机失败, error: %v", err)
+	}
+
+	internal := new(IbmPhysicalInternal)
+	if err := ParseIbmRPCResult(readIbmExample(t, "physical-internal.txt"), internal); err != nil {
+		t.Errorf("解析内部存储器失败, error: %v", err)
+	}
+	h.DriveClasses = internal.Classes
+	h.Drives = internal.Drives
+	if len(h.DriveClasses) != 3 || h.DriveClasses[0].TotalCapacity != 22791780827136 {
+		t.Errorf("磁盘类别解析错误, %+v", h.DriveClasses)
+	}
+
+	h.CountStatus()
+	if h.HostStatusCount["online"]+h.HostStatusCount["offline"] != 8 || h.HostStatusCount["offline"] == 0 {
+		t.Errorf("主机状态统计错误, %v", h.HostStatusCount)
+	}
+	if h.DriveStatusCount["online"] != 24 {
+		t.Errorf("磁盘状态统计错误, %v", h.DriveStatusCount)
+	}
+
+	if err := ParseIbmRPCResult("<html></html>", h.System); err == nil {
+		t.Errorf("非JSON响应应返回错误")
+	}
+}
+
+func TestIbmV7000_GetVolumes(t *testing.T) {
+	all := new(IbmQueryReadStore)
+	if err := json.Unmarshal([]byte(readIbmExample(t, "volumes.txt")), all); err != nil {
+		t.Fatalf("解析示例数据失败, error: %v", err)
+	}
+	// 示例数据只有第一页, 按ids补齐剩余的行
+	for i := len(all.Items); i < len(all.Ids); i++ {
+		all.Items = append(all.Items, &IbmVolume{Idty: all.Ids[i], Name: "vol_" + all.Ids[i]})
+	}
+
+	panelKeys := make(map[string]bool)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		panelKeys[r.PostForm.Get("panelKey")] = true
+		start, _ := strconv.Atoi(r.PostForm.Get("start"))
+		count, _ := strconv.Atoi(r.PostForm.Get("count"))
+		end := start + count
+		if end > len(all.Items) {
+			end = len(all.Items)
+		}
+		page := &IbmQueryReadStore{NumRows: all.NumRows, Ids: all.Ids, Items: all.Items[start:end]}
+		_ = json.NewEncoder(w).Encode(page)
+	}))
+	defer server.Close()
+
+	c := new(IbmV7000)
+	c.Log = zap.NewNop().Sugar()
+	c.Host = server.URL
+	c.CrawlerData = new(IbmV7000CrawlerData)
+	if err := c.GetVolumes(); err != nil {
+		t.Errorf("获取卷状态失败, error: %v", err)
+		return
+	}
+	if len(c.CrawlerData.Volumes) != 80 || c.CrawlerData.Volumes[79].Idty != "24-0" {
+		t.Errorf("分页查询卷数量错误, %d", len(c.CrawlerData.Volumes))
+	}
+	if v := c.CrawlerData.Volumes[0]; v.Name != "cbssitdb_vol_0" || v.Capacity != 214748364800 || v.MdiskGrpName != "vmpool2" {
+		t.Errorf("卷信息解析错误, %+v", v)
+	}
+	if len(panelKeys) != 1 {
+		t.Errorf("同一次查询应使用相同的panelKey, %v", panelKeys)
+	}
+}
+
+func TestIbmV7000_StatMetric(t *testing.T) {
+	cases := map[string]string{
+		"drive_w_mb":         "ibm_v7000_drive_write_bytes_per_second",
+		"vdisk_r_ms":         "ibm_v7000_vdisk_read_latency_seconds",
+		"vdisk_io":           "ibm_v7000_vdisk_iops",
+		"write_cache_pc":     "ibm_v7000_write_cache_ratio",
+		"iplink_comp_mb":     "ibm_v7000_iplink_comp_bytes_per_second",
+		"compression_cpu_pc": "ibm_v7000_compression_cpu_ratio",
+		"unknown_stat":       "ibm_v7000_unknown_stat",
+	}
+	for statName, expect := range cases {
+		if metric, _ := IbmStatMetric(statName); metric != expect {
+			t.Errorf("指标名称转换错误, %s -> %s", statName, metric)
+		}
+	}
+
+	stats := make([]*IbmStat, 0)
+	if err := ParseIbmRPCResult(readIbmExample(t, "node-states.txt"), &stats); err != nil {
+		t.Errorf("解析节点性能失败, error: %v", err)
+		return
+	}
+	samples := IbmStatSamples(stats, map[string]string{"node_id": "1"})
+	if len(samples) != len(stats)*2 {
+		t.Errorf("时序数据数量错误, %d", len(samples))
+		return
+	}
+	// write_cache_pc, statCurrent: 34
+	if s := samples[0]; s.Metric != "ibm_v7000_write_cache_ratio" || s.Value != 0.34 || s.Timestamp != 1631808195 || s.Labels["node_id"] != "1" {
+		t.Errorf("当前值转换错误, %+v", s)
+	}
+	if s := samples[1]; s.Metric != "ibm_v7000_write_cache_ratio_peak" || s.Timestamp != stats[0].StatPeakEpoch {
+		t.Errorf("峰值转换错误, %+v", s)
+	}
+}
+
+func TestIbmV7000_GetNodeStates(t *testing.T) {
+	nodeStats := readIbmExample(t, "node-states.txt")
+	queried := make([]int64, 0)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := struct {
+			MethodArgs []int64 `json:"methodArgs"`
+		}{}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		queried = append(queried, req.MethodArgs...)
+		_, _ = w.Write([]byte(nodeStats))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	c := new(IbmV7000)
+	c.Log = zap.NewNop().Sugar()
+	c.Host = server.URL
+	c.Sink = NewFileSink(dir + "/timeseries.json")
+	c.CrawlerData = new(IbmV7000CrawlerData)
+	c.CrawlerData.Health = &IbmSystemHealth{NodeHardware: []*IbmNodeHardware{
+		{Id: 12, Name: "node12"},
+		{Id: 3, Name: "node3"},
+	}}
+	if err := c.GetNodeStates(); err != nil {
+		t.Errorf("获取节点状态失败, error: %v", err)
+		return
+	}
+	if len(queried) != 2 || queried[0] != 12 || queried[1] != 3 {
+		t.Errorf("应只查询健康数据中的节点, %v", queried)
+	}
+	if ids := ibmNodeIds(c.CrawlerData.NodeStats); len(ids) != 2 || ids[0] != "3" || ids[1] != "12" {
+		t.Errorf("节点ID排序错误, %v", ids)
+	}
+
+	if err := c.WriteStatSamples(); err != nil {
+		t.Errorf("写入性能数据失败, error: %v", err)
+		return
+	}
+	data, _ := ioutil.ReadFile(dir + "/timeseries.json")
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	sample := new(Sample)
+	_ = json.Unmarshal([]byte(lines[0]), sample)
+	if sample.Labels["node_id"] != "3" || sample.Labels["node_name"] != "node3" {
+		t.Errorf("节点性能标签错误, %+v", sample)
+	}
+
+	// 健康数据中没有节点
+	c.CrawlerData.Health = new(IbmSystemHealth)
+	if err := c.GetNodeStates(); err == nil {
+		t.Errorf("没有节点时应返回错误")
+	}
+}
+
+func TestIbmV7000_SystemHealth(t *testing.T) {
+	// 示例文件第一行为请求参数, 最后一行为响应
+	lines := strings.Split(strings.TrimSpace(readIbmExample(t, "health-data.txt")), "\n")
+
+	h := new(IbmV7000CrawlerData)
+	h.Health = new(IbmSystemHealth)
+	if err := ParseIbmRPCResult(lines[len(lines)-1], h.Health); err != nil {
+		t.Errorf("解析系统健康数据失败, error: %v", err)
+		return
+	}
+	if len(h.Health.Ports) != 24 || len(h.Health.NodeHardware) != 2 || len(h.Health.EnclosureCanisters) != 2 {
+		t.Errorf("系统健康数据解析错误, %d, %d, %d", len(h.Health.Ports), len(h.Health.NodeHardware), len(h.Health.EnclosureCanisters))
+	}
+	if canister := h.Health.EnclosureCanisters[0]; canister.NodeName != "node1" || canister.Temperature != 49 {
+		t.Errorf("机柜控制器解析错误, %+v", canister)
+	}
+
+	h.CountHealthStatus()
+	if h.ComponentStatusCount["node"]["online"] != 2 || h.ComponentStatusCount["port_enclosure"]["offline"] == 0 {
+		t.Errorf("组件状态统计错误, %v", h.ComponentStatusCount)
+	}
+}
+
+func TestIbmV7000_RPCEnclosureAndEvents(t *testing.T) {
+	results := map[string]string{
+		"getEnclosures":         `[{"id":1,"status":"online","type":"control","productMtm":"2076-124","totalPsus":2,"onlinePsus":2},{"id":2,"status":"degraded","type":"expansion"}]`,
+		"getEnclosurePSUs":      `[{"enclosureId":1,"psuId":1,"status":"online"},{"enclosureId":2,"psuId":2,"status":"offline"}]`,
+		"getEnclosureBatteries": `[{"enclosureId":1,"batteryId":1,"status":"online","percentCharged":100}]`,
+		"getUnfixedEvents":      `[{"sequenceNumber":121,"lastTimestamp":"210917001200","objectType":"enclosure","objectId":"2","status":"alert","fixed":"no","errorCode":"1298","description":"A power supply unit is offline"},{"sequenceNumber":122,"status":"message","fixed":"no"}]`,
+	}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := struct {
+			MethodName string `json:"methodName"`
+		}{}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		result, ok := results[req.MethodName]
+		if !ok {
+			result = "null"
+		}
+		_, _ = w.Write([]byte(`{"clazz":"com.ibm.evo.rpc.RPCResponse","messages":null,"result":` + result + `}`))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	c := new(IbmV7000)
+	c.Log = zap.NewNop().Sugar()
+	c.Host = server.URL
+	c.AlarmFile = dir + "/alarm.json"
+	c.EventFile = dir + "/event.json"
+	c.CrawlerData = new(IbmV7000CrawlerData)
+	c.GetEnclosureHealth()
+	if err := c.GetEventLog(); err != nil {
+		t.Errorf("获取事件日志失败, error: %v", err)
+		return
+	}
+
+	h := c.CrawlerData
+	if e := h.Health.Enclosures[0]; e.ProductMTM != "2076-124" || e.TotalPSUs != 2 {
+		t.Errorf("机柜解析错误, %+v", e)
+	}
+	if len(h.Events) != 1 || h.Events[0].ErrorCode != "1298" || h.Events[0].ObjectType != "enclosure" {
+		t.Errorf("未修复告警解析错误, %+v", h.Events)
+	}
+	h.CountHealthStatus()
+	if h.ComponentStatusCount["enclosure"]["degraded"] != 1 || h.ComponentStatusCount["psu"]["offline"] != 1 ||
+		h.ComponentStatusCount["battery"]["online"] != 1 || h.UnfixedEventCount != 1 {
+		t.Errorf("组件状态统计错误, %v", h.ComponentStatusCount)
+	}
+	if data, _ := ioutil.ReadFile(c.EventFile); !strings.Contains(string(data), "1298") {
+		t.Errorf("告警事件未写入, %s", data)
+	}
+
+	// 设备不支持的方法跳过, 其他数据不受影响, 上次的告警不记录为恢复
+	delete(results, "getEnclosurePSUs")
+	delete(results, "getUnfixedEvents")
+	c.CrawlerData = new(IbmV7000CrawlerData)
+	c.GetEnclosureHealth()
+	if err := c.GetEventLog(); err == nil {
+		t.Errorf("方法不存在时应返回错误")
+	}
+	if h := c.CrawlerData.Health; len(h.Enclosures) != 2 || len(h.EnclosurePSUs) != 0 || len(h.EnclosureBatteries) != 1 {
+		t.Errorf("获取失败的数据应跳过, %+v", h)
+	}
+	if data, _ := ioutil.ReadFile(c.EventFile); strings.Contains(string(data), "alarm_cleared") {
+		t.Errorf("获取事件日志失败时不应记录告警恢复, %s", data)
+	}
+}
+
+func TestIbmV7000_SetEvents(t *testing.T) {
+	dir := t.TempDir()
+
+	c := new(IbmV7000)
+	c.Log = zap.NewNop().Sugar()
+	c.AlarmFile = dir + "/alarm.json"
+	c.EventFile = dir + "/event.json"
+	c.CrawlerData = new(IbmV7000CrawlerData)
+	// last_timestamp为设备时区的本地时间, 事件时间使用本机时区
+	c.CrawlerData.System = &IbmClusterSystem{TimeZone: "311 Asia/Shanghai"}
+	loc, _ := c.CrawlerData.System.Location()
+	raisedTime := time.Date(2021, 9, 17, 0, 11, 20, 0, loc).Local().Format("2006-01-02 15:04:05")
+
+	events := []*IbmEvent{
+		{SequenceNumber: 100, LastTimestamp: "210917001120", Status: "alert", Fixed: "no", ErrorCode: "1625", ObjectType: "node", ObjectName: "node1"},
+		{SequenceNumber: 101, Status: "alert", Fixed: "yes", ErrorCode: "1630"},
+		{SequenceNumber: 102, Status: "message", Fixed: "no"},
+	}
+	if err := c.SetEvents(events); err != nil {
+		t.Errorf("处理事件失败, error: %v", err)
+		return
+	}
+	if len(c.CrawlerData.Events) != 1 || c.CrawlerData.Events[0].SequenceNumber != 100 {
+		t.Errorf("未修复告警过滤错误, %+v", c.CrawlerData.Events)
+	}
+
+	// 告警被修复
+	if err := c.SetEvents(nil); err != nil {
+		t.Errorf("处理事件失败, error: %v", err)
+		return
+	}
+	data, _ := ioutil.ReadFile(c.EventFile)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], EventAlarmRaised) || !strings.Contains(lines[0], raisedTime) ||
+		!strings.Contains(lines[1], EventAlarmCleared) {
+		t.Errorf("告警事件错误, %v", lines)
+	}
+}
+
+func TestIbmV7000_Rest(t *testing.T) {
+	responses := map[string]string{
+		"lssystem":            `{"id":"000002006A20C9B6","name":"V7000","code_level":"8.2.1.11 (build 147.11.2004151010000)","total_mdisk_capacity":"16.35TB","total_overallocation":"85","statistics_frequency":"5"}`,
+		"lsmdiskgrp":          `[{"id":"0","name":"vmpool2","status":"online","capacity":"17979214479360","free_capacity":"2650469376000","type":"parent"}]`,
+		"lssystemstats":       `[{"stat_name":"vdisk_r_mb","stat_current":"12","stat_peak":"40","stat_peak_time":"210917001120"}]`,
+		"lsnodestats":         `[{"node_id":"1","node_name":"node1","stat_name":"cpu_pc","stat_current":"3","stat_peak":"5","stat_peak_time":"210917001120"}]`,
+		"lshost":              `[{"id":"0","name":"esxi01","port_count":"2","iogrp_count":"4","status":"online","host_cluster_id":"","host_cluster_name":""}]`,
+		"lsdrive":             `[{"id":"0","status":"online","use":"member","tech_type":"tier_enterprise","capacity":"1200000000000","enclosure_id":"1","slot_id":"3","drive_class_id":"0"},{"id":"1","status":"online","use":"spare","tech_type":"tier_enterprise","capacity":"1200000000000","enclosure_id":"1","slot_id":"4","drive_class_id":"0"}]`,
+		"lsdrive/0":           `{"id":"0","vendor_id":"IBM-E050","product_id":"ST1200MM0088","RPM":"10000","firmware_level":"B56S"}`,
+		"lsdriveclass":        `[{"id":"0","RPM":"10000","capacity":"1200000000000","tech_type":"tier_enterprise","block_size":"512","total_count":"1"}]`,
+		"lsvdisk":             `[{"id":"0","name":"cbssitdb_vol_0","IO_group_name":"io_grp0","status":"online","mdisk_grp_id":"0","mdisk_grp_name":"vmpool2","capacity":"214748364800","vdisk_UID":"6005076380810107C800000000000000","copy_count":"1","se_copy_count":"1","compressed_copy_count":"0"}]`,
+		"lsvdiskcopy":         `[{"vdisk_id":"0","vdisk_name":"cbssitdb_vol_0","copy_id":"0","status":"online","primary":"yes","mdisk_grp_id":"0","mdisk_grp_name":"vmpool2","capacity":"214748364800","se_copy":"yes","compressed_copy":"no"}]`,
+		"lssevdiskcopy":       `[{"vdisk_id":"0","copy_id":"0","used_capacity":"53687091200","real_capacity":"57982058496"}]`,
+		"lsportfc":            `[{"id":"0","port_id":"1","type":"fc","port_speed":"8Gb","node_id":"1","node_name":"node1","WWPN":"500507680C110B2F","status":"active","cluster_use":"local_partner"}]`,
+		"lsnodecanister":      `[]`,
+		"lsenclosure":         `[]`,
+		"lsenclosurecanister": `[]`,
+		"lsenclosurepsu":      `[]`,
+		"lsenclosurebattery":  `[]`,
+		"lsfcmap":             `[]`,
+		"lsfcconsistgrp":      `[]`,
+		"lsrcrelationship":    `[]`,
+		"lsrcconsistgrp":      `[]`,
+		"lshostvdiskmap":      `[]`,
+		"lseventlog":          `[{"sequence_number":"100","last_timestamp":"210917001120","object_type":"node","object_name":"node1","status":"alert","fixed":"no","error_code":"1625","description":"Incorrect configuration"}]`,
+	}
+	token, auths := "", 0
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/rest/auth" {
+			if r.Header.Get("X-Auth-Username") != "monitor" || r.Header.Get("X-Auth-Password") != "passw0rd" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			auths++
+			token = "token-" + strconv.Itoa(auths)
+			_, _ = w.Write([]byte(`{"token": "` + token + `"}`))
+			return
+		}
+		if r.Header.Get("X-Auth-Token") != token {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte("The token is invalid"))
+			return
+		}
+		resp, ok := responses[strings.TrimPrefix(r.URL.Path, "/rest/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(resp))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	c := new(IbmV7000)
+	c.Log = zap.NewNop().Sugar()
+	c.AlarmFile = dir + "/alarm.json"
+	c.EventFile = dir + "/event.json"
+	c.Sink = NewFileSink(dir + "/timeseries.json")
+	rest := NewIbmRestClient(server.URL, "monitor", "passw0rd")
+	rest.TokenFile = dir + "/token"
+	c.Commander = rest
+	c.CrawlerData = new(IbmV7000CrawlerData)
+
+	// 保存的令牌已过期, 需要重新获取
+	_ = ioutil.WriteFile(rest.TokenFile, []byte("expired"), 0644)
+	if err := c.StartCommand(); err != nil {
+		t.Errorf("REST接口采集失败, error: %v", err)
+		return
+	}
+	if auths != 1 {
+		t.Errorf("令牌过期后应重新获取一次, %d", auths)
+	}
+	if saved, _ := ioutil.ReadFile(rest.TokenFile); string(saved) != token {
+		t.Errorf("令牌保存错误, %s", saved)
+	}
+
+	h := c.CrawlerData
+	if h.System.Name != "V7000" || h.System.TotalMdiskCapacity>>30 != 16742 || h.System.TotalOverallocation != 85 {
+		t.Errorf("系统信息解析错误, %+v", h.System)
+	}
+	if len(h.Pools) != 1 || h.Pools[0].FreeCapacity != 2650469376000 {
+		t.Errorf("存储池解析错误, %+v", h.Pools)
+	}
+	if len(h.NodeStats["1"]) != 1 || h.ClusterStats[0].StatPeakEpoch != parseIbmTimestamp("210917001120", time.UTC).Unix() {
+		t.Errorf("性能数据解析错误, %+v", h.ClusterStats)
+	}
+	if v := h.Volumes[0]; v.Name != "cbssitdb_vol_0" || v.Capacity != 214748364800 || !v.IsThin || v.MdiskGrpName != "vmpool2" {
+		t.Errorf("卷信息解析错误, %+v", v)
+	}
+	if v := h.Volumes[0]; v.UsedCapacity != 53687091200 || v.RealCapacity != 57982058496 || v.CopyStatus != "online" {
+		t.Errorf("卷副本容量解析错误, %+v", v)
+	}
+	if len(h.Health.Ports) != 1 || h.Health.Ports[0].Wwpn != "500507680C110B2F" || h.Drives[0].SlotId != 3 {
+		t.Errorf("端口或磁盘解析错误, %+v", h.Health.Ports)
+	}
+	if h.Drives[0].Rpm != 10000 || h.Drives[0].FirmwareLevel != "B56S" || len(h.DriveClasses) != 1 || h.DriveClasses[0].MemberCapacity != 1200000000000 {
+		t.Errorf("磁盘详细信息或磁盘类别解析错误, %+v, %+v", h.Drives[0], h.DriveClasses)
+	}
+	// lsdrive/1 获取失败时保留列表视图的数据
+	if len(h.Drives) != 2 || h.Drives[1].SlotId != 4 || h.Drives[1].Rpm != 0 {
+		t.Errorf("磁盘详细信息获取失败时应保留列表视图的数据, %+v", h.Drives)
+	}
+	if len(h.Events) != 1 || h.Events[0].ErrorCode != "1625" {
+		t.Errorf("事件日志解析错误, %+v", h.Events)
+	}
+}
+
+func TestIbmV7000_ParseCLIOutput(t *testing.T) {
+	system := ParseIbmDetail(readIbmExample(t, "cli/lssystem.txt")).ToSystem()
+	if system.ConsoleIp != "7.3.20.15:443" || system.TotalFreeSpace != parseIbmSize("2.44TB") || system.StatisticsFrequency != 5 {
+		t.Errorf("详细视图解析错误, %+v", system)
+	}
+
+	events := ParseIbmList(readIbmExample(t, "cli/lseventlog.txt"))
+	if len(events) != 3 || events[0]["description"] != "Drive fault type 1: drive failed" || events[2]["status"] != "message" {
+		t.Errorf("列表视图解析错误, %v", events)
+	}
+	nodes := ParseIbmList(readIbmExample(t, "cli/lsnodecanister.txt"))
+	if n := nodes[1].ToNodeCanister(); n.Id != 2 || n.Status != "online" || n.IoGroupName != "io_grp0" {
+		t.Errorf("节点解析错误, %+v", n)
+	}
+	// iscsi_name中包含冒号, 后面的列不能错位
+	if nodes[0]["iscsi_name"] != "iqn.1986-03.com.ibm:2145.v7000.node1" || nodes[0]["panel_name"] != "01-1" || nodes[0]["enclosure_serial_number"] != "78G00H4" {
+		t.Errorf("值中包含冒号时列错位, %v", nodes[0])
+	}
+	if len(ParseIbmList("")) != 0 {
+		t.Errorf("没有数据时应返回空列表")
+	}
+
+	cases := map[string]int64{
+		"0.00MB":       0,
+		"512":          512,
+		"1.50GB":       1610612736,
+		"16.35TB":      17977015114137,
+		"214748364800": 214748364800,
+	}
+	for value, expect := range cases {
+		if size := parseIbmSize(value); size != expect {
+			t.Errorf("容量解析错误, %s -> %d", value, size)
+		}
+	}
+}
+
+func TestIbmV7000_Shell(t *testing.T) {
+	responses := make(map[string]string)
+	for _, command := range []string{"lssystem", "lssystemstats", "lsnodestats", "lshost", "lsportfc",
+		"lsnodecanister", "lsenclosure", "lsenclosurecanister", "lsenclosurepsu", "lsenclosurebattery",
+		"lsfcmap", "lsfcconsistgrp", "lsrcrelationship", "lsrcconsistgrp", "lshostvdiskmap"} {
+		responses[command+" -delim !"] = readIbmExample(t, "cli/"+command+".txt")
+	}
+	for _, command := range []string{"lsmdiskgrp", "lsdrive", "lsdriveclass", "lsvdisk", "lsvdiskcopy", "lssevdiskcopy"} {
+		responses[command+" -bytes -delim !"] = readIbmExample(t, "cli/"+command+".txt")
+	}
+	responses["lseventlog -fixed no -delim !"] = readIbmExample(t, "cli/lseventlog.txt")
+	// 详细视图, 所有对象使用相同的输出
+	for _, id := range []string{"0", "1", "2"} {
+		responses["lsdrive -bytes -delim ! "+id] = readIbmExample(t, "cli/lsdrive-detail.txt")
+	}
+	for _, id := range []string{"1", "2"} {
+		responses["lsnodehw -delim ! "+id] = readIbmExample(t, "cli/lsnodehw.txt")
+		responses["lsenclosurecanister -canister 1 -delim ! "+id] = readIbmExample(t, "cli/lsenclosurecanister-detail.txt")
+		responses["lsenclosurecanister -canister 2 -delim ! "+id] = readIbmExample(t, "cli/lsenclosurecanister-detail.txt")
+	}
+	address := startIbmShellServer(t, responses)
+
+	dir := t.TempDir()
+	c := new(IbmV7000)
+	c.Log = zap.NewNop().Sugar()
+	c.AlarmFile = dir + "/alarm.json"
+	c.EventFile = dir + "/event.json"
+	c.Sink = NewFileSink(dir + "/timeseries.json")
+	c.Commander = NewIbmShell(address, "monitor", "passw0rd")
+	c.CopyServices = true
+	c.CrawlerData = new(IbmV7000CrawlerData)
+	if err := c.StartCommand(); err != nil {
+		t.Errorf("SSH采集失败, error: %v", err)
+		return
+	}
+
+	h := c.CrawlerData
+	if h.System.Name != "V7000" || len(h.Pools) != 1 || h.Pools[0].Capacity != 17979214479360 {
+		t.Errorf("系统或存储池解析错误, %+v, %+v", h.System, h.Pools)
+	}
+	if len(h.Volumes) != 2 || !h.Volumes[0].IsThin || h.Volumes[1].IsThin {
+		t.Errorf("卷解析错误, %+v", h.Volumes)
+	}
+	if len(h.NodeStats["2"]) != 2 || len(h.ClusterStats) != 4 {
+		t.Errorf("性能数据解析错误, %+v", h.NodeStats)
+	}
+	if len(h.Events) != 2 {
+		t.Errorf("未修复告警数量错误, %+v", h.Events)
+	}
+	if len(h.FlashCopyMappings) != 2 || len(h.RemoteCopies) != 3 || len(h.RemoteCopyGroups) != 1 {
+		t.Errorf("复制服务解析错误, %+v, %+v", h.FlashCopyMappings, h.RemoteCopies)
+	}
+	if !h.Volumes[0].IsMapped || h.Volumes[0].HostMappings != 2 || h.Volumes[1].IsMapped {
+		t.Errorf("卷映射数量错误, %+v", h.Volumes)
+	}
+	if h.Volumes[0].UsedCapacity != 53687091200 || h.Volumes[1].UsedCapacity != 107374182400 || h.Volumes[1].RealCapacity != 107374182400 {
+		t.Errorf("卷已使用容量错误, %+v, %+v", h.Volumes[0], h.Volumes[1])
+	}
+	if h.Drives[1].VendorId != "IBM-E050" || h.Drives[1].Rpm != 10000 || h.DriveClasses[0].TotalCapacity != 3600000000000 ||
+		h.DriveClasses[0].MemberCapacity != 1200000000000 || h.DriveClasses[0].SpareCapacity != 1200000000000 {
+		t.Errorf("磁盘详细信息或磁盘类别错误, %+v, %+v", h.Drives[1], h.DriveClasses[0])
+	}
+	if n := h.Health.NodeHardware[0]; n.MemoryActual != 32 || n.CpuCount != 1 || !n.ActualValid {
+		t.Errorf("节点硬件解析错误, %+v", n)
+	}
+	if e := h.Health.EnclosureCanisters[0]; e.Temperature != 29 || e.FirmwareLevel != "30" || e.FaultLed != "off" {
+		t.Errorf("控制器详细信息解析错误, %+v", e)
+	}
+
+	h.CountStatus()
+	h.CountHealthStatus()
+	h.CountCopyStatus()
+	if h.FlashCopyStatusCount["copying"] != 1 || h.RemoteCopyStateCount["inconsistent_copying"] != 1 {
+		t.Errorf("复制状态统计错误, %v, %v", h.FlashCopyStatusCount, h.RemoteCopyStateCount)
+	}
+	// 状态只在_info样本中, 进度和RPO延迟的序列不随状态变化
+	data, _ := ioutil.ReadFile(dir + "/timeseries.json")
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	infos := 0
+	for i := 0; i < len(lines); i++ {
+		sample := new(Sample)
+		if err := json.Unmarshal([]byte(lines[i]), sample); err != nil {
+			t.Errorf("样本格式错误, %s", lines[i])
+			continue
+		}
+		_, hasState := sample.Labels["state"]
+		_, hasStatus := sample.Labels["status"]
+		if strings.HasSuffix(sample.Metric, "_info") {
+			infos++
+			if (!hasState && !hasStatus) || sample.Value != 1 {
+				t.Errorf("_info样本错误, %+v", sample)
+			}
+		} else if strings.Contains(sample.Metric, "copy") && (hasState || hasStatus) {
+			t.Errorf("复制服务指标不应包含状态标签, %+v", sample)
+		}
+	}
+	if infos != len(h.FlashCopyMappings)+len(h.RemoteCopies)+len(h.RemoteCopyGroups) {
+		t.Errorf("_info样本数量错误, %d", infos)
+	}
+	if h.DriveStatusCount["offline"] != 1 || h.HostStatusCount["degraded"] != 1 {
+		t.Errorf("状态统计错误, %v, %v", h.DriveStatusCount, h.HostStatusCount)
+	}
+	if h.ComponentStatusCount["psu"]["offline"] != 1 || h.ComponentStatusCount["enclosure"]["degraded"] != 1 ||
+		h.ComponentStatusCount["battery"]["online"] != 2 || h.ComponentStatusCount["canister"]["online"] != 4 {
+		t.Errorf("组件状态统计错误, %v", h.ComponentStatusCount)
+	}
+
+	// 命令出错
+	if _, err := NewIbmShell(address, "monitor", "passw0rd").Run("lsfabric"); err == nil || !strings.Contains(err.Error(), "CMMVC") {
+		t.Errorf("命令出错时应返回错误信息, %v", err)
+	}
+}
+
+// startIbmShellServer 启动本地SSH服务, 按命令返回固定的输出, 模拟设备的CLI
+func startIbmShellServer(t *testing.T, responses map[string]string) string {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if conn.User() == "monitor" && string(password) == "passw0rd" {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("密码错误")
+		},
+	}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, channels, requests, err := ssh.NewServerConn(conn, config)
+				if err != nil {
+					return
+				}
+				go ssh.DiscardRequests(requests)
+				for newChannel := range channels {
+					channel, requests, err := newChannel.Accept()
+					if err != nil {
+						return
+					}
+					go func() {
+						defer func() {
+							_ = channel.Close()
+						}()
+						for req := range requests {
+							if req.Type != "exec" {
+								_ = req.Reply(false, nil)
+								continue
+							}
+							payload := struct{ Command string }{}
+							_ = ssh.Unmarshal(req.Payload, &payload)
+							_ = req.Reply(true, nil)
+
+							status := struct{ Status uint32 }{}
+							if resp, ok := responses[payload.Command]; ok {
+								_, _ = channel.Write([]byte(resp))
+							} else {
+								_, _ = channel.Stderr().Write([]byte("CMMVC6051E An unsupported action was selected.\n"))
+								status.Status = 1
+							}
+							_, _ = channel.SendRequest("exit-status", false, ssh.Marshal(&status))
+							return
+						}
+					}()
+				}
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestIbmV7000_RemoteCopy(t *testing.T) {
+	// freeze_time为设备时区的本地时间
+	system := ParseIbmDetail(readIbmExample(t, "cli/lssystem.txt")).ToSystem()
+	if loc, err := system.Location(); err != nil || loc.String() != "UTC" {
+		t.Errorf("设备时区解析错误, %v, %v", loc, err)
+	}
+	loc, err := (&IbmClusterSystem{TimeZone: "311 Asia/Shanghai"}).Location()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2021, 9, 16, 16, 11, 20, 0, time.UTC).In(loc)
+	rows := ParseIbmList(readIbmExample(t, "cli/lsrcrelationship.txt"))
+	copies := make([]*IbmRemoteCopy, len(rows))
+	for i := 0; i < len(rows); i++ {
+		copies[i] = rows[i].ToRemoteCopy(now)
+	}
+	// 同步状态
+	if r := copies[0]; r.RpoLagSeconds != 0 || r.Progress != 100 || r.ConsistencyGroupName != "rccstgrp0" {
+		t.Errorf("同步状态解析错误, %+v", r)
+	}
+	// Global Mirror with Change Volumes, 辅助卷数据停留在freeze_time
+	if r := copies[1]; r.RpoLagSeconds != 380 || r.Progress != 76 || r.CyclingMode != "multi" {
+		t.Errorf("RPO延迟计算错误, %+v", r)
+	}
+	// 辅助卷数据不一致
+	if r := copies[2]; r.RpoLagSeconds != -1 || r.CopyType != "metro" {
+		t.Errorf("不一致状态解析错误, %+v", r)
+	}
+
+	// 辅助卷数据不一致时不写入RPO延迟样本
+	c := new(IbmV7000)
+	c.Log = zap.NewNop().Sugar()
+	sink := NewFileSink(t.TempDir() + "/timeseries.json")
+	c.Sink = sink
+	c.CrawlerData = &IbmV7000CrawlerData{RemoteCopies: copies}
+	if err := c.WriteCopySamples(now); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadFile(sink.Path)
+	if n := strings.Count(string(data), "ibm_v7000_remote_copy_rpo_lag_seconds"); n != 2 {
+		t.Errorf("RPO延迟样本数量错误, %d", n)
+	}
+
+	// 管理界面接口不支持复制服务, 启动时报错
+	if _, err := NewIbmV7000Crawler(IbmConfig{Transport: "rpc", CopyServices: true}); err == nil {
+		t.Errorf("rpc方式开启copy_services时应返回错误")
+	}
+}
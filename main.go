@@ -4,28 +4,42 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"time"
 )
 
 func init() {
 	if !isExist("cookie") {
 		_ = os.Mkdir("cookie", os.ModePerm)
 	}
+	if !isExist("data") {
+		_ = os.Mkdir("data", os.ModePerm)
+	}
 }
 
 func main() {
-	var ossType string
+	var (
+		ossType  string
+		backfill time.Duration
+	)
 	flag.StringVar(&ossType, "oss-type", "", "")
+	flag.DurationVar(&backfill, "backfill", 0, "补录指定时间范围的历史性能数据(仅华为), 例如: 1h")
 	flag.Parse()
 
 	if len(ossType) == 0 {
-		fmt.Printf("启动参数错误, 示例: oss-exporter --oss-type=ibm(huawei,hp,dell)")
+		fmt.Printf("启动参数错误, 示例: oss-exporter --oss-type=ibm(huawei,hp,dell,snmp,snmp-trap)")
+		return
+	}
+
+	conf, err := LoadConfig("config.toml")
+	if err != nil {
+		fmt.Printf("读取配置文件失败, %v", err)
 		return
 	}
 
 	switch ossType {
 	case "ibm":
 		// IBM存储设备数据抓取
-		if crawler, err := NewIbmV7000Crawler(); err != nil {
+		if crawler, err := NewIbmV7000Crawler(conf.Ibm); err != nil {
 			fmt.Printf("初始化IbmV7000任务失败, %v", err)
 			return
 		} else {
@@ -33,20 +47,38 @@ func main() {
 		}
 	case "huawei":
 		// 华为存储设备数据抓取
-		if crawler, err := NewHuaweiCrawler(); err != nil {
+		if crawler, err := NewHuaweiCrawler(conf.Huawei); err != nil {
 			fmt.Printf("初始化华为任务失败, %v", err)
 			return
+		} else if backfill > 0 {
+			crawler.StartBackfill(backfill)
 		} else {
 			crawler.Start()
 		}
 	case "hp":
 		// 惠普存储设备数据抓取
-		if crawler, err := NewHPCrawler(); err != nil {
+		if crawler, err := NewHPCrawler(conf.HP); err != nil {
 			fmt.Printf("初始化惠普任务失败, %v", err)
 			return
 		} else {
 			crawler.Start()
 		}
+	case "snmp":
+		// 通过SNMP抓取配置文件中的设备
+		if crawler, err := NewSnmpCrawler(conf.Snmp); err != nil {
+			fmt.Printf("初始化SNMP任务失败, %v", err)
+			return
+		} else {
+			crawler.Start()
+		}
+	case "snmp-trap":
+		// 接收配置文件中的设备发送的Trap, 持续运行
+		if receiver, err := NewSnmpTrapReceiver(conf.Snmp); err != nil {
+			fmt.Printf("初始化SNMP Trap任务失败, %v", err)
+			return
+		} else {
+			receiver.Start()
+		}
 	case "dell":
 		// 戴尔存储设备数据抓取
 		if crawler, err := NewDellCrawler(); err != nil {
@@ -0,0 +1,519 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/gosnmp/gosnmp"
+	"go.uber.org/zap"
+)
+
+// 组件的统一状态, 各厂商MIB中的状态值通过映射文件的status_maps转换
+const (
+	SnmpStatusOk      = "ok"
+	SnmpStatusWarning = "warning"
+	SnmpStatusFault   = "fault"
+	SnmpStatusUnknown = "unknown"
+)
+
+// snmpStatusValues 状态在时序数据中的值
+var snmpStatusValues = map[string]float64{
+	SnmpStatusOk:      0,
+	SnmpStatusWarning: 1,
+	SnmpStatusFault:   2,
+	SnmpStatusUnknown: 3,
+}
+
+// SnmpMapping 厂商的OID映射文件
+type SnmpMapping struct {
+	Vendor string `toml:"vendor"`
+	Mib    string `toml:"mib"`
+
+	System     map[string]string            `toml:"system"`      // 系统信息, 名称 -> 标量OID
+	StatusMaps map[string]map[string]string `toml:"status_maps"` // 状态映射, MIB中的状态值 -> 统一状态
+	Components []*SnmpComponentMapping      `toml:"components"`
+
+	Trap *SnmpTrapMapping `toml:"trap"` // Trap映射, 接收Trap时使用
+}
+
+// SnmpComponentMapping 组件的OID映射
+//
+// 表格组件的table为表格条目(Entry)的OID, 列的OID为 table.列号.索引, 每个索引为一个组件.
+// 标量组件(如整机状态)不配置table, 使用status_oid
+type SnmpComponentMapping struct {
+	Type         string                       `toml:"type"`
+	Table        string                       `toml:"table"`
+	NameColumn   int                          `toml:"name_column"` // 名称列, 未配置时使用索引
+	StatusColumn int                          `toml:"status_column"`
+	StatusOid    string                       `toml:"status_oid"`
+	StatusMap    string                       `toml:"status_map"` // status_maps中的名称
+	Metrics      map[string]SnmpMetricMapping `toml:"metrics"`    // 指标名称 -> 列
+}
+
+type SnmpMetricMapping struct {
+	Column int     `toml:"column"`
+	Scale  float64 `toml:"scale"` // 换算为基本单位的系数, 未配置时为1, 需要写成小数形式, 如 1048576.0
+}
+
+// LoadSnmpMapping 读取厂商的OID映射文件
+func LoadSnmpMapping(dir, vendor string) (*SnmpMapping, error) {
+	m := new(SnmpMapping)
+	if _, err := toml.DecodeFile(filepath.Join(dir, vendor+".toml"), m); err != nil {
+		return nil, err
+	}
+	for i := 0; i < len(m.Components); i++ {
+		component := m.Components[i]
+		if len(component.StatusMap) > 0 {
+			if _, ok := m.StatusMaps[component.StatusMap]; !ok {
+				return nil, fmt.Errorf("组件[%s]的状态映射[%s]不存在", component.Type, component.StatusMap)
+			}
+		}
+	}
+	return m, nil
+}
+
+// Status 将MIB中的状态值转换为统一状态
+func (m *SnmpMapping) Status(statusMap, value string) string {
+	if status, ok := m.StatusMaps[statusMap][value]; ok {
+		return status
+	}
+	return SnmpStatusUnknown
+}
+
+// SnmpComponent 组件(控制器, 磁盘, 电源等)
+type SnmpComponent struct {
+	Type      string             `json:"type"`
+	Index     string             `json:"index"`
+	Name      string             `json:"name"`
+	Status    string             `json:"status"`    // ok, warning, fault, unknown
+	RawStatus string             `json:"rawStatus"` // MIB中的状态值
+	Values    map[string]float64 `json:"values"`
+}
+
+// SnmpDeviceData 单个设备的采集结果
+type SnmpDeviceData struct {
+	Name    string `json:"name"`
+	Vendor  string `json:"vendor"`
+	Address string `json:"address"`
+	Error   string `json:"error"` // 采集失败的原因
+
+	System     map[string]string `json:"system"`
+	Components []*SnmpComponent  `json:"components"`
+
+	ComponentStatusCount map[string]map[string]int64 `json:"componentStatusCount"` // 各类组件各状态的数量
+}
+
+type SnmpCrawlerData struct {
+	Devices []*SnmpDeviceData `json:"devices"`
+}
+
+func (h *SnmpCrawlerData) PrintFile(path string) {
+	if data, err := json.Marshal(&h); err != nil {
+		log.Fatalln(err)
+	} else {
+		_ = os.Remove(path)
+		if err := ioutil.WriteFile(path, data, os.ModePerm); err != nil {
+			log.Fatalln(err)
+		}
+	}
+}
+
+type Snmp struct {
+	Log *zap.SugaredLogger
+
+	Conf SnmpConfig
+
+	TimeSeriesFile string
+
+	Sink TimeSeriesSink
+
+	CrawlerData *SnmpCrawlerData
+}
+
+func NewSnmpCrawler(conf SnmpConfig) (*Snmp, error) {
+	c := new(Snmp)
+
+	logger, err := NewLogger("snmp.log")
+	if err != nil {
+		return nil, err
+	}
+	c.Log = logger
+
+	c.Conf = conf
+
+	c.TimeSeriesFile = "data/snmp_timeseries.json"
+
+	c.Sink = NewFileSink(c.TimeSeriesFile)
+
+	c.CrawlerData = new(SnmpCrawlerData)
+
+	return c, nil
+}
+
+func (c *Snmp) Start() {
+	c.Log.Debug("通过SNMP抓取存储设备信息")
+
+	mappings := make(map[string]*SnmpMapping)
+	for i := 0; i < len(c.Conf.Devices); i++ {
+		device := c.Conf.Devices[i]
+		data := &SnmpDeviceData{Name: device.Name, Vendor: device.Vendor, Address: device.Address}
+		c.CrawlerData.Devices = append(c.CrawlerData.Devices, data)
+
+		mapping, ok := mappings[device.Vendor]
+		if !ok {
+			var err error
+			if mapping, err = LoadSnmpMapping(c.Conf.MappingDir, device.Vendor); err != nil {
+				c.Log.Errorf("读取OID映射文件失败, vendor: %s, error: %v", device.Vendor, err)
+				data.Error = err.Error()
+				_ = c.WriteSamples(data)
+				continue
+			}
+			mappings[device.Vendor] = mapping
+		}
+
+		// 单个设备失败不影响其他设备
+		if err := c.Collect(device, mapping, data); err != nil {
+			data.Error = err.Error()
+		}
+		_ = c.WriteSamples(data)
+	}
+
+	c.CrawlerData.PrintFile("snmp_text.txt")
+}
+
+// NewSnmpClient 根据设备配置创建SNMP连接参数
+func NewSnmpClient(device SnmpDevice, timeout time.Duration, retries int) (*gosnmp.GoSNMP, error) {
+	host, port := device.Address, uint16(161)
+	if h, p, err := net.SplitHostPort(device.Address); err == nil {
+		number, err := strconv.ParseUint(p, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("端口错误, %s", device.Address)
+		}
+		host, port = h, uint16(number)
+	}
+
+	client := &gosnmp.GoSNMP{
+		Target:  host,
+		Port:    port,
+		Timeout: timeout,
+		Retries: retries,
+		// 部分设备不支持较大的批量查询
+		MaxRepetitions: 20,
+	}
+	switch device.Version {
+	case "", "2c":
+		client.Version = gosnmp.Version2c
+		client.Community = device.Community
+	case "3":
+		client.Version = gosnmp.Version3
+		client.SecurityModel = gosnmp.UserSecurityModel
+		params, flags, err := device.UsmParameters()
+		if err != nil {
+			return nil, err
+		}
+		client.SecurityParameters = params
+		client.MsgFlags = flags
+	default:
+		return nil, fmt.Errorf("不支持的SNMP版本: %s", device.Version)
+	}
+	return client, nil
+}
+
+var snmpAuthProtocols = map[string]gosnmp.SnmpV3AuthProtocol{
+	"MD5":    gosnmp.MD5,
+	"SHA":    gosnmp.SHA,
+	"SHA224": gosnmp.SHA224,
+	"SHA256": gosnmp.SHA256,
+	"SHA384": gosnmp.SHA384,
+	"SHA512": gosnmp.SHA512,
+}
+
+var snmpPrivProtocols = map[string]gosnmp.SnmpV3PrivProtocol{
+	"DES":    gosnmp.DES,
+	"AES":    gosnmp.AES,
+	"AES192": gosnmp.AES192,
+	"AES256": gosnmp.AES256,
+}
+
+// UsmParameters SNMPv3的用户安全参数, 根据是否配置认证和加密协议确定安全级别
+func (d SnmpDevice) UsmParameters() (*gosnmp.UsmSecurityParameters, gosnmp.SnmpV3MsgFlags, error) {
+	params := &gosnmp.UsmSecurityParameters{
+		UserName:               d.Username,
+		AuthenticationProtocol: gosnmp.NoAuth,
+		PrivacyProtocol:        gosnmp.NoPriv,
+	}
+	if len(d.AuthProtocol) == 0 {
+		return params, gosnmp.NoAuthNoPriv, nil
+	}
+	auth, ok := snmpAuthProtocols[strings.ToUpper(d.AuthProtocol)]
+	if !ok {
+		return nil, 0, fmt.Errorf("不支持的认证协议: %s", d.AuthProtocol)
+	}
+	params.AuthenticationProtocol = auth
+	params.AuthenticationPassphrase = d.AuthPassword
+	if len(d.PrivProtocol) == 0 {
+		return params, gosnmp.AuthNoPriv, nil
+	}
+	priv, ok := snmpPrivProtocols[strings.ToUpper(d.PrivProtocol)]
+	if !ok {
+		return nil, 0, fmt.Errorf("不支持的加密协议: %s", d.PrivProtocol)
+	}
+	params.PrivacyProtocol = priv
+	params.PrivacyPassphrase = d.PrivPassword
+	return params, gosnmp.AuthPriv, nil
+}
+
+// Collect 按映射文件采集设备的系统信息和组件状态
+func (c *Snmp) Collect(device SnmpDevice, mapping *SnmpMapping, data *SnmpDeviceData) error {
+	c.Log.Debugf("采集设备[%s], 地址: %s", device.Name, device.Address)
+
+	client, err := NewSnmpClient(device, c.Conf.Timeout.Duration, c.Conf.Retries)
+	if err != nil {
+		c.Log.Errorf("设备[%s]配置错误, error: %v", device.Name, err)
+		return err
+	}
+	if err := client.Connect(); err != nil {
+		c.Log.Errorf("连接设备[%s]失败, error: %v", device.Name, err)
+		return err
+	}
+	defer func() {
+		_ = client.Conn.Close()
+	}()
+
+	// 系统信息
+	data.System = make(map[string]string)
+	if len(mapping.System) > 0 {
+		names := make(map[string]string)
+		oids := make([]string, 0, len(mapping.System))
+		for name, oid := range mapping.System {
+			names[snmpOid(oid)] = name
+			oids = append(oids, oid)
+		}
+		result, err := client.Get(oids)
+		if err != nil {
+			c.Log.Errorf("获取设备[%s]系统信息失败, error: %v", device.Name, err)
+			return err
+		}
+		for i := 0; i < len(result.Variables); i++ {
+			pdu := result.Variables[i]
+			if name, ok := names[snmpOid(pdu.Name)]; ok {
+				if value, ok := snmpValue(pdu); ok {
+					data.System[name] = value
+				}
+			}
+		}
+	}
+
+	// 组件
+	data.Components = make([]*SnmpComponent, 0)
+	for i := 0; i < len(mapping.Components); i++ {
+		component := mapping.Components[i]
+		var components []*SnmpComponent
+		if len(component.Table) > 0 {
+			components, err = c.collectTable(client, mapping, component)
+		} else {
+			components, err = c.collectScalar(client, mapping, component)
+		}
+		if err != nil {
+			c.Log.Errorf("获取设备[%s]的组件[%s]失败, error: %v", device.Name, component.Type, err)
+			return err
+		}
+		data.Components = append(data.Components, components...)
+	}
+	data.CountStatus()
+	return nil
+}
+
+func (c *Snmp) collectScalar(client *gosnmp.GoSNMP, mapping *SnmpMapping, component *SnmpComponentMapping) ([]*SnmpComponent, error) {
+	if len(component.StatusOid) == 0 {
+		return nil, fmt.Errorf("组件[%s]没有配置table或status_oid", component.Type)
+	}
+	result, err := client.Get([]string{component.StatusOid})
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Variables) == 0 {
+		return nil, nil
+	}
+	value, ok := snmpValue(result.Variables[0])
+	if !ok {
+		// 设备不支持该OID
+		return nil, nil
+	}
+	return []*SnmpComponent{{
+		Type:      component.Type,
+		Index:     "0",
+		Name:      component.Type,
+		Status:    mapping.Status(component.StatusMap, value),
+		RawStatus: value,
+		Values:    map[string]float64{},
+	}}, nil
+}
+
+func (c *Snmp) collectTable(client *gosnmp.GoSNMP, mapping *SnmpMapping, component *SnmpComponentMapping) ([]*SnmpComponent, error) {
+	pdus, err := client.BulkWalkAll(component.Table)
+	if err != nil {
+		return nil, err
+	}
+	indexes, rows := SnmpTableRows(component.Table, pdus)
+
+	components := make([]*SnmpComponent, 0, len(indexes))
+	for i := 0; i < len(indexes); i++ {
+		index := indexes[i]
+		row := rows[index]
+		item := &SnmpComponent{
+			Type:   component.Type,
+			Index:  index,
+			Name:   index,
+			Status: SnmpStatusUnknown,
+			Values: make(map[string]float64),
+		}
+		if name, ok := row[component.NameColumn]; ok && len(name) > 0 {
+			item.Name = name
+		}
+		if status, ok := row[component.StatusColumn]; ok {
+			item.RawStatus = status
+			item.Status = mapping.Status(component.StatusMap, status)
+		}
+		for name, metric := range component.Metrics {
+			value, ok := row[metric.Column]
+			if !ok {
+				continue
+			}
+			number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil {
+				continue
+			}
+			if metric.Scale != 0 {
+				number *= metric.Scale
+			}
+			item.Values[name] = number
+		}
+		components = append(components, item)
+	}
+	return components, nil
+}
+
+// SnmpTableRows 将表格的遍历结果按索引分组, 返回索引(按遍历顺序)和每个索引的 列号 -> 值
+func SnmpTableRows(table string, pdus []gosnmp.SnmpPDU) ([]string, map[string]map[int]string) {
+	prefix := snmpOid(table) + "."
+	indexes := make([]string, 0)
+	rows := make(map[string]map[int]string)
+	for i := 0; i < len(pdus); i++ {
+		pdu := pdus[i]
+		name := snmpOid(pdu.Name)
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		parts := strings.SplitN(strings.TrimPrefix(name, prefix), ".", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		column, err := strconv.Atoi(parts[0])
+		if err != nil {
+			continue
+		}
+		value, ok := snmpValue(pdu)
+		if !ok {
+			continue
+		}
+		index := parts[1]
+		if _, ok := rows[index]; !ok {
+			rows[index] = make(map[int]string)
+			indexes = append(indexes, index)
+		}
+		rows[index][column] = value
+	}
+	return indexes, rows
+}
+
+// snmpOid 去掉OID开头的点, gosnmp返回的OID以点开头
+func snmpOid(oid string) string {
+	return strings.TrimPrefix(oid, ".")
+}
+
+// snmpValue 将PDU的值转换为字符串, 对象不存在时返回false
+func snmpValue(pdu gosnmp.SnmpPDU) (string, bool) {
+	switch pdu.Type {
+	case gosnmp.NoSuchObject, gosnmp.NoSuchInstance, gosnmp.EndOfMibView, gosnmp.Null:
+		return "", false
+	case gosnmp.OctetString:
+		value, _ := pdu.Value.([]byte)
+		return strings.TrimRight(string(value), "\x00"), true
+	case gosnmp.ObjectIdentifier, gosnmp.IPAddress:
+		return snmpOid(fmt.Sprint(pdu.Value)), true
+	default:
+		return gosnmp.ToBigInt(pdu.Value).String(), true
+	}
+}
+
+// CountStatus 统计各类组件各状态的数量
+func (d *SnmpDeviceData) CountStatus() {
+	d.ComponentStatusCount = make(map[string]map[string]int64)
+	for i := 0; i < len(d.Components); i++ {
+		component := d.Components[i]
+		if _, ok := d.ComponentStatusCount[component.Type]; !ok {
+			d.ComponentStatusCount[component.Type] = make(map[string]int64)
+		}
+		d.ComponentStatusCount[component.Type][component.Status]++
+	}
+}
+
+// WriteSamples 写入设备的采集状态, 组件状态和组件指标
+//
+// 组件状态为 snmp_component_status, 值为 0: ok, 1: warning, 2: fault, 3: unknown; 组件指标为 snmp_<组件类型>_<指标名称>
+func (c *Snmp) WriteSamples(data *SnmpDeviceData) error {
+	now := time.Now().Unix()
+	up := 1.0
+	if len(data.Error) > 0 {
+		up = 0
+	}
+	samples := []*Sample{{
+		Metric:    "snmp_up",
+		Labels:    map[string]string{"device": data.Name, "vendor": data.Vendor},
+		Value:     up,
+		Timestamp: now,
+	}}
+	for i := 0; i < len(data.Components); i++ {
+		component := data.Components[i]
+		labels := map[string]string{
+			"device":    data.Name,
+			"vendor":    data.Vendor,
+			"component": component.Type,
+			"index":     component.Index,
+			"name":      component.Name,
+		}
+		statusLabels := map[string]string{"status": component.Status}
+		for key, value := range labels {
+			statusLabels[key] = value
+		}
+		samples = append(samples, &Sample{
+			Metric:    "snmp_component_status",
+			Labels:    statusLabels,
+			Value:     snmpStatusValues[component.Status],
+			Timestamp: now,
+		})
+		for name, value := range component.Values {
+			samples = append(samples, &Sample{
+				Metric:    "snmp_" + component.Type + "_" + name,
+				Labels:    labels,
+				Value:     value,
+				Timestamp: now,
+			})
+		}
+	}
+	if err := c.Sink.Write(samples); err != nil {
+		c.Log.Errorf("写入设备[%s]的时序数据失败, error: %v", data.Name, err)
+		return err
+	}
+	return nil
+}
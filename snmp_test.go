@@ -0,0 +1,543 @@
+package main
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"go.uber.org/zap"
+)
+
+type snmpRecord struct {
+	oid []int
+	pdu gosnmp.SnmpPDU
+}
+
+func parseSnmpOid(oid string) []int {
+	parts := strings.Split(strings.TrimPrefix(oid, "."), ".")
+	result := make([]int, len(parts))
+	for i := 0; i < len(parts); i++ {
+		result[i], _ = strconv.Atoi(parts[i])
+	}
+	return result
+}
+
+func compareSnmpOid(a, b []int) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return len(a) - len(b)
+}
+
+// loadSnmpRec 读取snmpsim格式的数据文件, 每行为 OID|类型|值
+func loadSnmpRec(t *testing.T, path string) []snmpRecord {
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("读取SNMP数据失败, error: %v", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	records := make([]snmpRecord, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), "|", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		pdu := gosnmp.SnmpPDU{Name: "." + parts[0]}
+		switch parts[1] {
+		case "2":
+			pdu.Type = gosnmp.Integer
+			pdu.Value, _ = strconv.Atoi(parts[2])
+		case "4":
+			pdu.Type = gosnmp.OctetString
+			pdu.Value = []byte(parts[2])
+		case "6":
+			pdu.Type = gosnmp.ObjectIdentifier
+			pdu.Value = parts[2]
+		case "65", "66", "67":
+			pdu.Type = map[string]gosnmp.Asn1BER{"65": gosnmp.Counter32, "66": gosnmp.Gauge32, "67": gosnmp.TimeTicks}[parts[1]]
+			value, _ := strconv.ParseUint(parts[2], 10, 32)
+			pdu.Value = uint32(value)
+		case "70":
+			pdu.Type = gosnmp.Counter64
+			pdu.Value, _ = strconv.ParseUint(parts[2], 10, 64)
+		default:
+			t.Fatalf("不支持的类型: %s", scanner.Text())
+		}
+		records = append(records, snmpRecord{oid: parseSnmpOid(parts[0]), pdu: pdu})
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return compareSnmpOid(records[i].oid, records[j].oid) < 0
+	})
+	return records
+}
+
+// startSnmpResponder 启动本地SNMP v2c应答服务, 模拟snmpsim按数据文件响应Get/GetNext/GetBulk请求
+func startSnmpResponder(t *testing.T, path, community string) string {
+	records := loadSnmpRec(t, path)
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	next := func(name string) gosnmp.SnmpPDU {
+		oid := parseSnmpOid(name)
+		for i := 0; i < len(records); i++ {
+			if compareSnmpOid(records[i].oid, oid) > 0 {
+				return records[i].pdu
+			}
+		}
+		return gosnmp.SnmpPDU{Name: name, Type: gosnmp.EndOfMibView}
+	}
+
+	go func() {
+		decoder := &gosnmp.GoSNMP{Version: gosnmp.Version2c}
+		buf := make([]byte, 65535)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			request, err := decoder.SnmpDecodePacket(buf[:n])
+			if err != nil || request.Community != community {
+				// 团体名错误时不响应
+				continue
+			}
+			variables := make([]gosnmp.SnmpPDU, 0)
+			for _, v := range request.Variables {
+				switch request.PDUType {
+				case gosnmp.GetRequest:
+					pdu := gosnmp.SnmpPDU{Name: v.Name, Type: gosnmp.NoSuchObject}
+					for i := 0; i < len(records); i++ {
+						if compareSnmpOid(records[i].oid, parseSnmpOid(v.Name)) == 0 {
+							pdu = records[i].pdu
+						}
+					}
+					variables = append(variables, pdu)
+				case gosnmp.GetNextRequest:
+					variables = append(variables, next(v.Name))
+				case gosnmp.GetBulkRequest:
+					repetitions := int(request.MaxRepetitions)
+					if repetitions == 0 {
+						repetitions = 10
+					}
+					name := v.Name
+					for i := 0; i < repetitions; i++ {
+						pdu := next(name)
+						variables = append(variables, pdu)
+						if pdu.Type == gosnmp.EndOfMibView {
+							break
+						}
+						name = pdu.Name
+					}
+				}
+			}
+			response := &gosnmp.SnmpPacket{
+				Version:   gosnmp.Version2c,
+				Community: request.Community,
+				PDUType:   gosnmp.GetResponse,
+				RequestID: request.RequestID,
+				Variables: variables,
+			}
+			if data, err := response.MarshalMsg(); err == nil {
+				_, _ = conn.WriteTo(data, addr)
+			}
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestSnmp_LoadMapping(t *testing.T) {
+	for _, vendor := range []string{"huawei", "hp", "dell", "ibm"} {
+		mapping, err := LoadSnmpMapping("snmp", vendor)
+		if err != nil {
+			t.Errorf("读取OID映射文件失败, vendor: %s, error: %v", vendor, err)
+			continue
+		}
+		if mapping.Vendor != vendor || len(mapping.Components) == 0 || mapping.Trap == nil || len(mapping.Trap.Types) == 0 {
+			t.Errorf("OID映射文件内容错误, %+v", mapping)
+		}
+	}
+	// connUnitTable的名称列为connUnitName(17), 20为connUnitContact
+	if mapping, err := LoadSnmpMapping("snmp", "hp"); err != nil || mapping.Components[0].Type != "unit" || mapping.Components[0].NameColumn != 17 {
+		t.Errorf("HP存储系统的名称列错误, %v", err)
+	}
+	if mapping, err := LoadSnmpMapping("snmp", "huawei"); err != nil || mapping.Status("health", "5") != SnmpStatusWarning ||
+		mapping.Status("health", "99") != SnmpStatusUnknown {
+		t.Errorf("状态映射错误")
+	}
+}
+
+func TestSnmp_Collect(t *testing.T) {
+	address := startSnmpResponder(t, "example/snmp/huawei.snmprec", "public")
+	mapping, err := LoadSnmpMapping("snmp", "huawei")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	c := new(Snmp)
+	c.Log = zap.NewNop().Sugar()
+	c.Conf.Timeout.Duration = 500 * time.Millisecond
+	c.Sink = NewFileSink(dir + "/timeseries.json")
+
+	device := SnmpDevice{Name: "oceanstor-01", Address: address, Vendor: "huawei", Version: "2c", Community: "public"}
+	data := &SnmpDeviceData{Name: device.Name, Vendor: device.Vendor}
+	if err := c.Collect(device, mapping, data); err != nil {
+		t.Errorf("SNMP采集失败, error: %v", err)
+		return
+	}
+	if data.System["name"] != "oceanstor-01" || data.System["version"] != "V500R007C60SPC300" {
+		t.Errorf("系统信息错误, %v", data.System)
+	}
+
+	components := make(map[string]*SnmpComponent)
+	for _, component := range data.Components {
+		components[component.Type+"/"+component.Name] = component
+	}
+	if s := components["system/system"]; s == nil || s.Status != SnmpStatusOk {
+		t.Errorf("整机状态错误, %+v", s)
+	}
+	if d := components["disk/CTE0.1"]; d == nil || d.Status != SnmpStatusFault || d.RawStatus != "2" {
+		t.Errorf("磁盘状态错误, %+v", d)
+	}
+	if c := components["controller/0B"]; c == nil || c.Values["cpu_usage_ratio"] != 0.09 {
+		t.Errorf("控制器指标错误, %+v", c)
+	}
+	if p := components["pool/StoragePool001"]; p == nil || p.Values["free_bytes"] != 5242880*1048576 {
+		t.Errorf("存储池容量错误, %+v", p)
+	}
+	if data.ComponentStatusCount["disk"][SnmpStatusOk] != 1 || data.ComponentStatusCount["disk"][SnmpStatusWarning] != 1 {
+		t.Errorf("组件状态统计错误, %v", data.ComponentStatusCount)
+	}
+
+	if err := c.WriteSamples(data); err != nil {
+		t.Errorf("写入时序数据失败, error: %v", err)
+		return
+	}
+	lines, _ := ioutil.ReadFile(dir + "/timeseries.json")
+	if !strings.Contains(string(lines), `"metric":"snmp_disk_temperature_celsius"`) ||
+		!strings.Contains(string(lines), `"metric":"snmp_up","labels":{"device":"oceanstor-01","vendor":"huawei"},"value":1`) {
+		t.Errorf("时序数据错误, %s", lines)
+	}
+
+	// 团体名错误时设备不响应
+	device.Community = "private"
+	c.Conf.Timeout.Duration = 100 * time.Millisecond
+	if err := c.Collect(device, mapping, &SnmpDeviceData{}); err == nil {
+		t.Errorf("团体名错误时应采集失败")
+	}
+}
+
+func TestSnmp_UsmParameters(t *testing.T) {
+	cases := []struct {
+		device SnmpDevice
+		flags  gosnmp.SnmpV3MsgFlags
+	}{
+		{SnmpDevice{Username: "monitor"}, gosnmp.NoAuthNoPriv},
+		{SnmpDevice{Username: "monitor", AuthProtocol: "sha", AuthPassword: "authpass"}, gosnmp.AuthNoPriv},
+		{SnmpDevice{Username: "monitor", AuthProtocol: "SHA256", AuthPassword: "authpass", PrivProtocol: "AES", PrivPassword: "privpass"}, gosnmp.AuthPriv},
+	}
+	for _, item := range cases {
+		if _, flags, err := item.device.UsmParameters(); err != nil || flags != item.flags {
+			t.Errorf("安全级别错误, %+v, %v, %v", item.device, flags, err)
+		}
+	}
+	if _, _, err := (SnmpDevice{AuthProtocol: "SHA1024"}).UsmParameters(); err == nil {
+		t.Errorf("不支持的认证协议应返回错误")
+	}
+	if _, err := NewSnmpClient(SnmpDevice{Address: "7.3.20.11", Version: "1"}, time.Second, 0); err == nil {
+		t.Errorf("不支持的版本应返回错误")
+	}
+}
+
+// captureSnmpTrap 使用client发送Trap, 返回接收到的报文
+func captureSnmpTrap(t *testing.T, client *gosnmp.GoSNMP, trapOid string, variables ...gosnmp.SnmpPDU) []byte {
+	return captureSnmpPacket(t, client, false, trapOid, variables...)
+}
+
+// captureSnmpPacket 使用client发送Trap或Inform, 返回接收到的报文, Inform不回复
+func captureSnmpPacket(t *testing.T, client *gosnmp.GoSNMP, inform bool, trapOid string, variables ...gosnmp.SnmpPDU) []byte {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	client.Target = "127.0.0.1"
+	client.Port = uint16(conn.LocalAddr().(*net.UDPAddr).Port)
+	client.Timeout = time.Second
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = client.Conn.Close()
+	}()
+	pdus := append([]gosnmp.SnmpPDU{{Name: snmpTrapOid, Type: gosnmp.ObjectIdentifier, Value: trapOid}}, variables...)
+	if inform {
+		// 等待回复直到连接关闭
+		go func() {
+			_, _ = client.SendTrap(gosnmp.SnmpTrap{Variables: pdus, IsInform: true})
+		}()
+	} else if _, err := client.SendTrap(gosnmp.SnmpTrap{Variables: pdus}); err != nil {
+		t.Fatalf("发送Trap失败, error: %v", err)
+	}
+
+	buf := make([]byte, 65535)
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("接收Trap失败, error: %v", err)
+	}
+	return buf[:n]
+}
+
+func newSnmpTrapReceiver(t *testing.T, devices ...SnmpDevice) *SnmpTrapReceiver {
+	dir := t.TempDir()
+	c := new(SnmpTrapReceiver)
+	c.Log = zap.NewNop().Sugar()
+	c.Conf.MappingDir = "snmp"
+	c.Conf.Devices = devices
+	c.EventFile = dir + "/event.json"
+	c.AlarmFile = dir + "/alarm.json"
+	c.TimeSeriesFile = dir + "/timeseries.json"
+	c.Sink = NewFileSink(c.TimeSeriesFile)
+	c.LoadDevices()
+	c.LoadAlarms()
+	return c
+}
+
+func TestSnmp_TrapV2c(t *testing.T) {
+	device := SnmpDevice{Name: "msa-01", Address: "127.0.0.1", Vendor: "hp", Version: "2c", Community: "public"}
+	c := newSnmpTrapReceiver(t, device)
+	source := &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 40162}
+
+	trap := func(community string, severity int) []byte {
+		client := &gosnmp.GoSNMP{Version: gosnmp.Version2c, Community: community}
+		return captureSnmpTrap(t, client, "1.3.6.1.3.94.0.4",
+			gosnmp.SnmpPDU{Name: "1.3.6.1.3.94.1.11.1.3.16.1.7", Type: gosnmp.OctetString, Value: []byte("B0201")},
+			gosnmp.SnmpPDU{Name: "1.3.6.1.3.94.1.11.1.6.16.1.7", Type: gosnmp.Integer, Value: severity},
+			gosnmp.SnmpPDU{Name: "1.3.6.1.3.94.1.11.1.8.16.1.7", Type: gosnmp.OctetString, Value: []byte("disk_01.05")},
+			gosnmp.SnmpPDU{Name: "1.3.6.1.3.94.1.11.1.9.16.1.7", Type: gosnmp.OctetString, Value: []byte("Disk failure detected")},
+		)
+	}
+
+	if _, err := c.HandlePacket(trap("public", 4), source); err != nil {
+		t.Fatalf("处理Trap失败, error: %v", err)
+	}
+	alarms := c.ActiveAlarms("msa-01")
+	if len(alarms) != 1 || alarms[0].Id != "B0201" || alarms[0].Level != "critical" || alarms[0].Source != "hp" ||
+		alarms[0].Location != "disk_01.05" || alarms[0].Message != "Disk failure detected" || alarms[0].Type != EventAlarmRaised {
+		t.Errorf("告警错误, %+v", alarms)
+	}
+	// 信息级别的Trap不作为告警
+	if _, err := c.HandlePacket(trap("public", 8), source); err != nil || len(c.ActiveAlarms("msa-01")) != 1 {
+		t.Errorf("信息级别的Trap处理错误, %v", err)
+	}
+
+	// 团体名错误, 来源地址未配置
+	if _, err := c.HandlePacket(trap("private", 4), source); err == nil {
+		t.Errorf("团体名错误的Trap应丢弃")
+	}
+	if _, err := c.HandlePacket(trap("public", 4), &net.UDPAddr{IP: net.ParseIP("7.3.20.11"), Port: 162}); err == nil {
+		t.Errorf("未配置设备的Trap应丢弃")
+	}
+
+	events, _ := ioutil.ReadFile(c.EventFile)
+	if strings.Count(string(events), "\n") != 2 || !strings.Contains(string(events), `"device":"msa-01","type":"event"`) {
+		t.Errorf("事件记录错误, %s", events)
+	}
+	lines, _ := ioutil.ReadFile(c.TimeSeriesFile)
+	if !strings.Contains(string(lines), `"metric":"snmp_trap_active_alarms","labels":{"device":"msa-01","level":"critical","vendor":"hp"},"value":1`) ||
+		!strings.Contains(string(lines), `"metric":"snmp_trap_received_total","labels":{"device":"msa-01","level":"info","vendor":"hp"},"value":1`) {
+		t.Errorf("时序数据错误, %s", lines)
+	}
+
+	// 重启后恢复未恢复的告警
+	restarted := newSnmpTrapReceiver(t, device)
+	restarted.AlarmFile = c.AlarmFile
+	restarted.LoadAlarms()
+	if len(restarted.ActiveAlarms("msa-01")) != 1 {
+		t.Errorf("读取告警状态失败, %v", restarted.Alarms)
+	}
+}
+
+func TestSnmp_TrapInform(t *testing.T) {
+	device := SnmpDevice{Name: "msa-01", Address: "127.0.0.1", Vendor: "hp", Version: "2c", Community: "public"}
+	c := newSnmpTrapReceiver(t, device)
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		_ = c.Serve(conn)
+		close(done)
+	}()
+
+	inform := func(community string) (*gosnmp.SnmpPacket, error) {
+		client := &gosnmp.GoSNMP{
+			Version:   gosnmp.Version2c,
+			Community: community,
+			Target:    "127.0.0.1",
+			Port:      uint16(conn.LocalAddr().(*net.UDPAddr).Port),
+			Timeout:   200 * time.Millisecond,
+		}
+		if err := client.Connect(); err != nil {
+			t.Fatal(err)
+		}
+		defer func() {
+			_ = client.Conn.Close()
+		}()
+		return client.SendTrap(gosnmp.SnmpTrap{IsInform: true, Variables: []gosnmp.SnmpPDU{
+			{Name: snmpTrapOid, Type: gosnmp.ObjectIdentifier, Value: "1.3.6.1.3.94.0.4"},
+			{Name: "1.3.6.1.3.94.1.11.1.3.16.1.7", Type: gosnmp.OctetString, Value: []byte("B0201")},
+			{Name: "1.3.6.1.3.94.1.11.1.6.16.1.7", Type: gosnmp.Integer, Value: 4},
+		}})
+	}
+	// 处理成功后回复Response, 团体名错误时不回复
+	if result, err := inform("public"); err != nil || result.PDUType != gosnmp.GetResponse {
+		t.Errorf("Inform没有回复, %v", err)
+	}
+	if _, err := inform("private"); err == nil {
+		t.Errorf("团体名错误的Inform不应回复")
+	}
+	_ = conn.Close()
+	<-done
+
+	// HP没有恢复Trap, 告警超过过期时间后自动清除
+	alarms := c.ActiveAlarms("msa-01")
+	if len(alarms) != 1 || len(alarms[0].ExpireTime) == 0 {
+		t.Fatalf("告警错误, %+v", alarms)
+	}
+	c.ExpireAlarms(time.Now().Add(23 * time.Hour))
+	if len(c.ActiveAlarms("msa-01")) != 1 {
+		t.Errorf("告警未过期时不应清除")
+	}
+	c.ExpireAlarms(time.Now().Add(25 * time.Hour))
+	if len(c.ActiveAlarms("msa-01")) != 0 {
+		t.Errorf("过期的告警未清除, %+v", c.Alarms)
+	}
+	events, _ := ioutil.ReadFile(c.EventFile)
+	if !strings.Contains(string(events), `"type":"alarm_cleared","id":"B0201"`) {
+		t.Errorf("事件记录错误, %s", events)
+	}
+	lines, _ := ioutil.ReadFile(c.TimeSeriesFile)
+	if !strings.Contains(string(lines), `"metric":"snmp_trap_active_alarms","labels":{"device":"msa-01","level":"critical","vendor":"hp"},"value":0`) {
+		t.Errorf("时序数据错误, %s", lines)
+	}
+}
+
+func TestSnmp_TrapV3(t *testing.T) {
+	engineId := "80001f8880e9630000d61ff449"
+	device := SnmpDevice{
+		Name: "oceanstor-01", Address: "127.0.0.1:161", Vendor: "huawei", Version: "3", Username: "trapuser",
+		AuthProtocol: "SHA", AuthPassword: "authpass123", PrivProtocol: "AES", PrivPassword: "privpass123", EngineId: engineId,
+	}
+	c := newSnmpTrapReceiver(t, device)
+	source := &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 40162}
+
+	trap := func(trapOid, authPassword string) []byte {
+		sender := device
+		sender.AuthPassword = authPassword
+		client, err := NewSnmpTrapClient(sender, time.Second, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return captureSnmpTrap(t, client, trapOid,
+			gosnmp.SnmpPDU{Name: "1.3.6.1.4.1.34774.4.1.3.1.2.0", Type: gosnmp.OctetString, Value: []byte("0xF00170013")},
+			gosnmp.SnmpPDU{Name: "1.3.6.1.4.1.34774.4.1.3.1.4.0", Type: gosnmp.Integer, Value: 3},
+			gosnmp.SnmpPDU{Name: "1.3.6.1.4.1.34774.4.1.3.1.6.0", Type: gosnmp.OctetString, Value: []byte("CTE0.A.IOM0.P1")},
+			gosnmp.SnmpPDU{Name: "1.3.6.1.4.1.34774.4.1.3.1.7.0", Type: gosnmp.OctetString, Value: []byte("The link to port is down")},
+		)
+	}
+
+	if _, err := c.HandlePacket(trap("1.3.6.1.4.1.34774.4.1.3.2", "authpass123"), source); err != nil {
+		t.Fatalf("处理Trap失败, error: %v", err)
+	}
+	alarms := c.ActiveAlarms("oceanstor-01")
+	if len(alarms) != 1 || alarms[0].Id != "0xF00170013" || alarms[0].Level != "major" || alarms[0].Location != "CTE0.A.IOM0.P1" {
+		t.Errorf("告警错误, %+v", alarms)
+	}
+
+	// 认证失败
+	if _, err := c.HandlePacket(trap("1.3.6.1.4.1.34774.4.1.3.3", "wrongpass123"), source); err == nil {
+		t.Errorf("认证失败的Trap应丢弃")
+	}
+
+	// 告警恢复
+	if _, err := c.HandlePacket(trap("1.3.6.1.4.1.34774.4.1.3.3", "authpass123"), source); err != nil {
+		t.Fatalf("处理Trap失败, error: %v", err)
+	}
+	if alarms := c.ActiveAlarms("oceanstor-01"); len(alarms) != 0 {
+		t.Errorf("告警未恢复, %+v", alarms)
+	}
+	events, _ := ioutil.ReadFile(c.EventFile)
+	if !strings.Contains(string(events), `"type":"alarm_raised"`) || !strings.Contains(string(events), `"type":"alarm_cleared"`) {
+		t.Errorf("事件记录错误, %s", events)
+	}
+	lines, _ := ioutil.ReadFile(c.TimeSeriesFile)
+	if !strings.Contains(string(lines), `"metric":"snmp_trap_active_alarms","labels":{"device":"oceanstor-01","level":"major","vendor":"huawei"},"value":0`) {
+		t.Errorf("时序数据错误, %s", lines)
+	}
+
+	// v3 Inform以接收端为权威引擎, 不支持
+	sender, err := NewSnmpTrapClient(device, time.Second, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inform := captureSnmpPacket(t, sender, true, "1.3.6.1.4.1.34774.4.1.3.2",
+		gosnmp.SnmpPDU{Name: "1.3.6.1.4.1.34774.4.1.3.1.2.0", Type: gosnmp.OctetString, Value: []byte("0xF00170014")})
+	if _, err := c.HandlePacket(inform, source); err == nil || !strings.Contains(err.Error(), "Inform") {
+		t.Errorf("SNMPv3 Inform应返回错误, %v", err)
+	}
+	if len(c.ActiveAlarms("oceanstor-01")) != 0 {
+		t.Errorf("SNMPv3 Inform不应记录告警")
+	}
+
+	if _, err := NewSnmpTrapClient(SnmpDevice{Version: "3", Username: "trapuser"}, time.Second, 0); err == nil {
+		t.Errorf("未配置引擎ID时应返回错误")
+	}
+}
+
+func TestSnmp_TrapUnknown(t *testing.T) {
+	mapping, err := LoadSnmpMapping("snmp", "ibm")
+	if err != nil || mapping.Trap == nil {
+		t.Fatalf("读取Trap映射失败, %v", err)
+	}
+	now := time.Now()
+	event := mapping.Trap.ToEvent("1.3.6.1.4.1.2.6.190.2", []gosnmp.SnmpPDU{
+		{Name: ".1.3.6.1.4.1.2.6.190.4.4", Type: gosnmp.OctetString, Value: []byte("1630")},
+		{Name: ".1.3.6.1.4.1.2.6.190.4.3", Type: gosnmp.OctetString, Value: []byte("Number of device logins reduced")},
+	}, now)
+	if event.Type != EventAlarmRaised || event.Level != "warning" || event.Id != "1630" {
+		t.Errorf("Trap转换错误, %+v", event)
+	}
+	// 未配置的Trap记录为事件, 描述为全部varbind
+	event = mapping.Trap.ToEvent("1.3.6.1.4.1.2.6.190.9", []gosnmp.SnmpPDU{
+		{Name: ".1.3.6.1.4.1.2.6.190.4.20", Type: gosnmp.OctetString, Value: []byte("node1")},
+	}, now)
+	if event.Type != EventLog || event.Id != "1.3.6.1.4.1.2.6.190.9" || event.Message != "1.3.6.1.4.1.2.6.190.4.20=node1" {
+		t.Errorf("Trap转换错误, %+v", event)
+	}
+}
@@ -0,0 +1,485 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"go.uber.org/zap"
+)
+
+// snmpTrapOid Trap中标识Trap类型的varbind(snmpTrapOID.0)
+const snmpTrapOid = "1.3.6.1.6.3.1.1.4.1.0"
+
+// SnmpTrapLevelCleared 映射为该级别的Trap为告警恢复
+const SnmpTrapLevelCleared = "cleared"
+
+// SnmpTrapMapping 厂商Trap的映射, 各字段为varbind的OID前缀(表格列的varbind带有索引)
+type SnmpTrapMapping struct {
+	AlarmId     string `toml:"alarm_id"`
+	Severity    string `toml:"severity"`
+	Description string `toml:"description"`
+	Location    string `toml:"location"`
+
+	SeverityMap map[string]string `toml:"severity_map"` // 级别值 -> critical, major, warning, info, cleared
+	Types       []*SnmpTrapType   `toml:"types"`
+}
+
+// SnmpTrapType Trap类型, 未配置的Trap作为普通事件记录
+type SnmpTrapType struct {
+	Oid   string `toml:"oid"`   // snmpTrapOID的值
+	Type  string `toml:"type"`  // alarm_raised, alarm_cleared, event
+	Level string `toml:"level"` // Trap中没有级别时使用
+
+	// 设备不发送恢复Trap时, 告警超过该时间没有再次收到自动清除, 不配置时一直保留到收到恢复Trap
+	Expire Duration `toml:"expire"`
+}
+
+// ToEvent 将Trap转换为事件, 级别映射为cleared时为告警恢复, 级别为info的告警作为普通事件
+func (m *SnmpTrapMapping) ToEvent(trapOid string, variables []gosnmp.SnmpPDU, now time.Time) *Event {
+	event := &Event{
+		Time:  now.Format("2006-01-02 15:04:05"),
+		Type:  EventLog,
+		Id:    trapOid,
+		Level: "info",
+	}
+	var expire time.Duration
+	for i := 0; i < len(m.Types); i++ {
+		t := m.Types[i]
+		if snmpOid(t.Oid) == trapOid {
+			event.Type = t.Type
+			if len(event.Type) == 0 {
+				event.Type = EventAlarmRaised
+			}
+			if len(t.Level) > 0 {
+				event.Level = t.Level
+			}
+			expire = t.Expire.Duration
+			break
+		}
+	}
+
+	others := make([]string, 0)
+	for i := 0; i < len(variables); i++ {
+		pdu := variables[i]
+		oid := snmpOid(pdu.Name)
+		value, ok := snmpValue(pdu)
+		if !ok || oid == snmpTrapOid || oid == "1.3.6.1.2.1.1.3.0" {
+			continue
+		}
+		switch {
+		case snmpOidHasPrefix(oid, m.AlarmId):
+			event.Id = value
+		case snmpOidHasPrefix(oid, m.Severity):
+			if level, ok := m.SeverityMap[value]; ok {
+				event.Level = level
+			}
+		case snmpOidHasPrefix(oid, m.Location):
+			event.Location = value
+		case snmpOidHasPrefix(oid, m.Description):
+			event.Message = value
+		default:
+			others = append(others, oid+"="+value)
+		}
+	}
+	// 没有描述时记录全部varbind, 便于补充映射
+	if len(event.Message) == 0 {
+		event.Message = strings.Join(others, "; ")
+	}
+
+	if event.Level == SnmpTrapLevelCleared {
+		event.Type = EventAlarmCleared
+		event.Level = "info"
+	} else if event.Type == EventAlarmRaised && event.Level == "info" {
+		event.Type = EventLog
+	}
+	if event.Type == EventAlarmRaised && expire > 0 {
+		event.ExpireTime = now.Add(expire).Format("2006-01-02 15:04:05")
+	}
+	return event
+}
+
+func snmpOidHasPrefix(oid, prefix string) bool {
+	prefix = snmpOid(prefix)
+	if len(prefix) == 0 {
+		return false
+	}
+	return oid == prefix || strings.HasPrefix(oid, prefix+".")
+}
+
+// snmpTrapDevice 接收Trap的设备, client用于解码和校验该设备发送的Trap
+type snmpTrapDevice struct {
+	Conf    SnmpDevice
+	Mapping *SnmpMapping
+
+	client *gosnmp.GoSNMP
+}
+
+// SnmpTrapReceiver 接收设备发送的Trap, 按来源地址对应到配置的设备, 转换为告警事件和时序数据
+//
+// 未配置的来源地址, 团体名或用户不匹配的Trap直接丢弃
+type SnmpTrapReceiver struct {
+	Log *zap.SugaredLogger
+
+	Conf SnmpConfig
+
+	EventFile      string
+	AlarmFile      string // 保存未恢复的告警, 重启后继续使用
+	TimeSeriesFile string
+
+	Sink TimeSeriesSink
+
+	Alarms map[string]*Event // 未恢复的告警, 设备名称|告警ID|位置 -> 告警事件
+
+	devices  map[string]*snmpTrapDevice    // IP -> 设备
+	received map[string]map[string]float64 // 设备名称 -> 级别 -> 接收数量
+}
+
+func NewSnmpTrapReceiver(conf SnmpConfig) (*SnmpTrapReceiver, error) {
+	c := new(SnmpTrapReceiver)
+
+	logger, err := NewLogger("snmp_trap.log")
+	if err != nil {
+		return nil, err
+	}
+	c.Log = logger
+
+	c.Conf = conf
+
+	c.EventFile = "data/snmp_trap_event.json"
+	c.AlarmFile = "data/snmp_trap_alarm.json"
+	c.TimeSeriesFile = "data/snmp_trap_timeseries.json"
+
+	c.Sink = NewFileSink(c.TimeSeriesFile)
+
+	return c, nil
+}
+
+func (c *SnmpTrapReceiver) Start() {
+	c.Log.Debugf("接收SNMP Trap, 地址: %s", c.Conf.TrapAddress)
+
+	c.LoadDevices()
+	c.LoadAlarms()
+
+	conn, err := net.ListenPacket("udp", c.Conf.TrapAddress)
+	if err != nil {
+		c.Log.Errorf("监听地址[%s]失败, error: %v", c.Conf.TrapAddress, err)
+		return
+	}
+	if err := c.Serve(conn); err != nil {
+		c.Log.Errorf("接收Trap失败, error: %v", err)
+	}
+}
+
+// LoadDevices 读取设备的Trap映射, 配置错误的设备不接收Trap
+func (c *SnmpTrapReceiver) LoadDevices() {
+	c.devices = make(map[string]*snmpTrapDevice)
+	c.received = make(map[string]map[string]float64)
+
+	mappings := make(map[string]*SnmpMapping)
+	for i := 0; i < len(c.Conf.Devices); i++ {
+		device := c.Conf.Devices[i]
+		mapping, ok := mappings[device.Vendor]
+		if !ok {
+			var err error
+			if mapping, err = LoadSnmpMapping(c.Conf.MappingDir, device.Vendor); err != nil {
+				c.Log.Errorf("读取OID映射文件失败, vendor: %s, error: %v", device.Vendor, err)
+				continue
+			}
+			mappings[device.Vendor] = mapping
+		}
+		if mapping.Trap == nil {
+			c.Log.Errorf("设备[%s]的OID映射文件中没有Trap映射, vendor: %s", device.Name, device.Vendor)
+			continue
+		}
+
+		client, err := NewSnmpTrapClient(device, c.Conf.Timeout.Duration, c.Conf.Retries)
+		if err != nil {
+			c.Log.Errorf("设备[%s]配置错误, error: %v", device.Name, err)
+			continue
+		}
+		ip, err := net.ResolveIPAddr("ip", client.Target)
+		if err != nil {
+			c.Log.Errorf("解析设备[%s]的地址失败, error: %v", device.Name, err)
+			continue
+		}
+		c.devices[ip.IP.String()] = &snmpTrapDevice{Conf: device, Mapping: mapping, client: client}
+	}
+}
+
+// NewSnmpTrapClient 创建解码Trap的连接参数
+//
+// SNMPv3的Trap由设备作为权威引擎发送, 密钥需要使用设备的引擎ID生成, 不能通过发现获取.
+// SNMPv3 Inform以接收端为权威引擎, 不支持
+func NewSnmpTrapClient(device SnmpDevice, timeout time.Duration, retries int) (*gosnmp.GoSNMP, error) {
+	client, err := NewSnmpClient(device, timeout, retries)
+	if err != nil {
+		return nil, err
+	}
+	if client.Version == gosnmp.Version3 {
+		engineId, err := hex.DecodeString(strings.TrimPrefix(device.EngineId, "0x"))
+		if err != nil || len(engineId) == 0 {
+			return nil, fmt.Errorf("SNMPv3需要配置设备的引擎ID, engine_id: %s", device.EngineId)
+		}
+		client.SecurityParameters.(*gosnmp.UsmSecurityParameters).AuthoritativeEngineID = string(engineId)
+	}
+	return client, nil
+}
+
+// LoadAlarms 读取上次保存的未恢复告警
+func (c *SnmpTrapReceiver) LoadAlarms() {
+	c.Alarms = make(map[string]*Event)
+	data, err := ioutil.ReadFile(c.AlarmFile)
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, &c.Alarms); err != nil {
+		c.Log.Errorf("解析告警状态文件失败, error: %v", err)
+		c.Alarms = make(map[string]*Event)
+	}
+}
+
+// snmpTrapExpireInterval 检查告警是否过期的间隔, 没有收到Trap时也需要清除过期的告警
+const snmpTrapExpireInterval = time.Minute
+
+// Serve 循环读取Trap, 连接关闭时返回
+//
+// InformRequest(v2c)处理成功后回复Response, 处理失败时不回复, 设备会重发
+func (c *SnmpTrapReceiver) Serve(conn net.PacketConn) error {
+	buf := make([]byte, 65535)
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(snmpTrapExpireInterval))
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if e, ok := err.(net.Error); ok && e.Timeout() {
+				c.ExpireAlarms(time.Now())
+				continue
+			}
+			return err
+		}
+		data := make([]byte, n)
+		copy(data, buf[:n])
+		packet, err := c.HandlePacket(data, addr)
+		if err != nil {
+			c.Log.Warnf("丢弃来自[%s]的Trap, %v", addr, err)
+			continue
+		}
+		if packet.PDUType == gosnmp.InformRequest {
+			if err := c.ReplyInform(conn, packet, addr); err != nil {
+				c.Log.Errorf("回复[%s]的Inform失败, error: %v", addr, err)
+			}
+		}
+		c.ExpireAlarms(time.Now())
+	}
+}
+
+// ReplyInform 回复InformRequest, Response使用请求中相同的varbind
+func (c *SnmpTrapReceiver) ReplyInform(conn net.PacketConn, packet *gosnmp.SnmpPacket, addr net.Addr) error {
+	packet.PDUType = gosnmp.GetResponse
+	packet.Error = gosnmp.NoError
+	packet.ErrorIndex = 0
+	data, err := packet.MarshalMsg()
+	if err != nil {
+		return err
+	}
+	_, err = conn.WriteTo(data, addr)
+	return err
+}
+
+// HandlePacket 解码Trap, 记录事件和告警状态, 写入时序数据, 返回解码后的Trap
+func (c *SnmpTrapReceiver) HandlePacket(data []byte, addr net.Addr) (*gosnmp.SnmpPacket, error) {
+	host := addr.String()
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	device, ok := c.devices[net.ParseIP(host).String()]
+	if !ok {
+		return nil, errors.New("来源地址不是配置的设备")
+	}
+
+	packet := device.client.UnmarshalTrap(data, false)
+	if packet == nil {
+		return nil, fmt.Errorf("设备[%s]的Trap解码或认证失败", device.Conf.Name)
+	}
+	if packet.Version != device.client.Version {
+		return nil, fmt.Errorf("设备[%s]的Trap版本不一致: %s", device.Conf.Name, packet.Version)
+	}
+	switch packet.Version {
+	case gosnmp.Version2c:
+		if packet.Community != device.client.Community {
+			return nil, fmt.Errorf("设备[%s]的Trap团体名不一致", device.Conf.Name)
+		}
+	case gosnmp.Version3:
+		params, ok := packet.SecurityParameters.(*gosnmp.UsmSecurityParameters)
+		if !ok || params.UserName != device.Conf.Username {
+			return nil, fmt.Errorf("设备[%s]的Trap用户不一致", device.Conf.Name)
+		}
+	}
+	if packet.PDUType != gosnmp.SNMPv2Trap && packet.PDUType != gosnmp.InformRequest {
+		return nil, fmt.Errorf("设备[%s]发送的不是Trap: %v", device.Conf.Name, packet.PDUType)
+	}
+	if packet.Version == gosnmp.Version3 && packet.PDUType == gosnmp.InformRequest {
+		// Inform以接收端为权威引擎, 设备需要发现接收端的引擎ID并用其生成密钥, 这里只按设备的引擎ID校验
+		return nil, fmt.Errorf("设备[%s]发送的是SNMPv3 Inform, 不支持, 需要在设备上配置为Trap", device.Conf.Name)
+	}
+
+	trapOid := ""
+	for i := 0; i < len(packet.Variables); i++ {
+		pdu := packet.Variables[i]
+		if snmpOid(pdu.Name) == snmpTrapOid {
+			trapOid, _ = snmpValue(pdu)
+			break
+		}
+	}
+	event := device.Mapping.Trap.ToEvent(trapOid, packet.Variables, time.Now())
+	event.Source = device.Conf.Vendor
+	event.Device = device.Conf.Name
+	c.Log.Warnf("设备[%s]的Trap[%s], 事件[%s], 告警ID: %s, 级别: %s, 位置: %s, 描述: %s",
+		device.Conf.Name, trapOid, event.Type, event.Id, event.Level, event.Location, event.Message)
+
+	if err := c.SetEvent(event); err != nil {
+		return nil, err
+	}
+	return packet, nil
+}
+
+// SetEvent 保存事件, 更新未恢复的告警
+func (c *SnmpTrapReceiver) SetEvent(event *Event) error {
+	if _, ok := c.received[event.Device]; !ok {
+		c.received[event.Device] = make(map[string]float64)
+	}
+	c.received[event.Device][event.Level]++
+
+	if err := AppendEvents(c.EventFile, []*Event{event}); err != nil {
+		c.Log.Errorf("写入告警事件失败, error: %v", err)
+		return err
+	}
+
+	key := event.Device + "|" + event.Id + "|" + event.Location
+	switch event.Type {
+	case EventAlarmRaised:
+		c.Alarms[key] = event
+	case EventAlarmCleared:
+		delete(c.Alarms, key)
+	default:
+		_ = c.WriteSamples(event)
+		return nil
+	}
+	data, _ := json.Marshal(c.Alarms)
+	if err := ioutil.WriteFile(c.AlarmFile, data, os.ModePerm); err != nil {
+		c.Log.Errorf("写入告警状态文件失败, error: %v", err)
+		return err
+	}
+	return c.WriteSamples(event)
+}
+
+// ExpireAlarms 清除超过过期时间的告警, 记录为告警恢复事件
+func (c *SnmpTrapReceiver) ExpireAlarms(now time.Time) {
+	expired := make([]*Event, 0)
+	for key, alarm := range c.Alarms {
+		if len(alarm.ExpireTime) == 0 {
+			continue
+		}
+		expireTime, err := time.ParseInLocation("2006-01-02 15:04:05", alarm.ExpireTime, time.Local)
+		if err != nil || now.Before(expireTime) {
+			continue
+		}
+		delete(c.Alarms, key)
+		expired = append(expired, &Event{
+			Time:     now.Format("2006-01-02 15:04:05"),
+			Source:   alarm.Source,
+			Device:   alarm.Device,
+			Type:     EventAlarmCleared,
+			Id:       alarm.Id,
+			Level:    "info",
+			Location: alarm.Location,
+			Message:  "超过过期时间没有再次收到告警, 自动清除: " + alarm.Message,
+		})
+	}
+	if len(expired) == 0 {
+		return
+	}
+	c.Log.Infof("清除%d条过期的告警", len(expired))
+
+	if err := AppendEvents(c.EventFile, expired); err != nil {
+		c.Log.Errorf("写入告警事件失败, error: %v", err)
+	}
+	data, _ := json.Marshal(c.Alarms)
+	if err := ioutil.WriteFile(c.AlarmFile, data, os.ModePerm); err != nil {
+		c.Log.Errorf("写入告警状态文件失败, error: %v", err)
+	}
+	// 每个设备写入一次告警数量
+	written := make(map[string]bool)
+	for i := 0; i < len(expired); i++ {
+		if !written[expired[i].Device] {
+			written[expired[i].Device] = true
+			_ = c.WriteSamples(expired[i])
+		}
+	}
+}
+
+// ActiveAlarms 设备未恢复的告警, 按告警ID排序
+func (c *SnmpTrapReceiver) ActiveAlarms(device string) []*Event {
+	alarms := make([]*Event, 0)
+	for _, alarm := range c.Alarms {
+		if alarm.Device == device {
+			alarms = append(alarms, alarm)
+		}
+	}
+	sort.Slice(alarms, func(i, j int) bool {
+		if alarms[i].Id != alarms[j].Id {
+			return alarms[i].Id < alarms[j].Id
+		}
+		return alarms[i].Location < alarms[j].Location
+	})
+	return alarms
+}
+
+// WriteSamples 写入事件所属设备的Trap接收数量和未恢复的告警数量
+//
+// snmp_trap_received_total 为进程启动后按级别累计的接收数量, snmp_trap_active_alarms 按级别统计
+func (c *SnmpTrapReceiver) WriteSamples(event *Event) error {
+	now := time.Now().Unix()
+	samples := make([]*Sample, 0)
+	for level, value := range c.received[event.Device] {
+		samples = append(samples, &Sample{
+			Metric:    "snmp_trap_received_total",
+			Labels:    map[string]string{"device": event.Device, "vendor": event.Source, "level": level},
+			Value:     value,
+			Timestamp: now,
+		})
+	}
+	active := make(map[string]float64)
+	alarms := c.ActiveAlarms(event.Device)
+	for i := 0; i < len(alarms); i++ {
+		active[alarms[i].Level]++
+	}
+	// 告警全部恢复后需要写入0
+	levels := []string{"critical", "major", "warning"}
+	for i := 0; i < len(levels); i++ {
+		if _, ok := active[levels[i]]; !ok {
+			active[levels[i]] = 0
+		}
+	}
+	for level, value := range active {
+		samples = append(samples, &Sample{
+			Metric:    "snmp_trap_active_alarms",
+			Labels:    map[string]string{"device": event.Device, "vendor": event.Source, "level": level},
+			Value:     value,
+			Timestamp: now,
+		})
+	}
+	if err := c.Sink.Write(samples); err != nil {
+		c.Log.Errorf("写入设备[%s]的Trap时序数据失败, error: %v", event.Device, err)
+		return err
+	}
+	return nil
+}
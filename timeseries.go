@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Sample struct {
+	Metric    string            `json:"metric"`
+	Labels    map[string]string `json:"labels"`
+	Value     float64           `json:"value"`
+	Timestamp int64             `json:"timestamp"` // 采样时间(秒)
+}
+
+// Key 指标名称, 标签和采样时间相同的采样点为同一个
+func (s *Sample) Key() string {
+	names := make([]string, 0, len(s.Labels))
+	for name := range s.Labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString(s.Metric)
+	for i := 0; i < len(names); i++ {
+		b.WriteString("," + names[i] + "=" + s.Labels[names[i]])
+	}
+	b.WriteString("@" + strconv.FormatInt(s.Timestamp, 10))
+	return b.String()
+}
+
+// TimeSeriesSink 时序数据写入目标
+type TimeSeriesSink interface {
+	Write(samples []*Sample) error
+}
+
+// TimeSeriesKeyReader 可以读取已写入采样点的写入目标, 补录时用于去重
+type TimeSeriesKeyReader interface {
+	Keys(start, end int64) (map[string]bool, error)
+}
+
+// FileSink 以JSON Lines格式追加写入文件
+type FileSink struct {
+	Path string
+}
+
+func NewFileSink(path string) *FileSink {
+	return &FileSink{Path: path}
+}
+
+func (s *FileSink) Write(samples []*Sample) error {
+	values := make([]interface{}, len(samples))
+	for i := 0; i < len(samples); i++ {
+		values[i] = samples[i]
+	}
+	return AppendJsonLines(s.Path, values...)
+}
+
+// Keys 读取采样时间在[start, end]范围内的已写入采样点
+func (s *FileSink) Keys(start, end int64) (map[string]bool, error) {
+	keys := make(map[string]bool)
+	file, err := os.Open(s.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return keys, nil
+		}
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		sample := new(Sample)
+		if err := json.Unmarshal(scanner.Bytes(), sample); err != nil {
+			continue
+		}
+		if sample.Timestamp >= start && sample.Timestamp <= end {
+			keys[sample.Key()] = true
+		}
+	}
+	return keys, scanner.Err()
+}
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"encoding/json"
 	"os"
+	"time"
 )
 
 func isExist(path string) bool {
@@ -17,3 +19,45 @@ func isExist(path string) bool {
 	}
 	return true
 }
+
+// RateLimiter 限制每秒请求数, rate小于等于0时不限制
+type RateLimiter struct {
+	ticker *time.Ticker
+}
+
+func NewRateLimiter(rate int) *RateLimiter {
+	l := new(RateLimiter)
+	if rate > 0 {
+		l.ticker = time.NewTicker(time.Second / time.Duration(rate))
+	}
+	return l
+}
+
+func (l *RateLimiter) Wait() {
+	if l == nil || l.ticker == nil {
+		return
+	}
+	<-l.ticker.C
+}
+
+// AppendJsonLines 以JSON Lines格式追加写入文件
+func AppendJsonLines(path string, values ...interface{}) error {
+	if len(values) == 0 {
+		return nil
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	encoder := json.NewEncoder(file)
+	for i := 0; i < len(values); i++ {
+		if err := encoder.Encode(values[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}